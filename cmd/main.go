@@ -12,20 +12,50 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/julienschmidt/httprouter"
 	"github.com/sonyamoonglade/notification-service/config"
 	"github.com/sonyamoonglade/notification-service/internal/app_middlewares"
+	"github.com/sonyamoonglade/notification-service/internal/audit"
+	"github.com/sonyamoonglade/notification-service/internal/escalation"
 	"github.com/sonyamoonglade/notification-service/internal/events"
+	"github.com/sonyamoonglade/notification-service/internal/group"
+	"github.com/sonyamoonglade/notification-service/internal/stats"
 	"github.com/sonyamoonglade/notification-service/internal/storage"
 	"github.com/sonyamoonglade/notification-service/internal/subscription"
+	"github.com/sonyamoonglade/notification-service/internal/tenant"
+	"github.com/sonyamoonglade/notification-service/pkg/alert"
 	"github.com/sonyamoonglade/notification-service/pkg/bot"
 	"github.com/sonyamoonglade/notification-service/pkg/formatter"
+	"github.com/sonyamoonglade/notification-service/pkg/health"
 	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/metrics"
 	"github.com/sonyamoonglade/notification-service/pkg/postgres"
+	"github.com/sonyamoonglade/notification-service/pkg/requestid"
 	"github.com/sonyamoonglade/notification-service/pkg/server"
+	"github.com/sonyamoonglade/notification-service/pkg/signature"
 	"github.com/sonyamoonglade/notification-service/pkg/telegram"
 	"github.com/sonyamoonglade/notification-service/pkg/template"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+	"github.com/sonyamoonglade/notification-service/pkg/tracing"
 )
 
+//serviceName is service.name of traces
+const serviceName = "notification-service"
+
+//Bots are pinged every botPingInterval. Readiness fails if telegram hasn't answered getMe for botPingMaxAge
+//or getUpdates for botPollMaxAge. getUpdates is a long poll of 60 seconds (see bot.NewBot)
+const (
+	botPingInterval = time.Second * 30
+	botPingMaxAge   = time.Minute * 2
+	botPollMaxAge   = time.Minute * 3
+)
+
+//alertWatchInterval is how often postgres and bot polls are checked for admin alerts
+const alertWatchInterval = time.Second * 30
+
+//noncePurgeInterval is how often expired nonces of signed requests are purged
+const noncePurgeInterval = time.Minute
+
 func main() {
 
 	log.Println("booting an application")
@@ -55,19 +85,28 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 
-	pg, err := postgres.New(ctx, appCfg.DatabaseURL)
+	shutdownTracing, err := tracing.Init(ctx, tracing.Config{
+		Exporter:    appCfg.TracingExporter,
+		Endpoint:    appCfg.TracingEndpoint,
+		Insecure:    appCfg.TracingInsecure,
+		SampleRatio: appCfg.TracingSampleRatio,
+		ServiceName: serviceName,
+	})
+	if err != nil {
+		logger.Fatalf("could not init tracing. %s", err.Error())
+	}
+
+	pg, err := postgres.New(ctx, logger, appCfg.DatabaseURL, appCfg.DatabaseMinConns, appCfg.DatabaseMaxConns)
 	if err != nil {
 		logger.Fatalf("could not create pool. %s", err.Error())
 	}
+	if err = metrics.Register(metrics.NewPoolCollector(pg.Pool)); err != nil {
+		logger.Fatalf("could not register pool metrics. %s", err.Error())
+	}
 	logger.Info("database has connected")
 
 	srv, router := server.NewServer(&appCfg)
 
-	appBot, err := bot.NewBot(appCfg.BotToken, logger)
-	if err != nil {
-		logger.Fatalf("could not create bot instance. %s", err.Error())
-	}
-
 	appFmt := formatter.NewFormatter()
 	templateProvider := template.NewTemplateProvider()
 
@@ -78,22 +117,160 @@ func main() {
 
 	pgStorage := storage.NewPostgresStorage(logger, pg.Pool)
 
-	eventsService := events.NewEventsService(logger, pgStorage, templateProvider)
+	auditService := audit.NewAuditService(logger, pgStorage)
+
+	eventsService := events.NewEventsService(logger, pgStorage, auditService, templateProvider)
+
+	tenantService := tenant.NewTenantService(logger, pgStorage, eventsService, auditService)
+	tenantTransport := tenant.NewTenantTransport(logger, tenantService, appCfg.AdminAPIKey)
+
+	//Default tenant gets its first api key, otherwise its producers are rejected after upgrade to tenants (see README)
+	bootstrapKey, err := tenantService.Bootstrap(ctx, appCfg.DefaultTenantAPIKey)
+	if err != nil {
+		logger.Fatalf("could not issue api key of default tenant. %s", err.Error())
+	}
+	if bootstrapKey != "" {
+		logger.Warnf("api key of default tenant is issued, it won't be shown again: %s", bootstrapKey)
+	}
+
+	//Producers sign fire requests of their tenants only
+	producers := make(map[string]signature.Producer, len(appCfg.SigningProducers))
+	for _, pc := range appCfg.SigningProducers {
+		t, err := tenantService.GetTenantByName(ctx, pc.Tenant)
+		if err != nil {
+			logger.Fatalf("could not get tenant %s of %s producer. %s", pc.Tenant, pc.Name, err.Error())
+		}
+		producers[pc.Name] = signature.Producer{Secret: pc.Secret, TenantID: t.TenantID}
+	}
+	verifier := signature.NewVerifier(logger, producers, appCfg.SigningSkew, pgStorage)
 
-	mw := app_middlewares.New(logger, eventsService)
+	//Webhooks are verified by secret token and tenant routes by admin key, every other request is scoped to tenant of api key
+	mw := app_middlewares.New(logger,
+		eventsService,
+		tenantService,
+		verifier,
+		appCfg.SigningRequired,
+		telegram.WebhookPath,
+		tenant.RoutesPrefix,
+		metrics.Path,
+		health.LivePath,
+		health.ReadyPath)
+	routeOf := func(r *http.Request) string {
+		return server.Route(router, r)
+	}
+	matchOf := func(r *http.Request) (string, httprouter.Params) {
+		return server.Match(router, r)
+	}
+	//Request id goes first, so traces, access log and logs of handlers have it
+	srv.Handler = requestid.Wrap(
+		tracing.Wrap(routeOf,
+			logging.AccessLog(logger, matchOf,
+				metrics.Instrument(routeOf, mw.APIKey.Wrap(router)))))
+
+	//Default bot sends every event of default tenant not assigned to branded bots
+	bots := bot.NewRegistry()
+	appBot, err := bot.NewBot(bot.DefaultName, appCfg.BotToken, appCfg.BotAPIEndpoint, logger)
+	if err != nil {
+		logger.Fatalf("could not create bot instance. %s", err.Error())
+	}
+	if err = bots.Add(appBot, tenancy.DefaultID, nil); err != nil {
+		logger.Fatalf("could not add bot. %s", err.Error())
+	}
+	for _, bc := range appCfg.Bots {
+		b, err := bot.NewBot(bc.Name, bc.Token, appCfg.BotAPIEndpoint, logger)
+		if err != nil {
+			logger.Fatalf("could not create %s bot instance. %s", bc.Name, err.Error())
+		}
+		t, err := tenantService.GetTenantByName(ctx, bc.Tenant)
+		if err != nil {
+			logger.Fatalf("could not get tenant %s of %s bot. %s", bc.Tenant, bc.Name, err.Error())
+		}
+		if err = bots.Add(b, t.TenantID, bc.Events); err != nil {
+			logger.Fatalf("could not add bot. %s", err.Error())
+		}
+	}
+
+	//Admins are alerted about internal failures by one of the bots
+	alertBot, ok := bots.Get(appCfg.AlertBot)
+	if ok != true {
+		logger.Fatalf("unknown alert bot %s", appCfg.AlertBot)
+	}
+	alerter := alert.NewNotifier(logger, alertBot.NotifyAdmin, alert.Config{
+		ChatIDs:         appCfg.AlertChatIDs,
+		SummaryInterval: appCfg.AlertSummaryInterval,
+		MaxPerMinute:    appCfg.AlertMaxPerMinute,
+	})
+	for _, b := range bots.All() {
+		b.SetAlerter(alerter)
+	}
 
-	subscriptionService := subscription.NewSubscriptionService(logger, pgStorage)
+	escalationService := escalation.NewEscalationService(logger, pgStorage, auditService, bots)
+	//Read escalations.json
+	if err = escalationService.ReadPolicies(); err != nil {
+		logger.Fatalf("could not read escalation policies. %s", err.Error())
+	}
+	escalationTransport := escalation.NewEscalationTransport(logger, escalationService, mw.APIKey)
+	escalationWorker := escalation.NewWorker(logger, escalationService)
+
+	subscriptionService := subscription.NewSubscriptionService(logger, pgStorage, auditService)
 	subscriptionTransport := subscription.NewSubscriptionTransport(logger,
 		subscriptionService,
 		mw.DoesExist,
+		mw.Signed,
+		mw.APIKey,
 		eventsService,
-		templateProvider,
+		escalationService,
 		appFmt,
-		appBot)
-
-	telegramListener := telegram.NewTelegramListener(logger, appBot, subscriptionService)
+		bots,
+		alerter,
+		appCfg.Location())
+
+	groupService := group.NewGroupService(logger, pgStorage, auditService)
+	groupTransport := group.NewGroupTransport(logger, groupService, subscriptionService, eventsService, mw.APIKey)
+
+	auditTransport := audit.NewAuditTransport(logger, auditService, mw.APIKey)
+
+	statsService := stats.NewStatsService(logger, pgStorage)
+	statsTransport := stats.NewStatsTransport(logger, statsService, mw.APIKey)
+
+	//Every bot has its own listener, since chat ids and links are per bot
+	telegramListeners := make(map[string]telegram.Listener)
+	for _, b := range bots.All() {
+		telegramListeners[b.Name()] = telegram.NewTelegramListener(logger,
+			b,
+			bots.TenantOf(b.Name()),
+			subscriptionService,
+			escalationService,
+			eventsService,
+			appCfg.SelfSubscribableEvents,
+			appCfg.Location())
+	}
 
 	subscriptionTransport.InitRoutes(router)
+	escalationTransport.InitRoutes(router)
+	groupTransport.InitRoutes(router)
+	tenantTransport.InitRoutes(router)
+	auditTransport.InitRoutes(router)
+	statsTransport.InitRoutes(router)
+	router.Handler(http.MethodGet, metrics.Path, metrics.Handler())
+
+	//Readiness fails if any dependency is down, so orchestrator stops routing requests to the replica
+	checker := health.NewChecker(logger)
+	checker.Add("postgres", pg.Pool.Ping)
+	checker.Add("events", eventsService.Loaded)
+	for _, b := range bots.All() {
+		checker.Add("telegram:"+b.Name(), health.Recent("getMe of "+b.Name()+" bot", b.LastPing, botPingMaxAge))
+		if appCfg.BotMode == config.PollingMode {
+			checker.Add("telegram_poll:"+b.Name(), health.Recent("getUpdates of "+b.Name()+" bot", b.LastPoll, botPollMaxAge))
+		}
+	}
+	checker.InitRoutes(router)
+	if appCfg.BotMode == config.WebhookMode {
+		for name, l := range telegramListeners {
+			webhook := telegram.NewWebhook(logger, l, name, appCfg.WebhookSecret)
+			webhook.InitRoutes(router)
+		}
+	}
 	logger.Info("initialized routes")
 
 	//Read events.json
@@ -101,8 +278,58 @@ func main() {
 		logger.Fatalf("could not load base events. %s", err.Error())
 	}
 
-	go telegramListener.ListenForUpdates()
-	logger.Info("bot is listening to updates and ready to notify")
+	//Events are assigned to bots by names
+	for _, tenantID := range bots.Tenants() {
+		evnts, err := eventsService.GetAvailableEvents(tenancy.WithID(ctx, tenantID))
+		if err != nil {
+			logger.Fatalf("could not get events. %s", err.Error())
+		}
+		if err = bots.ResolveEvents(tenantID, evnts); err != nil {
+			logger.Fatalf("could not assign events to bots. %s", err.Error())
+		}
+	}
+
+	//Escalation policies are per tenant and name events the same way
+	for _, name := range escalationService.PolicyTenants() {
+		t, err := tenantService.GetTenantByName(ctx, name)
+		if err != nil {
+			logger.Fatalf("could not get tenant %s of escalation policies. %s", name, err.Error())
+		}
+		evnts, err := eventsService.GetAvailableEvents(tenancy.WithID(ctx, t.TenantID))
+		if err != nil {
+			logger.Fatalf("could not get events. %s", err.Error())
+		}
+		if err = escalationService.ResolvePolicies(t.TenantID, name, evnts); err != nil {
+			logger.Fatalf("could not resolve escalation policies. %s", err.Error())
+		}
+	}
+
+	if appCfg.BotMode == config.PollingMode {
+		for _, l := range telegramListeners {
+			go l.ListenForUpdates()
+		}
+		logger.Info("bots are listening to updates and ready to notify")
+	}
+
+	workerCtx, workerCancel := context.WithCancel(context.Background())
+	defer workerCancel()
+
+	go escalationWorker.Run(workerCtx)
+	logger.Info("escalation worker has started")
+
+	go bots.KeepAlive(workerCtx, botPingInterval)
+
+	go verifier.PurgeExpired(workerCtx, noncePurgeInterval)
+
+	go alerter.Run(workerCtx)
+	go alerter.Watch(workerCtx, alert.KindPostgres, pg.Pool.Ping, alertWatchInterval)
+	if appCfg.BotMode == config.PollingMode {
+		for _, b := range bots.All() {
+			go alerter.Watch(workerCtx, alert.KindBotPoll,
+				health.Recent("getUpdates of "+b.Name()+" bot", b.LastPoll, botPollMaxAge), alertWatchInterval)
+		}
+	}
+	logger.Info("admin alerts are watching postgres and bot polls")
 
 	go func() {
 		err := srv.ListenAndServe()
@@ -113,6 +340,16 @@ func main() {
 	}()
 	logger.Infof("server has started on port: %s", appCfg.AppPort)
 
+	//Server is up, so telegram might start pushing updates
+	if appCfg.BotMode == config.WebhookMode {
+		for _, b := range bots.All() {
+			if err := b.SetWebhook(appCfg.WebhookURL+telegram.WebhookPathFor(b.Name()), appCfg.WebhookSecret); err != nil {
+				logger.Fatalf("could not set webhook of %s bot. %s", b.Name(), err.Error())
+			}
+		}
+		logger.Info("bot webhooks are set and ready to notify")
+	}
+
 	exit := make(chan os.Signal, 1)
 	signal.Notify(exit, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 
@@ -120,17 +357,42 @@ func main() {
 	//Graceful shutdown
 	logger.Info("Shutting down gracefully...")
 
+	//Requests are still served while orchestrator notices failing readiness
+	checker.Shutdown()
+	time.Sleep(appCfg.ShutdownDelay)
+
 	//Timeout for shutdown
 	gctx, gcancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer gcancel()
 
+	if appCfg.BotMode == config.WebhookMode && appCfg.DeleteWebhookOnShutdown {
+		for _, b := range bots.All() {
+			if err := b.DeleteWebhook(); err != nil {
+				logger.Error(err.Error())
+			}
+		}
+		logger.Info("bot webhooks are deleted")
+	}
+
 	defer func() {
+		workerCancel()
+		logger.Info("stopping escalation worker...")
+
+		if err := shutdownTracing(gctx); err != nil {
+			logger.Error(err.Error())
+		}
+		logger.Info("flushing traces...")
+
 		logger.Info("before closing postgres")
 		pg.CloseConn()
 		logger.Info("closing postgres connection...")
 
-		appBot.ClosePoll()
-		logger.Info("closing bot poll...")
+		if appCfg.BotMode == config.PollingMode {
+			for _, b := range bots.All() {
+				b.ClosePoll()
+			}
+			logger.Info("closing bot polls...")
+		}
 	}()
 
 	if err := srv.Shutdown(gctx); err != nil {
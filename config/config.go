@@ -4,21 +4,139 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 const (
-	DatabaseURL = "DATABASE_URL"
-	BotToken    = "BOT_TOKEN"
-	Env         = "ENV"
+	DatabaseURL   = "DATABASE_URL"
+	BotToken      = "BOT_TOKEN"
+	Env           = "ENV"
+	WebhookSecret = "WEBHOOK_SECRET"
+	AdminAPIKey   = "ADMIN_API_KEY"
+	//DefaultTenantAPIKey is the first api key of default tenant, see tenant.Service.Bootstrap
+	DefaultTenantAPIKey = "DEFAULT_TENANT_API_KEY"
 )
 
+//minDefaultTenantAPIKeyLen is length of hex of 16 random bytes
+const minDefaultTenantAPIKeyLen = 32
+
+//defaultBotName is reserved for the bot created from BOT_TOKEN (see bot.DefaultName)
+const defaultBotName = "default"
+
+const defaultSigningSkew = time.Minute * 5
+
+//Exporters of traces (see tracing.Config)
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+//defaultShutdownDelay is how long failing readiness is served before the server stops accepting requests
+const defaultShutdownDelay = time.Second * 5
+
+//Size of database pool unless database.min_conns and database.max_conns are set
+const (
+	defaultDatabaseMinConns = 2
+	defaultDatabaseMaxConns = 4
+)
+
+//defaultTenantName is tenant of the default bot and of bots without tenant (see tenancy.DefaultName)
+const defaultTenantName = "default"
+
+//Ways bot receives updates. Polling is the default, webhook lets several replicas run at the same time
+const (
+	PollingMode = "polling"
+	WebhookMode = "webhook"
+)
+
+//Secret token might contain only A-Z, a-z, 0-9, _ and - (see setWebhook docs)
+var webhookSecretRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
 type AppConfig struct {
 	DatabaseURL string
-	BotToken    string
-	AppPort     string
-	Env         string
+	//DatabaseMinConns and DatabaseMaxConns size the pool. Watch notification_db_pool_* metrics to tune them
+	DatabaseMinConns int32
+	DatabaseMaxConns int32
+	BotToken         string
+	AppPort          string
+	Env              string
+	//TimeOffset is hours from UTC used for human-friendly times (e.g. /mute until tomorrow)
+	TimeOffset int
+	//SelfSubscribableEvents are names of events subscribers can subscribe to via bot by themselves
+	SelfSubscribableEvents []string
+	//BotMode is either PollingMode or WebhookMode
+	BotMode string
+	//BotAPIEndpoint overrides telegram api endpoint, e.g. to run against local fake api
+	BotAPIEndpoint string
+	//WebhookURL is public base url telegram sends updates to. Required in WebhookMode
+	WebhookURL string
+	//WebhookSecret is checked against X-Telegram-Bot-Api-Secret-Token header. Required in WebhookMode
+	WebhookSecret string
+	//DeleteWebhookOnShutdown must be off if several replicas share the webhook
+	DeleteWebhookOnShutdown bool
+	//Bots are branded bots in addition to the default one created from BotToken
+	Bots []BotConfig
+	//AdminAPIKey guards tenant management routes. They're disabled if it's empty
+	AdminAPIKey string
+	//DefaultTenantAPIKey is issued to default tenant if it has no api keys yet, e.g. right after upgrade to tenants
+	DefaultTenantAPIKey string
+	//SigningRequired rejects unsigned fire requests. Signed ones are verified anyway.
+	//It's on by default if there're producers
+	SigningRequired bool
+	//SigningSkew is how far timestamp of signed request might be from now
+	SigningSkew time.Duration
+	//SigningProducers sign fire requests of their tenants
+	SigningProducers []ProducerConfig
+	//TracingExporter is one of TracingNone (default), TracingStdout and TracingOTLP
+	TracingExporter string
+	//TracingEndpoint is host:port of OTLP/HTTP collector. Empty is OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318
+	TracingEndpoint string
+	TracingInsecure bool
+	//TracingSampleRatio is share of traces started by the service that are recorded
+	TracingSampleRatio float64
+	//AlertBot sends alerts about internal failures to AlertChatIDs. Alerts are only logged if there're no chats
+	AlertBot     string
+	AlertChatIDs []int64
+	//AlertSummaryInterval is how often repeated alerts are summarized
+	AlertSummaryInterval time.Duration
+	AlertMaxPerMinute    int
+	//ShutdownDelay lets orchestrator notice failing readiness and stop routing requests before the server is closed
+	ShutdownDelay time.Duration
+}
+
+//BotConfig is branded bot of Tenant. Events are names of events it sends, the rest are sent by the first bot of Tenant
+type BotConfig struct {
+	Name   string
+	Token  string
+	Tenant string
+	Events []string
+}
+
+//ProducerConfig is producer signing fire requests of Tenant with Secret
+type ProducerConfig struct {
+	Name   string
+	Secret string
+	Tenant string
+}
+
+//producerConfig is signing.producers[] item of config file. Secret is read from SecretEnv env variable
+type producerConfig struct {
+	Name      string `mapstructure:"name"`
+	SecretEnv string `mapstructure:"secret_env"`
+	Tenant    string `mapstructure:"tenant"`
+}
+
+//botConfig is bots[] item of config file. Token is read from TokenEnv env variable
+type botConfig struct {
+	Name     string   `mapstructure:"name"`
+	TokenEnv string   `mapstructure:"token_env"`
+	Tenant   string   `mapstructure:"tenant"`
+	Events   []string `mapstructure:"events"`
 }
 
 func GetAppConfig() (AppConfig, error) {
@@ -47,14 +165,212 @@ func GetAppConfig() (AppConfig, error) {
 		return AppConfig{}, fmt.Errorf("missing %s", Env)
 	}
 
+	dbMinConns := int32(defaultDatabaseMinConns)
+	if v.IsSet("database.min_conns") {
+		dbMinConns = v.GetInt32("database.min_conns")
+	}
+	dbMaxConns := int32(defaultDatabaseMaxConns)
+	if v.IsSet("database.max_conns") {
+		dbMaxConns = v.GetInt32("database.max_conns")
+	}
+	if dbMinConns < 0 || dbMaxConns < 1 || dbMinConns > dbMaxConns {
+		return AppConfig{}, errors.New("invalid database.min_conns or database.max_conns")
+	}
+
+	timeOffset := v.GetInt("app.time_offset")
+	selfSubscribable := v.GetStringSlice("bot.self_subscribable_events")
+
+	botMode := v.GetString("bot.mode")
+	if botMode == "" {
+		botMode = PollingMode
+	}
+	if botMode != PollingMode && botMode != WebhookMode {
+		return AppConfig{}, fmt.Errorf("invalid bot.mode %s", botMode)
+	}
+
+	webhookURL := v.GetString("bot.webhook.url")
+	webhookSecret := os.Getenv(WebhookSecret)
+	deleteWebhookOnShutdown := true
+	if v.IsSet("bot.webhook.delete_on_shutdown") {
+		deleteWebhookOnShutdown = v.GetBool("bot.webhook.delete_on_shutdown")
+	}
+
+	bots, err := readBots(v)
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	signingProducers, err := readProducers(v)
+	if err != nil {
+		return AppConfig{}, err
+	}
+	signingRequired := len(signingProducers) > 0
+	if v.IsSet("signing.required") {
+		signingRequired = v.GetBool("signing.required")
+	}
+	if signingRequired && len(signingProducers) == 0 {
+		return AppConfig{}, errors.New("signing.required is set, but there're no signing.producers")
+	}
+	signingSkew := defaultSigningSkew
+	if v.IsSet("signing.skew_seconds") {
+		signingSkew = time.Duration(v.GetInt("signing.skew_seconds")) * time.Second
+	}
+	if signingSkew <= 0 {
+		return AppConfig{}, errors.New("invalid signing.skew_seconds")
+	}
+
+	tracingExporter := v.GetString("tracing.exporter")
+	if tracingExporter == "" {
+		tracingExporter = TracingNone
+	}
+	if tracingExporter != TracingNone && tracingExporter != TracingStdout && tracingExporter != TracingOTLP {
+		return AppConfig{}, fmt.Errorf("invalid tracing.exporter %s", tracingExporter)
+	}
+	tracingSampleRatio := 1.0
+	if v.IsSet("tracing.sample_ratio") {
+		tracingSampleRatio = v.GetFloat64("tracing.sample_ratio")
+	}
+	if tracingSampleRatio < 0 || tracingSampleRatio > 1 {
+		return AppConfig{}, errors.New("invalid tracing.sample_ratio")
+	}
+
+	alertBot := v.GetString("alerts.bot")
+	if alertBot == "" {
+		alertBot = defaultBotName
+	}
+	if alertBot != defaultBotName && botConfigured(bots, alertBot) != true {
+		return AppConfig{}, fmt.Errorf("unknown alerts.bot %s", alertBot)
+	}
+	var alertChatIDs []int64
+	if err := v.UnmarshalKey("alerts.chat_ids", &alertChatIDs); err != nil {
+		return AppConfig{}, fmt.Errorf("invalid alerts.chat_ids. %s", err.Error())
+	}
+	alertSummaryInterval := time.Duration(v.GetInt("alerts.summary_minutes")) * time.Minute
+	if alertSummaryInterval < 0 {
+		return AppConfig{}, errors.New("invalid alerts.summary_minutes")
+	}
+
+	shutdownDelay := defaultShutdownDelay
+	if v.IsSet("app.shutdown_delay_seconds") {
+		shutdownDelay = time.Duration(v.GetInt("app.shutdown_delay_seconds")) * time.Second
+	}
+	if shutdownDelay < 0 {
+		return AppConfig{}, errors.New("invalid app.shutdown_delay_seconds")
+	}
+
+	defaultTenantAPIKey := os.Getenv(DefaultTenantAPIKey)
+	if defaultTenantAPIKey != "" && len(defaultTenantAPIKey) < minDefaultTenantAPIKeyLen {
+		return AppConfig{}, fmt.Errorf("%s must be at least %d characters", DefaultTenantAPIKey, minDefaultTenantAPIKeyLen)
+	}
+
+	if botMode == WebhookMode {
+		if webhookURL == "" {
+			return AppConfig{}, errors.New("missing bot.webhook.url")
+		}
+		if webhookSecretRegexp.MatchString(webhookSecret) != true {
+			return AppConfig{}, fmt.Errorf("missing or invalid %s", WebhookSecret)
+		}
+	}
+
 	return AppConfig{
-		DatabaseURL: dbURL,
-		BotToken:    botToken,
-		AppPort:     appPort,
-		Env:         env,
+		DatabaseURL:             dbURL,
+		DatabaseMinConns:        dbMinConns,
+		DatabaseMaxConns:        dbMaxConns,
+		BotToken:                botToken,
+		AppPort:                 appPort,
+		Env:                     env,
+		TimeOffset:              timeOffset,
+		SelfSubscribableEvents:  selfSubscribable,
+		BotMode:                 botMode,
+		BotAPIEndpoint:          v.GetString("bot.api_endpoint"),
+		WebhookURL:              strings.TrimSuffix(webhookURL, "/"),
+		WebhookSecret:           webhookSecret,
+		DeleteWebhookOnShutdown: deleteWebhookOnShutdown,
+		Bots:                    bots,
+		AdminAPIKey:             os.Getenv(AdminAPIKey),
+		DefaultTenantAPIKey:     defaultTenantAPIKey,
+		SigningRequired:         signingRequired,
+		SigningSkew:             signingSkew,
+		SigningProducers:        signingProducers,
+		TracingExporter:         tracingExporter,
+		TracingEndpoint:         v.GetString("tracing.endpoint"),
+		TracingInsecure:         v.GetBool("tracing.insecure"),
+		TracingSampleRatio:      tracingSampleRatio,
+		AlertBot:                alertBot,
+		AlertChatIDs:            alertChatIDs,
+		AlertSummaryInterval:    alertSummaryInterval,
+		AlertMaxPerMinute:       v.GetInt("alerts.max_per_minute"),
+		ShutdownDelay:           shutdownDelay,
 	}, nil
 }
 
+func readProducers(v *viper.Viper) ([]ProducerConfig, error) {
+	var raw []producerConfig
+	if err := v.UnmarshalKey("signing.producers", &raw); err != nil {
+		return nil, err
+	}
+
+	producers := make([]ProducerConfig, 0, len(raw))
+	known := make(map[string]bool, len(raw))
+	for _, p := range raw {
+		if p.Name == "" {
+			return nil, errors.New("missing signing.producers[].name")
+		}
+		if known[p.Name] {
+			return nil, fmt.Errorf("duplicate producer %s", p.Name)
+		}
+		known[p.Name] = true
+		secret, ok := os.LookupEnv(p.SecretEnv)
+		if ok != true || p.SecretEnv == "" || secret == "" {
+			return nil, fmt.Errorf("missing secret of %s producer (%s)", p.Name, p.SecretEnv)
+		}
+		tenant := p.Tenant
+		if tenant == "" {
+			tenant = defaultTenantName
+		}
+		producers = append(producers, ProducerConfig{
+			Name:   p.Name,
+			Secret: secret,
+			Tenant: tenant,
+		})
+	}
+	return producers, nil
+}
+
+func readBots(v *viper.Viper) ([]BotConfig, error) {
+	var raw []botConfig
+	if err := v.UnmarshalKey("bots", &raw); err != nil {
+		return nil, err
+	}
+
+	bots := make([]BotConfig, 0, len(raw))
+	for _, b := range raw {
+		if b.Name == "" || b.Name == defaultBotName {
+			return nil, fmt.Errorf("invalid bots[].name %s", b.Name)
+		}
+		token, ok := os.LookupEnv(b.TokenEnv)
+		if ok != true || b.TokenEnv == "" {
+			return nil, fmt.Errorf("missing token of %s bot (%s)", b.Name, b.TokenEnv)
+		}
+		tenant := b.Tenant
+		if tenant == "" {
+			tenant = defaultTenantName
+		}
+		bots = append(bots, BotConfig{
+			Name:   b.Name,
+			Token:  token,
+			Tenant: tenant,
+			Events: b.Events,
+		})
+	}
+	return bots, nil
+}
+
+//Location is fixed zone of TimeOffset
+func (c AppConfig) Location() *time.Location {
+	return time.FixedZone("", c.TimeOffset*60*60)
+}
+
 func readConfig() (*viper.Viper, error) {
 
 	env, ok := os.LookupEnv(Env)
@@ -80,3 +396,12 @@ func readConfig() (*viper.Viper, error) {
 	return viper.GetViper(), nil
 
 }
+
+func botConfigured(bots []BotConfig, name string) bool {
+	for _, b := range bots {
+		if b.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -3,15 +3,29 @@ package app_middlewares
 import (
 	"github.com/sonyamoonglade/notification-service/internal/events"
 	"github.com/sonyamoonglade/notification-service/internal/events/middlewares"
+	"github.com/sonyamoonglade/notification-service/internal/tenant"
+	"github.com/sonyamoonglade/notification-service/internal/tenant/middlewares"
+	"github.com/sonyamoonglade/notification-service/pkg/signature"
 	"go.uber.org/zap"
 )
 
 type AppMiddlewares struct {
 	*event_middlewares.DoesExist
+	*event_middlewares.Signed
+	*tenant_middlewares.APIKey
 }
 
-func New(logger *zap.SugaredLogger, eventService events.Service) *AppMiddlewares {
+//New creates app middlewares. Requests to paths of skipAuthPrefixes aren't checked for api key
+func New(logger *zap.SugaredLogger,
+	eventService events.Service,
+	tenantService tenant.Service,
+	verifier *signature.Verifier,
+	signingRequired bool,
+	skipAuthPrefixes ...string) *AppMiddlewares {
+
 	return &AppMiddlewares{
 		event_middlewares.NewDoesExist(logger, eventService),
+		event_middlewares.NewSigned(logger, verifier, signingRequired),
+		tenant_middlewares.NewAPIKey(logger, tenantService, skipAuthPrefixes...),
 	}
 }
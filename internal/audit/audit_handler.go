@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/tenant/middlewares"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/response"
+	"go.uber.org/zap"
+)
+
+type Transport interface {
+	GetEntries(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	InitRoutes(router *httprouter.Router)
+}
+
+type auditTransport struct {
+	auditService Service
+	auth         *tenant_middlewares.APIKey
+	logger       *zap.SugaredLogger
+}
+
+func NewAuditTransport(logger *zap.SugaredLogger, service Service, auth *tenant_middlewares.APIKey) Transport {
+	return &auditTransport{logger: logger, auditService: service, auth: auth}
+}
+
+func (a *auditTransport) InitRoutes(router *httprouter.Router) {
+	router.GET("/api/audit", a.auth.Require(entity.ScopeAuditRead, a.GetEntries))
+}
+
+func (a *auditTransport) GetEntries(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), a.logger).Debug(err.Error())
+		return
+	}
+
+	entries, next, err := a.auditService.GetEntries(r.Context(), filter)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), a.logger).Error(err.Error())
+		return
+	}
+
+	//Absent next_cursor means the last page
+	var nextCursor *string
+	if next != 0 {
+		c := strconv.FormatUint(next, 10)
+		nextCursor = &c
+	}
+
+	response.Json(a.logger, w, http.StatusOK, response.JSON{
+		"entries":     entries,
+		"next_cursor": nextCursor,
+	})
+}
+
+//parseFilter reads filter from query, e.g. ?target_type=subscription&since=2022-11-01T00:00:00Z&cursor=120
+func parseFilter(q url.Values) (entity.AuditFilter, error) {
+	filter := entity.AuditFilter{
+		Action:     q.Get("action"),
+		ActorType:  q.Get("actor_type"),
+		ActorID:    q.Get("actor_id"),
+		TargetType: q.Get("target_type"),
+		TargetID:   q.Get("target_id"),
+	}
+
+	for name, dst := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, http_errors.ErrInvalidAuditFilter
+		}
+		*dst = &t
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return filter, http_errors.ErrInvalidAuditFilter
+		}
+		filter.Cursor = cursor
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > MaxLimit {
+			return filter, http_errors.ErrInvalidAuditFilter
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/pkg/actor"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/requestid"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+//Actions of audit entries
+const (
+	ActionCreate      = "create"
+	ActionUpdate      = "update"
+	ActionDelete      = "delete"
+	ActionRevoke      = "revoke"
+	ActionAcknowledge = "acknowledge"
+	ActionDeactivate  = "deactivate"
+	ActionReactivate  = "reactivate"
+	ActionMigrate     = "migrate"
+	ActionImport      = "import"
+	ActionErase       = "erase"
+)
+
+//Types of targets of audit entries
+const (
+	TargetSubscriber        = "subscriber"
+	TargetSubscription      = "subscription"
+	TargetTelegramLink      = "telegram_link"
+	TargetLinkCode          = "link_code"
+	TargetMute              = "mute"
+	TargetGroup             = "group"
+	TargetGroupMember       = "group_member"
+	TargetGroupSubscription = "group_subscription"
+	TargetTemplate          = "template"
+	TargetEscalation        = "escalation"
+	TargetTenant            = "tenant"
+	TargetAPIKey            = "api_key"
+)
+
+//Fields is before or after state of target which isn't an entity
+type Fields map[string]interface{}
+
+type Service interface {
+	Record(ctx context.Context, action string, targetType string, targetID interface{}, before interface{}, after interface{})
+	GetEntries(ctx context.Context, filter entity.AuditFilter) ([]*entity.AuditEntry, uint64, error)
+}
+
+type auditService struct {
+	storage storage.DBStorage
+	logger  *zap.SugaredLogger
+}
+
+func NewAuditService(logger *zap.SugaredLogger, storage storage.DBStorage) Service {
+	return &auditService{logger: logger, storage: storage}
+}
+
+//Record appends the change made by actor of ctx to audit log of tenant of ctx.
+//The change has already happened, so failing to record it is logged rather than returned
+func (s *auditService) Record(ctx context.Context, action string, targetType string, targetID interface{}, before interface{}, after interface{}) {
+	a := actor.FromContext(ctx)
+	e := &entity.AuditEntry{
+		ActorType:  a.Type,
+		ActorID:    a.ID,
+		ActorName:  a.Name,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   fmt.Sprint(targetID),
+		RequestID:  requestid.FromContext(ctx),
+	}
+
+	var err error
+	if e.Before, err = marshal(before); err != nil {
+		logging.FromContext(ctx, s.logger).Errorf("could not audit %s of %s %s. %s", action, targetType, e.TargetID, err.Error())
+		return
+	}
+	if e.After, err = marshal(after); err != nil {
+		logging.FromContext(ctx, s.logger).Errorf("could not audit %s of %s %s. %s", action, targetType, e.TargetID, err.Error())
+		return
+	}
+
+	if err = s.storage.CreateAuditEntry(ctx, e); err != nil {
+		logging.FromContext(ctx, s.logger).Errorf("could not audit %s of %s %s. %s", action, targetType, e.TargetID, err.Error())
+	}
+}
+
+//GetEntries returns a page of entries matching filter, the newest first, and cursor of the next page.
+//Cursor is 0 on the last page
+func (s *auditService) GetEntries(ctx context.Context, filter entity.AuditFilter) ([]*entity.AuditEntry, uint64, error) {
+	if filter.Limit <= 0 || filter.Limit > MaxLimit {
+		filter.Limit = DefaultLimit
+	}
+	limit := filter.Limit
+	//One more entry tells whether there's the next page
+	filter.Limit++
+
+	entries, err := s.storage.GetAuditEntries(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if entries == nil {
+		return []*entity.AuditEntry{}, 0, nil
+	}
+	if len(entries) <= limit {
+		return entries, 0, nil
+	}
+
+	entries = entries[:limit]
+	return entries, entries[limit-1].AuditID, nil
+}
+
+//marshal keeps absent state, e.g. before creation, as nil
+func marshal(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//AuditEntry is a change made by actor to target. Entries are never updated nor deleted
+type AuditEntry struct {
+	AuditID    uint64          `json:"audit_id" db:"audit_id"`
+	TenantID   uint64          `json:"-" db:"tenant_id"`
+	ActorType  string          `json:"actor_type" db:"actor_type"`
+	ActorID    string          `json:"actor_id" db:"actor_id"`
+	ActorName  string          `json:"actor_name" db:"actor_name"`
+	Action     string          `json:"action" db:"action"`
+	TargetType string          `json:"target_type" db:"target_type"`
+	TargetID   string          `json:"target_id" db:"target_id"`
+	Before     json.RawMessage `json:"before" db:"before"`
+	After      json.RawMessage `json:"after" db:"after"`
+	RequestID  string          `json:"request_id" db:"request_id"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+//AuditFilter selects entries older than Cursor, the newest first. Empty fields match everything
+type AuditFilter struct {
+	Action     string
+	ActorType  string
+	ActorID    string
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+	Cursor     uint64
+	Limit      int
+}
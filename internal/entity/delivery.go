@@ -0,0 +1,51 @@
+package entity
+
+import "time"
+
+const (
+	//DeliverySent is recorded when telegram has accepted the message
+	DeliverySent = "sent"
+	//DeliveryFailed is recorded when telegram has refused the message for any reason but unreachable chat
+	DeliveryFailed = "failed"
+	//DeliveryUnreachable is recorded when the chat has blocked the bot or is gone. See telegram_errors.ErrChatUnreachable
+	DeliveryUnreachable = "unreachable"
+	//DeliverySuppressed is recorded for recipients skipped in fan-out because of mute
+	DeliverySuppressed = "suppressed"
+)
+
+//ChannelTelegram is the only channel deliveries are made through for now
+const ChannelTelegram = "telegram"
+
+const (
+	//IdempotencyPending is state of the key while the fire with it is in progress
+	IdempotencyPending = "pending"
+	//IdempotencyPartial is state of the key when the fire has reached some recipients only. Such key is claimed again on retry
+	IdempotencyPartial = "partial"
+	//IdempotencyDone is state of the key when the fire is over
+	IdempotencyDone = "done"
+)
+
+type Delivery struct {
+	DeliveryID uint64  `json:"delivery_id" db:"delivery_id"`
+	FireID     *uint64 `json:"fire_id" db:"fire_id"`
+	EventID    uint64  `json:"event_id" db:"event_id"`
+	//SubscriberID is nil once the subscriber is erased
+	SubscriberID *uint64 `json:"subscriber_id" db:"subscriber_id"`
+	Channel      string  `json:"channel" db:"channel"`
+	TelegramID   *int64  `json:"telegram_id" db:"telegram_id"`
+	Status       string  `json:"status" db:"status"`
+	Error        string  `json:"error,omitempty" db:"error"`
+	//LatencyMs is time from fire to telegram accepting the message. Only sent deliveries have it
+	LatencyMs *int64    `json:"latency_ms" db:"latency_ms"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+//Fire is a single fire of an event, whatever it ended with. Status is one of response_object.Fire*
+type Fire struct {
+	FireID     uint64    `json:"fire_id" db:"fire_id"`
+	EventID    uint64    `json:"event_id" db:"event_id"`
+	Status     string    `json:"status" db:"status"`
+	Recipients int       `json:"recipients" db:"recipients"`
+	RequestID  string    `json:"request_id" db:"request_id"`
+	FiredAt    time.Time `json:"fired_at" db:"fired_at"`
+}
@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+const (
+	EscalationPending      = "pending"
+	EscalationAcknowledged = "acknowledged"
+	EscalationExhausted    = "exhausted"
+)
+
+type Escalation struct {
+	EscalationID     uint64     `json:"escalation_id" db:"escalation_id"`
+	EventID          uint64     `json:"event_id" db:"event_id"`
+	Text             string     `json:"text" db:"text"`
+	Tier             int        `json:"tier" db:"tier"`
+	Status           string     `json:"status" db:"status"`
+	AcknowledgedBy   *int64     `json:"acknowledged_by" db:"acknowledged_by"`
+	AcknowledgedAt   *time.Time `json:"acknowledged_at" db:"acknowledged_at"`
+	NextEscalationAt *time.Time `json:"next_escalation_at" db:"next_escalation_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	TenantID         uint64     `json:"-" db:"tenant_id"`
+}
+
+type EscalationTier struct {
+	PhoneNumbers   []string `json:"phone_numbers"`
+	Groups         []string `json:"groups"`
+	TimeoutMinutes int      `json:"timeout_minutes"`
+}
+
+//EscalationPolicy is policy of the event of the tenant. Event ids are the same in every tenant, so the policy names both
+type EscalationPolicy struct {
+	//Tenant is name of the tenant, default tenant if empty
+	Tenant string `json:"tenant"`
+	Event  string `json:"event"`
+	//TimeoutMinutes is how long to wait for acknowledgement after the initial fire
+	TimeoutMinutes int              `json:"timeout_minutes"`
+	Tiers          []EscalationTier `json:"tiers"`
+}
+
+type EscalationPolicies struct {
+	Policies []EscalationPolicy `json:"policies"`
+}
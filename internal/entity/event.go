@@ -8,4 +8,5 @@ type Event struct {
 	EventID   uint64 `json:"event_id,omitempty" db:"event_id"`
 	Name      string `json:"name" db:"name"`
 	Translate string `json:"translate" db:"translate"`
+	TenantID  uint64 `json:"-" db:"tenant_id"`
 }
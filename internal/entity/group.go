@@ -0,0 +1,17 @@
+package entity
+
+type Group struct {
+	GroupID  uint64 `json:"group_id" db:"group_id"`
+	Name     string `json:"name" db:"name"`
+	TenantID uint64 `json:"-" db:"tenant_id"`
+}
+
+type GroupMember struct {
+	GroupID      uint64 `json:"group_id" db:"group_id"`
+	SubscriberID uint64 `json:"subscriber_id" db:"subscriber_id"`
+}
+
+type GroupSubscription struct {
+	GroupID uint64 `json:"group_id" db:"group_id"`
+	EventID uint64 `json:"event_id" db:"event_id"`
+}
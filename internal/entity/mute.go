@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+type Mute struct {
+	MuteID       uint64 `json:"mute_id" db:"mute_id"`
+	SubscriberID uint64 `json:"subscriber_id" db:"subscriber_id"`
+	//EventID is nil when every event is muted
+	EventID    *uint64   `json:"event_id" db:"event_id"`
+	MutedUntil time.Time `json:"muted_until" db:"muted_until"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	TenantID   uint64    `json:"-" db:"tenant_id"`
+}
@@ -0,0 +1,87 @@
+package entity
+
+import "time"
+
+//Buckets of delivery stats
+const (
+	BucketDay  = "day"
+	BucketHour = "hour"
+)
+
+//StatsFilter selects fires and deliveries made in [From, To). Empty EventName matches every event.
+//Limit is the number of top failing recipients
+type StatsFilter struct {
+	From      time.Time
+	To        time.Time
+	Bucket    string
+	EventName string
+	Limit     int
+}
+
+//DeliveryCounts are counts of deliveries by status and latency of sent ones, in ms
+type DeliveryCounts struct {
+	Sent        int64    `json:"sent" db:"sent"`
+	Failed      int64    `json:"failed" db:"failed"`
+	Unreachable int64    `json:"unreachable" db:"unreachable"`
+	Suppressed  int64    `json:"suppressed" db:"suppressed"`
+	SuccessRate *float64 `json:"success_rate" db:"-"`
+	FailureRate *float64 `json:"failure_rate" db:"-"`
+	LatencyP50  *float64 `json:"latency_p50_ms" db:"latency_p50"`
+	LatencyP95  *float64 `json:"latency_p95_ms" db:"latency_p95"`
+}
+
+//SetRates computes rates of deliveries actually sent to telegram, i.e. not suppressed.
+//Rates are nil when nothing has been sent
+func (c *DeliveryCounts) SetRates() {
+	attempted := c.Sent + c.Failed + c.Unreachable
+	if attempted == 0 {
+		c.SuccessRate, c.FailureRate = nil, nil
+		return
+	}
+	success := float64(c.Sent) / float64(attempted)
+	failure := 1 - success
+	c.SuccessRate, c.FailureRate = &success, &failure
+}
+
+type TotalStats struct {
+	Fires int64 `json:"fires" db:"fires"`
+	DeliveryCounts
+}
+
+type EventStats struct {
+	EventName string `json:"event_name" db:"event_name"`
+	Fires     int64  `json:"fires" db:"fires"`
+	DeliveryCounts
+}
+
+type ChannelStats struct {
+	Channel string `json:"channel" db:"channel"`
+	DeliveryCounts
+}
+
+type BucketStats struct {
+	Start time.Time `json:"start" db:"start"`
+	Fires int64     `json:"fires" db:"fires"`
+	DeliveryCounts
+}
+
+//FailingRecipient is a subscriber telegram hasn't delivered to the most
+type FailingRecipient struct {
+	SubscriberID uint64    `json:"subscriber_id" db:"subscriber_id"`
+	PhoneNumber  string    `json:"phone_number" db:"phone_number"`
+	Failed       int64     `json:"failed" db:"failed"`
+	Unreachable  int64     `json:"unreachable" db:"unreachable"`
+	LastError    string    `json:"last_error" db:"last_error"`
+	LastFailedAt time.Time `json:"last_failed_at" db:"last_failed_at"`
+}
+
+type Stats struct {
+	From                 time.Time           `json:"from"`
+	To                   time.Time           `json:"to"`
+	Bucket               string              `json:"bucket"`
+	Totals               TotalStats          `json:"totals"`
+	Events               []*EventStats       `json:"events"`
+	Channels             []*ChannelStats     `json:"channels"`
+	Buckets              []*BucketStats      `json:"buckets"`
+	TopFailingRecipients []*FailingRecipient `json:"top_failing_recipients"`
+}
@@ -1,6 +1,33 @@
 package entity
 
+import "time"
+
 type Subscriber struct {
 	SubscriberID uint64 `json:"subscriber_id" db:"subscriber_id"`
 	PhoneNumber  string `json:"phone_number" db:"phone_number"`
+	TenantID     uint64 `json:"-" db:"tenant_id"`
+	//Inactive subscriber keeps subscriptions and telegram links, but is skipped in fan-out
+	Active        bool       `json:"active" db:"active"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty" db:"deactivated_at"`
+}
+
+//Sorts of subscriber listings. Leading minus is descending order
+const (
+	SortPhoneNumber     = "phone_number"
+	SortPhoneNumberDesc = "-phone_number"
+	SortSubscriberID    = "subscriber_id"
+	SortSubscriberDesc  = "-subscriber_id"
+)
+
+//SubscriberFilter selects a page of subscribers after the one with sort value After. Empty fields match everything.
+//Cursor is the opaque form of Sort and After given to clients
+type SubscriberFilter struct {
+	PhonePrefix             string
+	EventName               string
+	HasTelegramSubscription *bool
+	GroupName               string
+	Sort                    string
+	Cursor                  string
+	After                   string
+	Limit                   int
 }
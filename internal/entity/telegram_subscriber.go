@@ -1,6 +1,33 @@
 package entity
 
+import "time"
+
+//TelegramSubscriber is a link between subscriber and telegram chat.
+//Subscriber might have many links and the chat might be linked with many subscribers
 type TelegramSubscriber struct {
+	LinkID       uint64 `json:"link_id" db:"link_id"`
 	SubscriberID uint64 `json:"subscriber_id" db:"subscriber_id"`
 	TelegramID   int64  `json:"telegram_id" db:"telegram_id"`
+	ChatType     string `json:"chat_type" db:"chat_type"`
+	//BotName is the bot link was made with. Telegram chat ids are per bot
+	BotName string `json:"bot_name" db:"bot_name"`
+	//ThreadID is forum topic in supergroup, 0 otherwise
+	ThreadID int `json:"thread_id,omitempty" db:"thread_id"`
+	//Nothing is sent through disabled link
+	Enabled bool `json:"enabled" db:"enabled"`
+	//Link is inactive when telegram refuses to deliver to the chat, e.g. user has blocked the bot
+	Active         bool       `json:"active" db:"active"`
+	InactiveReason string     `json:"inactive_reason,omitempty" db:"inactive_reason"`
+	InactiveSince  *time.Time `json:"inactive_since,omitempty" db:"inactive_since"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+//LinkCode is issued by admin to link group, supergroup or channel chat with subscriber via /link command
+type LinkCode struct {
+	Code         string    `json:"code" db:"code"`
+	SubscriberID uint64    `json:"subscriber_id" db:"subscriber_id"`
+	ThreadID     *int      `json:"thread_id" db:"thread_id"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	TenantID     uint64    `json:"-" db:"tenant_id"`
 }
@@ -1,8 +1,8 @@
 package entity
 
 type Template struct {
-	EventID uint64 `json:"event_id"`
-	Text    string `json:"text"`
+	EventID uint64 `json:"event_id" db:"event_id"`
+	Text    string `json:"text" db:"text"`
 }
 
 type Templates struct {
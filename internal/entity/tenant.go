@@ -0,0 +1,61 @@
+package entity
+
+import "time"
+
+type Tenant struct {
+	TenantID  uint64    `json:"tenant_id" db:"tenant_id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+//Scopes of api keys. Every route requires one of them
+const (
+	ScopeAll                = "*"
+	ScopeEventsRead         = "events:read"
+	ScopeEventsFire         = "events:fire"
+	ScopeTemplatesWrite     = "templates:write"
+	ScopeSubscribersRead    = "subscribers:read"
+	ScopeSubscribersWrite   = "subscribers:write"
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	ScopeGroupsRead         = "groups:read"
+	ScopeGroupsWrite        = "groups:write"
+	ScopeEscalationsRead    = "escalations:read"
+	ScopeAuditRead          = "audit:read"
+	ScopeStatsRead          = "stats:read"
+)
+
+var Scopes = []string{
+	ScopeAll,
+	ScopeEventsRead,
+	ScopeEventsFire,
+	ScopeTemplatesWrite,
+	ScopeSubscribersRead,
+	ScopeSubscribersWrite,
+	ScopeSubscriptionsWrite,
+	ScopeGroupsRead,
+	ScopeGroupsWrite,
+	ScopeEscalationsRead,
+	ScopeAuditRead,
+	ScopeStatsRead,
+}
+
+//APIKey is identified by sha256 of the key. The key itself is shown only once on creation
+type APIKey struct {
+	APIKeyID   uint64     `json:"api_key_id" db:"api_key_id"`
+	TenantID   uint64     `json:"tenant_id" db:"tenant_id"`
+	Name       string     `json:"name" db:"name"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+//HasScope reports whether the key is allowed to access routes requiring scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == ScopeAll || s == scope {
+			return true
+		}
+	}
+	return false
+}
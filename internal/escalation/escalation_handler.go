@@ -0,0 +1,74 @@
+package escalation
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/tenant/middlewares"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/response"
+	"go.uber.org/zap"
+)
+
+type Transport interface {
+	GetEscalation(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetEscalations(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	InitRoutes(router *httprouter.Router)
+}
+
+type escalationTransport struct {
+	escalationService Service
+	auth              *tenant_middlewares.APIKey
+	logger            *zap.SugaredLogger
+}
+
+func NewEscalationTransport(logger *zap.SugaredLogger, service Service, auth *tenant_middlewares.APIKey) Transport {
+	return &escalationTransport{logger: logger, escalationService: service, auth: auth}
+}
+
+func (e *escalationTransport) InitRoutes(router *httprouter.Router) {
+	router.GET("/api/escalations", e.auth.Require(entity.ScopeEscalationsRead, e.GetEscalations))
+	router.GET("/api/escalations/:escalationId", e.auth.Require(entity.ScopeEscalationsRead, e.GetEscalation))
+}
+
+func (e *escalationTransport) GetEscalation(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	escalationIDstr := params.ByName("escalationId")
+
+	escalationID, err := strconv.ParseUint(escalationIDstr, 10, 64)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidEscalationID)
+		logging.FromContext(r.Context(), e.logger).Debug(err.Error())
+		return
+	}
+
+	esc, err := e.escalationService.GetEscalation(r.Context(), escalationID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), e.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(e.logger, w, http.StatusOK, response.JSON{
+		"escalation": esc,
+	})
+}
+
+func (e *escalationTransport) GetEscalations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	status := r.URL.Query().Get("status")
+
+	escs, err := e.escalationService.GetEscalations(r.Context(), status)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), e.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(e.logger, w, http.StatusOK, response.JSON{
+		"escalations": escs,
+	})
+}
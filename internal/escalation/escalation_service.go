@@ -0,0 +1,302 @@
+package escalation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/audit"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/pkg/bot"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/message"
+	"github.com/sonyamoonglade/notification-service/pkg/telegram_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+	"go.uber.org/zap"
+)
+
+var path = "./escalations.json"
+
+type Service interface {
+	ReadPolicies() error
+	PolicyTenants() []string
+	ResolvePolicies(tenantID uint64, tenantName string, events []*entity.Event) error
+	GetPolicy(ctx context.Context, eventID uint64) (entity.EscalationPolicy, bool)
+	Start(ctx context.Context, eventID uint64, text string) (uint64, error)
+	Cancel(ctx context.Context, escalationID uint64) error
+	Acknowledge(ctx context.Context, escalationID uint64, telegramID int64) error
+	GetEscalation(ctx context.Context, escalationID uint64) (*entity.Escalation, error)
+	GetEscalations(ctx context.Context, status string) ([]*entity.Escalation, error)
+	EscalateDue(ctx context.Context) error
+}
+
+type escalationService struct {
+	storage      storage.DBStorage
+	auditService audit.Service
+	logger       *zap.SugaredLogger
+	bots         *bot.Registry
+	//namedPolicies is tenant name -> event name -> policy. Resolved into policies by ResolvePolicies
+	namedPolicies map[string]map[string]entity.EscalationPolicy
+	//policies is tenant id -> event id -> policy
+	policies map[uint64]map[uint64]entity.EscalationPolicy
+}
+
+func NewEscalationService(logger *zap.SugaredLogger, storage storage.DBStorage, auditService audit.Service, bots *bot.Registry) Service {
+	return &escalationService{
+		logger:        logger,
+		storage:       storage,
+		auditService:  auditService,
+		bots:          bots,
+		namedPolicies: make(map[string]map[string]entity.EscalationPolicy),
+		policies:      make(map[uint64]map[uint64]entity.EscalationPolicy),
+	}
+}
+
+//ReadPolicies reads escalations.json. Escalations are optional, so missing file means no event is escalated
+func (s *escalationService) ReadPolicies() error {
+	_, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.logger.Infof("%s is not found, events won't be escalated", path)
+			return nil
+		}
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	bytes, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	var content entity.EscalationPolicies
+	if err := json.Unmarshal(bytes, &content); err != nil {
+		return err
+	}
+
+	for _, p := range content.Policies {
+		if p.Tenant == "" {
+			p.Tenant = tenancy.DefaultName
+		}
+		if p.Event == "" {
+			return fmt.Errorf("escalation policy of tenant %s has no event", p.Tenant)
+		}
+		if p.TimeoutMinutes <= 0 {
+			return fmt.Errorf("escalation policy for event %s of tenant %s has invalid timeout", p.Event, p.Tenant)
+		}
+		for i, tier := range p.Tiers {
+			if tier.TimeoutMinutes <= 0 {
+				return fmt.Errorf("escalation tier %d for event %s of tenant %s has invalid timeout", i, p.Event, p.Tenant)
+			}
+		}
+		if _, ok := s.namedPolicies[p.Tenant]; ok != true {
+			s.namedPolicies[p.Tenant] = make(map[string]entity.EscalationPolicy)
+		}
+		if _, ok := s.namedPolicies[p.Tenant][p.Event]; ok {
+			return fmt.Errorf("duplicate escalation policy for event %s of tenant %s", p.Event, p.Tenant)
+		}
+		s.namedPolicies[p.Tenant][p.Event] = p
+		s.logger.Infof("escalation policy for event %s of tenant %s is ok", p.Event, p.Tenant)
+	}
+
+	return nil
+}
+
+//PolicyTenants returns names of tenants having escalation policies
+func (s *escalationService) PolicyTenants() []string {
+	names := make([]string, 0, len(s.namedPolicies))
+	for name := range s.namedPolicies {
+		names = append(names, name)
+	}
+	return names
+}
+
+//ResolvePolicies maps event names of the tenant policies to ids. Must be called before GetPolicy once events are registered
+func (s *escalationService) ResolvePolicies(tenantID uint64, tenantName string, events []*entity.Event) error {
+	known := make(map[string]bool, len(events))
+	resolved := make(map[uint64]entity.EscalationPolicy)
+	for _, e := range events {
+		known[e.Name] = true
+		if p, ok := s.namedPolicies[tenantName][e.Name]; ok {
+			resolved[e.EventID] = p
+		}
+	}
+	for name := range s.namedPolicies[tenantName] {
+		if known[name] != true {
+			return fmt.Errorf("escalation policy of tenant %s is for unknown event %s", tenantName, name)
+		}
+	}
+	s.policies[tenantID] = resolved
+	return nil
+}
+
+//GetPolicy returns policy of the event of tenant of ctx
+func (s *escalationService) GetPolicy(ctx context.Context, eventID uint64) (entity.EscalationPolicy, bool) {
+	tenantID, _ := tenancy.FromContext(ctx)
+	p, ok := s.policies[tenantID][eventID]
+	return p, ok
+}
+
+//Start creates pending escalation for fired event. Caller is responsible for the initial broadcast and cancels the escalation if it has reached nobody
+func (s *escalationService) Start(ctx context.Context, eventID uint64, text string) (uint64, error) {
+	p, ok := s.GetPolicy(ctx, eventID)
+	if ok != true {
+		return 0, fmt.Errorf("no escalation policy for event %d", eventID)
+	}
+	return s.storage.CreateEscalation(ctx, eventID, text, p.TimeoutMinutes)
+}
+
+//Cancel drops escalation which initial broadcast has reached nobody, so there's nothing to acknowledge
+func (s *escalationService) Cancel(ctx context.Context, escalationID uint64) error {
+	_, err := s.storage.DeleteEscalation(ctx, escalationID)
+	return err
+}
+
+func (s *escalationService) Acknowledge(ctx context.Context, escalationID uint64, telegramID int64) error {
+	ok, err := s.storage.AcknowledgeEscalation(ctx, escalationID, telegramID)
+	if err != nil {
+		return err
+	}
+	//Either already acknowledged, exhausted or never existed
+	if ok != true {
+		return http_errors.ErrEscalationAlreadyHandled
+	}
+
+	//Acknowledging telegram user is the actor of the entry, see storage.EraseSubscriber
+	s.auditService.Record(ctx, audit.ActionAcknowledge, audit.TargetEscalation, escalationID, nil, audit.Fields{
+		"status": entity.EscalationAcknowledged,
+	})
+	return nil
+}
+
+func (s *escalationService) GetEscalation(ctx context.Context, escalationID uint64) (*entity.Escalation, error) {
+	esc, err := s.storage.GetEscalation(ctx, escalationID)
+	if err != nil {
+		return nil, err
+	}
+	if esc == nil {
+		return nil, http_errors.ErrEscalationDoesNotExist
+	}
+	return esc, nil
+}
+
+func (s *escalationService) GetEscalations(ctx context.Context, status string) ([]*entity.Escalation, error) {
+	switch status {
+	case "", entity.EscalationPending, entity.EscalationAcknowledged, entity.EscalationExhausted:
+		return s.storage.GetEscalations(ctx, status)
+	default:
+		return nil, http_errors.ErrInvalidPayload
+	}
+}
+
+//EscalateDue notifies the next tier of every escalation which deadline has passed, in every tenant.
+//Failure of one tenant doesn't hold escalations of the others
+func (s *escalationService) EscalateDue(ctx context.Context) error {
+	tenants, err := s.storage.GetTenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tenants {
+		tenantCtx := tenancy.WithID(ctx, t.TenantID)
+		if err := s.escalateTenantDue(tenantCtx); err != nil {
+			logging.FromContext(tenantCtx, s.logger).Errorf("could not escalate due escalations of tenant %d. %s", t.TenantID, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (s *escalationService) escalateTenantDue(ctx context.Context) error {
+	due, err := s.storage.GetDueEscalations(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, esc := range due {
+		p, ok := s.GetPolicy(ctx, esc.EventID)
+		//Policy might be removed from escalations.json after restart, or all tiers are already notified
+		if ok != true || esc.Tier >= len(p.Tiers) {
+			_, err := s.storage.ExhaustEscalation(ctx, esc.EscalationID, esc.Tier)
+			if err != nil {
+				return err
+			}
+			logging.FromContext(ctx, s.logger).Infof("escalation %d is exhausted", esc.EscalationID)
+			continue
+		}
+
+		tier := p.Tiers[esc.Tier]
+		//Promote before notifying, so other replica won't notify the same tier twice
+		ok, err := s.storage.PromoteEscalation(ctx, esc.EscalationID, esc.Tier, tier.TimeoutMinutes)
+		if err != nil {
+			return err
+		}
+		if ok != true {
+			continue
+		}
+
+		s.notifyTier(ctx, esc, tier)
+	}
+
+	return nil
+}
+
+func (s *escalationService) notifyTier(ctx context.Context, esc *entity.Escalation, tier entity.EscalationTier) {
+	phoneNumbers := append([]string{}, tier.PhoneNumbers...)
+
+	//Group membership is resolved at the moment of escalation
+	if len(tier.Groups) != 0 {
+		members, err := s.storage.GetGroupsMembers(ctx, tier.Groups)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error(err.Error())
+			return
+		}
+		for _, m := range members {
+			phoneNumbers = append(phoneNumbers, m.PhoneNumber)
+		}
+	}
+
+	if len(phoneNumbers) == 0 {
+		return
+	}
+
+	//Escalation is sent by the same bot as the event
+	eventBot := s.bots.ForEvent(esc.TenantID, esc.EventID)
+
+	telegramSubs, err := s.storage.GetTelegramSubscribers(ctx, eventBot.Name(), phoneNumbers)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error(err.Error())
+		return
+	}
+
+	text := message.Format(message.Escalated, esc.Text)
+	kb := eventBot.AckKeyboard(esc.EscalationID)
+
+	for _, sub := range telegramSubs {
+		//Failure of one recipient should not stop escalation to the others
+		if err := eventBot.NotifyWithKeyboard(ctx, sub.TelegramID, sub.ThreadID, text, kb); err != nil {
+			logging.FromContext(ctx, s.logger).Error(err.Error())
+			if errors.Is(err, telegram_errors.ErrChatUnreachable) {
+				s.deactivate(ctx, eventBot.Name(), sub.TelegramID, telegram_errors.Reason(err))
+			}
+		}
+	}
+	logging.FromContext(ctx, s.logger).Infof("escalation %d reached tier %d", esc.EscalationID, esc.Tier+1)
+}
+
+func (s *escalationService) deactivate(ctx context.Context, botName string, telegramID int64, reason string) {
+	_, err := s.storage.DeactivateTelegramChat(ctx, botName, telegramID, reason)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error(err.Error())
+	}
+}
@@ -0,0 +1,202 @@
+package escalation_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/escalation"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/pkg/bot"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const policies = `{
+  "policies": [
+    {
+      "event": "user_order_create",
+      "timeout_minutes": 5,
+      "tiers": [
+        {"phone_numbers": ["+79990001122"], "timeout_minutes": 10},
+        {"groups": ["managers"], "timeout_minutes": 10}
+      ]
+    }
+  ]
+}`
+
+//escalationStorage keeps escalations of default tenant, every one of them is due. Other tenants fail
+type escalationStorage struct {
+	storage.DBStorage
+	escalations map[uint64]*entity.Escalation
+	//telegramIDs is phone number -> telegram id of its link
+	telegramIDs map[string]int64
+	managers    []*entity.Subscriber
+}
+
+func (f *escalationStorage) GetTenants(_ context.Context) ([]*entity.Tenant, error) {
+	return []*entity.Tenant{{TenantID: 2}, {TenantID: tenancy.DefaultID}}, nil
+}
+
+func (f *escalationStorage) GetDueEscalations(ctx context.Context) ([]*entity.Escalation, error) {
+	if tenantID, _ := tenancy.FromContext(ctx); tenantID != tenancy.DefaultID {
+		return nil, errors.New("connection refused")
+	}
+	var due []*entity.Escalation
+	for _, esc := range f.escalations {
+		if esc.Status == entity.EscalationPending {
+			e := *esc
+			due = append(due, &e)
+		}
+	}
+	return due, nil
+}
+
+func (f *escalationStorage) PromoteEscalation(_ context.Context, escalationID uint64, fromTier int, _ int) (bool, error) {
+	esc := f.escalations[escalationID]
+	if esc.Tier != fromTier || esc.Status != entity.EscalationPending {
+		return false, nil
+	}
+	esc.Tier++
+	return true, nil
+}
+
+func (f *escalationStorage) ExhaustEscalation(_ context.Context, escalationID uint64, fromTier int) (bool, error) {
+	esc := f.escalations[escalationID]
+	if esc.Tier != fromTier || esc.Status != entity.EscalationPending {
+		return false, nil
+	}
+	esc.Status = entity.EscalationExhausted
+	return true, nil
+}
+
+func (f *escalationStorage) GetGroupsMembers(_ context.Context, groupNames []string) ([]*entity.Subscriber, error) {
+	if len(groupNames) == 1 && groupNames[0] == "managers" {
+		return f.managers, nil
+	}
+	return nil, nil
+}
+
+func (f *escalationStorage) GetTelegramSubscribers(_ context.Context, _ string, phoneNumbers []string) ([]*entity.TelegramSubscriber, error) {
+	var subs []*entity.TelegramSubscriber
+	for _, phone := range phoneNumbers {
+		if telegramID, ok := f.telegramIDs[phone]; ok {
+			subs = append(subs, &entity.TelegramSubscriber{TelegramID: telegramID})
+		}
+	}
+	return subs, nil
+}
+
+//fakeBot remembers chats it has notified
+type fakeBot struct {
+	bot.Bot
+	notified []int64
+}
+
+func (f *fakeBot) Name() string {
+	return bot.DefaultName
+}
+
+func (f *fakeBot) AckKeyboard(_ uint64) tg.InlineKeyboardMarkup {
+	return tg.InlineKeyboardMarkup{}
+}
+
+func (f *fakeBot) NotifyWithKeyboard(_ context.Context, receiverID int64, _ int, _ string, _ tg.InlineKeyboardMarkup) error {
+	f.notified = append(f.notified, receiverID)
+	return nil
+}
+
+//readPolicies reads content as escalations.json of working directory. Empty content is missing file
+func readPolicies(t *testing.T, service escalation.Service, content string) error {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	if content != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "escalations.json"), []byte(content), 0o600))
+	}
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	return service.ReadPolicies()
+}
+
+func TestEscalateDueNotifiesTiersInOrder(t *testing.T) {
+	db := &escalationStorage{
+		escalations: map[uint64]*entity.Escalation{
+			10: {EscalationID: 10, EventID: 2, Status: entity.EscalationPending, TenantID: tenancy.DefaultID},
+		},
+		telegramIDs: map[string]int64{"+79990001122": 100, "+79993334455": 200},
+		managers:    []*entity.Subscriber{{SubscriberID: 5, PhoneNumber: "+79993334455"}},
+	}
+	b := &fakeBot{}
+	bots := bot.NewRegistry()
+	require.NoError(t, bots.Add(b, tenancy.DefaultID, nil))
+
+	service := escalation.NewEscalationService(zap.NewNop().Sugar(), db, nil, bots)
+	require.NoError(t, readPolicies(t, service, policies))
+	require.NoError(t, service.ResolvePolicies(tenancy.DefaultID, tenancy.DefaultName, []*entity.Event{
+		{EventID: 2, Name: "user_order_create"},
+	}))
+
+	//Policies are of the tenant they name only
+	_, ok := service.GetPolicy(tenancy.WithID(context.Background(), tenancy.DefaultID), 2)
+	assert.True(t, ok)
+	_, ok = service.GetPolicy(tenancy.WithID(context.Background(), 2), 2)
+	assert.False(t, ok)
+
+	//Failing tenant 2 goes first and doesn't hold escalations of default tenant
+	require.NoError(t, service.EscalateDue(context.Background()))
+	assert.Equal(t, []int64{100}, b.notified)
+	assert.Equal(t, 1, db.escalations[10].Tier)
+
+	//Group of the next tier is resolved at the moment of escalation
+	require.NoError(t, service.EscalateDue(context.Background()))
+	assert.Equal(t, []int64{100, 200}, b.notified)
+	assert.Equal(t, 2, db.escalations[10].Tier)
+
+	//Every tier is notified, so nobody is left to escalate to
+	require.NoError(t, service.EscalateDue(context.Background()))
+	assert.Equal(t, []int64{100, 200}, b.notified)
+	assert.Equal(t, entity.EscalationExhausted, db.escalations[10].Status)
+}
+
+func TestReadPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		valid   bool
+	}{
+		{"missing file", "", true},
+		{"no policies", `{"policies": []}`, true},
+		{"missing event", `{"policies": [{"timeout_minutes": 5}]}`, false},
+		{"invalid timeout", `{"policies": [{"event": "worker_login", "timeout_minutes": 0}]}`, false},
+		{"invalid tier timeout", `{"policies": [{"event": "worker_login", "timeout_minutes": 5, "tiers": [{"timeout_minutes": -1}]}]}`, false},
+		{"duplicate", `{"policies": [{"event": "worker_login", "timeout_minutes": 5}, {"tenant": "default", "event": "worker_login", "timeout_minutes": 5}]}`, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := escalation.NewEscalationService(zap.NewNop().Sugar(), nil, nil, bot.NewRegistry())
+			err := readPolicies(t, service, tc.content)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestResolvePoliciesOfUnknownEvent(t *testing.T) {
+	service := escalation.NewEscalationService(zap.NewNop().Sugar(), nil, nil, bot.NewRegistry())
+	require.NoError(t, readPolicies(t, service, policies))
+
+	err := service.ResolvePolicies(tenancy.DefaultID, tenancy.DefaultName, []*entity.Event{{EventID: 3, Name: "worker_login"}})
+	assert.Error(t, err)
+}
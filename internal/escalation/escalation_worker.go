@@ -0,0 +1,39 @@
+package escalation
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultInterval = time.Second * 15
+
+type Worker struct {
+	logger   *zap.SugaredLogger
+	service  Service
+	interval time.Duration
+}
+
+func NewWorker(logger *zap.SugaredLogger, service Service) *Worker {
+	return &Worker{logger: logger, service: service, interval: defaultInterval}
+}
+
+//Run blocks until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tctx, cancel := context.WithTimeout(ctx, w.interval)
+			if err := w.service.EscalateDue(tctx); err != nil {
+				w.logger.Errorf("could not escalate. %s", err.Error())
+			}
+			cancel()
+		}
+	}
+}
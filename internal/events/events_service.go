@@ -3,14 +3,18 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"os"
 
+	"github.com/sonyamoonglade/notification-service/internal/audit"
 	"github.com/sonyamoonglade/notification-service/internal/entity"
 	"github.com/sonyamoonglade/notification-service/internal/events/payload"
 	"github.com/sonyamoonglade/notification-service/internal/storage"
 	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
 	"github.com/sonyamoonglade/notification-service/pkg/template"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
 
 	"go.uber.org/zap"
 )
@@ -22,16 +26,28 @@ type Service interface {
 	DoesExist(ctx context.Context, eventName string) (uint64, error)
 	RegisterEvent(ctx context.Context, e entity.Event) error
 	GetAvailableEvents(ctx context.Context) ([]*entity.Event, error)
+	RegisterCatalog(ctx context.Context) error
+	GetTemplate(ctx context.Context, eventID uint64) (string, error)
+	SetTemplate(ctx context.Context, eventID uint64, text string) error
+	DeleteTemplate(ctx context.Context, eventID uint64) error
+	//Loaded fails unless events.json is read and every event of it has a template
+	Loaded(ctx context.Context) error
 }
 
 type eventService struct {
 	storage          storage.DBStorage
+	auditService     audit.Service
 	logger           *zap.SugaredLogger
 	templateProvider template.Provider
+	//catalog is events.json. Every tenant has the same events
+	catalog []entity.Event
 }
 
-func NewEventsService(logger *zap.SugaredLogger, storage storage.DBStorage, templateProvider template.Provider) Service {
-	return &eventService{logger: logger, storage: storage, templateProvider: templateProvider}
+func NewEventsService(logger *zap.SugaredLogger,
+	storage storage.DBStorage,
+	auditService audit.Service,
+	templateProvider template.Provider) Service {
+	return &eventService{logger: logger, storage: storage, auditService: auditService, templateProvider: templateProvider}
 }
 
 func (s *eventService) GetAvailableEvents(ctx context.Context) ([]*entity.Event, error) {
@@ -41,6 +57,7 @@ func (s *eventService) RegisterEvent(ctx context.Context, e entity.Event) error
 	return s.storage.RegisterEvent(ctx, e)
 }
 
+//ReadEvents reads events.json and registers its events in every tenant
 func (s *eventService) ReadEvents(ctx context.Context) error {
 	_, err := os.Stat(path)
 	if err != nil {
@@ -84,15 +101,98 @@ func (s *eventService) ReadEvents(ctx context.Context) error {
 		}
 		s.logger.Infof("template for event %d is ok", e.EventID)
 
+		s.catalog = append(s.catalog, event)
+	}
+
+	tenants, err := s.storage.GetTenants(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range tenants {
+		if err := s.RegisterCatalog(tenancy.WithID(ctx, t.TenantID)); err != nil {
+			return err
+		}
+		s.logger.Infof("events of tenant %s are ready to be fired", t.Name)
+	}
+
+	return nil
+}
+
+func (s *eventService) Loaded(ctx context.Context) error {
+	if len(s.catalog) == 0 {
+		return errors.New("events are not loaded")
+	}
+	for _, e := range s.catalog {
+		if _, err := s.templateProvider.Find(e.EventID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//RegisterCatalog registers events read by ReadEvents in tenant of ctx
+func (s *eventService) RegisterCatalog(ctx context.Context) error {
+	for _, event := range s.catalog {
 		//Register/justify event to be fired
-		err = s.RegisterEvent(ctx, event)
+		err := s.RegisterEvent(ctx, event)
 		if err != nil {
-			s.logger.Errorf("could not register base event. %s", err.Error())
+			logging.FromContext(ctx, s.logger).Errorf("could not register base event. %s", err.Error())
 			return err
 		}
-		s.logger.Infof("event %s is ready to be fired", e.Name)
+	}
+	return nil
+}
+
+//GetTemplate returns template of the event overridden by tenant of ctx, the one from templates.json otherwise
+func (s *eventService) GetTemplate(ctx context.Context, eventID uint64) (string, error) {
+	tmpl, err := s.storage.GetTemplate(ctx, eventID)
+	if err != nil {
+		return "", err
+	}
+	if tmpl != nil {
+		return tmpl.Text, nil
+	}
+	return s.templateProvider.Find(eventID)
+}
+
+func (s *eventService) SetTemplate(ctx context.Context, eventID uint64, text string) error {
+	before, err := s.storage.GetTemplate(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.SetTemplate(ctx, eventID, text); err != nil {
+		return err
+	}
+
+	//Absent before means the event used template from templates.json
+	action := audit.ActionUpdate
+	if before == nil {
+		action = audit.ActionCreate
+	}
+	s.auditService.Record(ctx, action, audit.TargetTemplate, eventID, before, entity.Template{EventID: eventID, Text: text})
+	return nil
+}
+
+//DeleteTemplate makes tenant of ctx use template from templates.json again
+func (s *eventService) DeleteTemplate(ctx context.Context, eventID uint64) error {
+	before, err := s.storage.GetTemplate(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return http_errors.ErrTemplateDoesNotExist
+	}
+
+	ok, err := s.storage.DeleteTemplate(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return http_errors.ErrTemplateDoesNotExist
 	}
 
+	s.auditService.Record(ctx, audit.ActionDelete, audit.TargetTemplate, eventID, before, nil)
 	return nil
 }
 
@@ -7,6 +7,10 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/sonyamoonglade/notification-service/internal/events"
 	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -29,14 +33,16 @@ func (m *DoesExist) Check(h httprouter.Handle) httprouter.Handle {
 		eventName := params.ByName("eventName")
 
 		if eventName == "" {
-			m.logger.Debug("empty eventName string")
+			logging.FromContext(r.Context(), m.logger).Debug("empty eventName string")
 			http_errors.MakeErrorResponse(w, http_errors.ErrNoEventName)
 			return
 		}
 
-		eventID, err := m.eventService.DoesExist(ctx, eventName)
+		spanCtx, span := tracing.Start(ctx, "events DoesExist", trace.WithAttributes(attribute.String("event.name", eventName)))
+		eventID, err := m.eventService.DoesExist(spanCtx, eventName)
+		tracing.End(span, err)
 		if err != nil {
-			m.logger.Error(err.Error())
+			logging.FromContext(r.Context(), m.logger).Error(err.Error())
 			http_errors.MakeErrorResponse(w, err)
 			return
 		}
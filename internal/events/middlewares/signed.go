@@ -0,0 +1,72 @@
+package event_middlewares
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/signature"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+	"go.uber.org/zap"
+)
+
+//Signed verifies HMAC signature of producer over the request (see signature package).
+//Producer must be of the tenant of api key. Unsigned requests pass through unless signature is required
+type Signed struct {
+	logger   *zap.SugaredLogger
+	verifier *signature.Verifier
+	required bool
+}
+
+func NewSigned(logger *zap.SugaredLogger, verifier *signature.Verifier, required bool) *Signed {
+	return &Signed{
+		logger:   logger,
+		verifier: verifier,
+		required: required,
+	}
+}
+
+func (m *Signed) Check(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		sig := r.Header.Get(signature.SignatureHeader)
+		if sig == "" && m.required != true {
+			h(w, r, params)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			logging.FromContext(r.Context(), m.logger).Error(err.Error())
+			http_errors.MakeErrorResponse(w, err)
+			return
+		}
+		//Handler reads the body once again
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		producerTenantID, err := m.verifier.Verify(r.Context(), signature.Request{
+			Producer:  r.Header.Get(signature.ProducerHeader),
+			Timestamp: r.Header.Get(signature.TimestampHeader),
+			Nonce:     r.Header.Get(signature.NonceHeader),
+			Signature: sig,
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Body:      body,
+		})
+		if err != nil {
+			logging.FromContext(r.Context(), m.logger).Warnf("rejected signature of producer %s. %s", r.Header.Get(signature.ProducerHeader), err.Error())
+			http_errors.MakeErrorResponse(w, err)
+			return
+		}
+		//Secret of producer is no use against another tenant even with its api key
+		if tenantID, _ := tenancy.FromContext(r.Context()); tenantID != producerTenantID {
+			logging.FromContext(r.Context(), m.logger).Warnf("rejected producer %s of tenant %d for tenant %d", r.Header.Get(signature.ProducerHeader), producerTenantID, tenantID)
+			http_errors.MakeErrorResponse(w, signature.ErrForeignProducer)
+			return
+		}
+
+		h(w, r, params)
+	}
+}
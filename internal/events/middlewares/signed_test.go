@@ -0,0 +1,69 @@
+package event_middlewares_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/internal/events/middlewares"
+	"github.com/sonyamoonglade/notification-service/pkg/signature"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+//freshNonces accepts every nonce
+type freshNonces struct{}
+
+func (freshNonces) ClaimNonce(_ context.Context, _ string, _ string, _ time.Time, _ time.Time) (bool, error) {
+	return true, nil
+}
+
+func (freshNonces) PurgeNonces(_ context.Context, _ time.Time) error {
+	return nil
+}
+
+func TestSignedChecksTenantOfProducer(t *testing.T) {
+	verifier := signature.NewVerifier(zap.NewNop().Sugar(), map[string]signature.Producer{
+		"delivery": {Secret: "secret", TenantID: 2},
+	}, time.Minute*5, freshNonces{})
+	signed := event_middlewares.NewSigned(zap.NewNop().Sugar(), verifier, true)
+
+	handled := false
+	h := signed.Check(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		handled = true
+	})
+
+	path := "/api/events/fire/worker_login"
+	body := []byte(`{}`)
+
+	tests := []struct {
+		name         string
+		tenantID     uint64
+		expectedCode int
+	}{
+		{"tenant of producer", 2, http.StatusOK},
+		{"another tenant", 3, http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		handled = false
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req = req.WithContext(tenancy.WithID(req.Context(), tc.tenantID))
+		req.Header.Set(signature.ProducerHeader, "delivery")
+		req.Header.Set(signature.TimestampHeader, ts)
+		req.Header.Set(signature.NonceHeader, tc.name)
+		req.Header.Set(signature.SignatureHeader, signature.Sign("secret", ts, tc.name, http.MethodPost, path, body))
+		w := httptest.NewRecorder()
+		h(w, req, nil)
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+		assert.Equal(t, tc.expectedCode == http.StatusOK, handled, tc.name)
+	}
+}
@@ -0,0 +1,13 @@
+package dto
+
+type CreateGroupDto struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type AddGroupMemberDto struct {
+	PhoneNumber string `json:"phone_number" validate:"required"`
+}
+
+type SubscribeGroupDto struct {
+	EventName string `json:"event_name" validate:"required"`
+}
@@ -0,0 +1,306 @@
+package group
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/delivery-service/pkg/binder"
+	"github.com/sonyamoonglade/delivery-service/pkg/validation"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/events"
+	"github.com/sonyamoonglade/notification-service/internal/group/dto"
+	"github.com/sonyamoonglade/notification-service/internal/subscription"
+	"github.com/sonyamoonglade/notification-service/internal/tenant/middlewares"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/response"
+	"go.uber.org/zap"
+)
+
+type Transport interface {
+	CreateGroup(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	GetGroups(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	GetGroup(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	DeleteGroup(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	AddMember(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RemoveMember(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	Subscribe(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	Cancel(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	InitRoutes(router *httprouter.Router)
+}
+
+type groupTransport struct {
+	groupService        Service
+	subscriptionService subscription.Service
+	eventsService       events.Service
+	auth                *tenant_middlewares.APIKey
+	logger              *zap.SugaredLogger
+}
+
+func NewGroupTransport(logger *zap.SugaredLogger,
+	service Service,
+	subscriptionService subscription.Service,
+	eventsService events.Service,
+	auth *tenant_middlewares.APIKey) Transport {
+
+	return &groupTransport{
+		logger:              logger,
+		groupService:        service,
+		subscriptionService: subscriptionService,
+		eventsService:       eventsService,
+		auth:                auth,
+	}
+}
+
+func (g *groupTransport) InitRoutes(router *httprouter.Router) {
+	router.POST("/api/groups", g.auth.Require(entity.ScopeGroupsWrite, g.CreateGroup))
+	router.GET("/api/groups", g.auth.Require(entity.ScopeGroupsRead, g.GetGroups))
+	router.GET("/api/groups/:groupId", g.auth.Require(entity.ScopeGroupsRead, g.GetGroup))
+	router.DELETE("/api/groups/:groupId", g.auth.Require(entity.ScopeGroupsWrite, g.DeleteGroup))
+	router.POST("/api/groups/:groupId/members", g.auth.Require(entity.ScopeGroupsWrite, g.AddMember))
+	router.DELETE("/api/groups/:groupId/members/:subscriberId", g.auth.Require(entity.ScopeGroupsWrite, g.RemoveMember))
+	router.POST("/api/groups/:groupId/subscriptions", g.auth.Require(entity.ScopeGroupsWrite, g.Subscribe))
+	router.DELETE("/api/groups/:groupId/subscriptions/:eventName", g.auth.Require(entity.ScopeGroupsWrite, g.Cancel))
+}
+
+func (g *groupTransport) CreateGroup(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	var inp dto.CreateGroupDto
+
+	err := binder.Bind(r.Body, &inp)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	if NormalizeName(inp.Name) == "" {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidPayload)
+		logging.FromContext(r.Context(), g.logger).Debug("empty group name")
+		return
+	}
+
+	groupID, err := g.groupService.CreateGroup(r.Context(), inp.Name)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(g.logger, w, http.StatusCreated, response.JSON{
+		"group_id": groupID,
+	})
+}
+
+func (g *groupTransport) GetGroups(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	groups, err := g.groupService.GetGroups(r.Context())
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(g.logger, w, http.StatusOK, response.JSON{
+		"groups": groups,
+	})
+}
+
+func (g *groupTransport) GetGroup(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	groupID, err := parseGroupID(params)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Debug(err.Error())
+		return
+	}
+
+	group, err := g.groupService.GetGroup(r.Context(), groupID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(g.logger, w, http.StatusOK, response.JSON{
+		"group": group,
+	})
+}
+
+func (g *groupTransport) DeleteGroup(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	groupID, err := parseGroupID(params)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Debug(err.Error())
+		return
+	}
+
+	err = g.groupService.DeleteGroup(r.Context(), groupID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	response.Ok(w)
+}
+
+func (g *groupTransport) AddMember(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	ctx := r.Context()
+
+	groupID, err := parseGroupID(params)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Debug(err.Error())
+		return
+	}
+
+	var inp dto.AddGroupMemberDto
+
+	err = binder.Bind(r.Body, &inp)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	ok := validation.ValidatePhoneNumber(inp.PhoneNumber)
+	if ok != true {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidPayload)
+		logging.FromContext(r.Context(), g.logger).Debug("invalid phone number")
+		return
+	}
+
+	subscriberID, err := g.getOrRegisterSubscriber(ctx, inp.PhoneNumber)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	err = g.groupService.AddMember(ctx, groupID, subscriberID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+	logging.FromContext(r.Context(), g.logger).Debugf("subscriber with phone %s has joined group %d", inp.PhoneNumber, groupID)
+
+	response.Created(w)
+}
+
+func (g *groupTransport) RemoveMember(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	groupID, err := parseGroupID(params)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Debug(err.Error())
+		return
+	}
+
+	subscriberID, err := strconv.ParseUint(params.ByName("subscriberId"), 10, 64)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidSubscriberID)
+		logging.FromContext(r.Context(), g.logger).Debug(err.Error())
+		return
+	}
+
+	err = g.groupService.RemoveMember(r.Context(), groupID, subscriberID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	response.Ok(w)
+}
+
+func (g *groupTransport) Subscribe(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	ctx := r.Context()
+
+	groupID, err := parseGroupID(params)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Debug(err.Error())
+		return
+	}
+
+	var inp dto.SubscribeGroupDto
+
+	err = binder.Bind(r.Body, &inp)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	eventID, err := g.eventsService.DoesExist(ctx, inp.EventName)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	err = g.groupService.SubscribeToEvent(ctx, groupID, eventID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+	logging.FromContext(r.Context(), g.logger).Debugf("group %d has subscribed to event %d", groupID, eventID)
+
+	response.Created(w)
+}
+
+func (g *groupTransport) Cancel(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	ctx := r.Context()
+
+	groupID, err := parseGroupID(params)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Debug(err.Error())
+		return
+	}
+
+	eventID, err := g.eventsService.DoesExist(ctx, params.ByName("eventName"))
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	err = g.groupService.CancelSubscription(ctx, groupID, eventID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), g.logger).Error(err.Error())
+		return
+	}
+
+	response.Ok(w)
+}
+
+func (g *groupTransport) getOrRegisterSubscriber(ctx context.Context, phoneNumber string) (uint64, error) {
+	subscriber, err := g.subscriptionService.GetSubscriberByPhone(ctx, phoneNumber)
+	if err != nil {
+		if !errors.Is(err, http_errors.ErrSubscriberDoesNotExist) {
+			return 0, err
+		}
+		return g.subscriptionService.RegisterSubscriber(ctx, phoneNumber)
+	}
+	return subscriber.SubscriberID, nil
+}
+
+func parseGroupID(params httprouter.Params) (uint64, error) {
+	groupID, err := strconv.ParseUint(params.ByName("groupId"), 10, 64)
+	if err != nil {
+		return 0, http_errors.ErrInvalidGroupID
+	}
+	return groupID, nil
+}
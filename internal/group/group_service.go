@@ -0,0 +1,190 @@
+package group
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sonyamoonglade/notification-service/internal/audit"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/group/response_object"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"go.uber.org/zap"
+)
+
+type Service interface {
+	CreateGroup(ctx context.Context, name string) (uint64, error)
+	GetGroups(ctx context.Context) ([]*entity.Group, error)
+	GetGroup(ctx context.Context, groupID uint64) (*response_object.GroupRO, error)
+	DeleteGroup(ctx context.Context, groupID uint64) error
+	AddMember(ctx context.Context, groupID uint64, subscriberID uint64) error
+	RemoveMember(ctx context.Context, groupID uint64, subscriberID uint64) error
+	SubscribeToEvent(ctx context.Context, groupID uint64, eventID uint64) error
+	CancelSubscription(ctx context.Context, groupID uint64, eventID uint64) error
+}
+
+type groupService struct {
+	storage      storage.DBStorage
+	auditService audit.Service
+	logger       *zap.SugaredLogger
+}
+
+func NewGroupService(logger *zap.SugaredLogger, storage storage.DBStorage, auditService audit.Service) Service {
+	return &groupService{logger: logger, storage: storage, auditService: auditService}
+}
+
+//NormalizeName makes "Managers " and "managers" the same group
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (s *groupService) CreateGroup(ctx context.Context, name string) (uint64, error) {
+	name = NormalizeName(name)
+	groupID, err := s.storage.CreateGroup(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if groupID == 0 {
+		return 0, http_errors.ErrGroupAlreadyExists
+	}
+
+	s.auditService.Record(ctx, audit.ActionCreate, audit.TargetGroup, groupID, nil, entity.Group{GroupID: groupID, Name: name})
+	return groupID, nil
+}
+
+func (s *groupService) GetGroups(ctx context.Context) ([]*entity.Group, error) {
+	groups, err := s.storage.GetGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if groups == nil {
+		return []*entity.Group{}, nil
+	}
+	return groups, nil
+}
+
+func (s *groupService) GetGroup(ctx context.Context, groupID uint64) (*response_object.GroupRO, error) {
+	group, err := s.mustGetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.storage.GetGroupMembers(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	evnts, err := s.storage.GetGroupEvents(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response_object.GroupRO{
+		GroupID: group.GroupID,
+		Name:    group.Name,
+		Members: members,
+		Events:  evnts,
+	}, nil
+}
+
+func (s *groupService) DeleteGroup(ctx context.Context, groupID uint64) error {
+	//Members and subscriptions are deleted with the group, so they're audited as well
+	before, err := s.GetGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.storage.DeleteGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return http_errors.ErrGroupDoesNotExist
+	}
+
+	s.auditService.Record(ctx, audit.ActionDelete, audit.TargetGroup, groupID, before, nil)
+	return nil
+}
+
+func (s *groupService) AddMember(ctx context.Context, groupID uint64, subscriberID uint64) error {
+	if _, err := s.mustGetGroup(ctx, groupID); err != nil {
+		return err
+	}
+
+	ok, err := s.storage.AddGroupMember(ctx, groupID, subscriberID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return http_errors.ErrGroupMemberAlreadyExists
+	}
+
+	s.auditService.Record(ctx, audit.ActionCreate, audit.TargetGroupMember, groupID, nil, audit.Fields{
+		"group_id":      groupID,
+		"subscriber_id": subscriberID,
+	})
+	return nil
+}
+
+func (s *groupService) RemoveMember(ctx context.Context, groupID uint64, subscriberID uint64) error {
+	ok, err := s.storage.RemoveGroupMember(ctx, groupID, subscriberID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return http_errors.ErrGroupMemberDoesNotExist
+	}
+
+	s.auditService.Record(ctx, audit.ActionDelete, audit.TargetGroupMember, groupID, audit.Fields{
+		"group_id":      groupID,
+		"subscriber_id": subscriberID,
+	}, nil)
+	return nil
+}
+
+func (s *groupService) SubscribeToEvent(ctx context.Context, groupID uint64, eventID uint64) error {
+	if _, err := s.mustGetGroup(ctx, groupID); err != nil {
+		return err
+	}
+
+	ok, err := s.storage.SubscribeGroupToEvent(ctx, groupID, eventID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return http_errors.ErrGroupSubscriptionAlreadyExists
+	}
+
+	s.auditService.Record(ctx, audit.ActionCreate, audit.TargetGroupSubscription, groupID, nil, audit.Fields{
+		"group_id": groupID,
+		"event_id": eventID,
+	})
+	return nil
+}
+
+func (s *groupService) CancelSubscription(ctx context.Context, groupID uint64, eventID uint64) error {
+	ok, err := s.storage.CancelGroupSubscription(ctx, groupID, eventID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return http_errors.ErrGroupSubscriptionDoesNotExist
+	}
+
+	s.auditService.Record(ctx, audit.ActionDelete, audit.TargetGroupSubscription, groupID, audit.Fields{
+		"group_id": groupID,
+		"event_id": eventID,
+	}, nil)
+	return nil
+}
+
+func (s *groupService) mustGetGroup(ctx context.Context, groupID uint64) (*entity.Group, error) {
+	group, err := s.storage.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, http_errors.ErrGroupDoesNotExist
+	}
+	return group, nil
+}
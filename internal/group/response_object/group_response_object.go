@@ -0,0 +1,10 @@
+package response_object
+
+import "github.com/sonyamoonglade/notification-service/internal/entity"
+
+type GroupRO struct {
+	GroupID uint64               `json:"group_id"`
+	Name    string               `json:"name"`
+	Members []*entity.Subscriber `json:"members"`
+	Events  []*entity.Event      `json:"events"`
+}
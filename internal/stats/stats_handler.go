@@ -0,0 +1,92 @@
+package stats
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/tenant/middlewares"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/response"
+	"go.uber.org/zap"
+)
+
+type Transport interface {
+	GetStats(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	InitRoutes(router *httprouter.Router)
+}
+
+type statsTransport struct {
+	statsService Service
+	auth         *tenant_middlewares.APIKey
+	logger       *zap.SugaredLogger
+}
+
+func NewStatsTransport(logger *zap.SugaredLogger, service Service, auth *tenant_middlewares.APIKey) Transport {
+	return &statsTransport{logger: logger, statsService: service, auth: auth}
+}
+
+func (s *statsTransport) InitRoutes(router *httprouter.Router) {
+	router.GET("/api/stats", s.auth.Require(entity.ScopeStatsRead, s.GetStats))
+}
+
+func (s *statsTransport) GetStats(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	filter, err := parseFilter(r.URL.Query(), time.Now())
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Debug(err.Error())
+		return
+	}
+
+	stats, err := s.statsService.GetStats(r.Context(), filter)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(s.logger, w, http.StatusOK, response.JSON{
+		"stats": stats,
+	})
+}
+
+//parseFilter reads filter from query, e.g. ?from=2022-11-01T00:00:00Z&bucket=hour&event=worker_login.
+//to defaults to now and from to DefaultRange before to
+func parseFilter(q url.Values, now time.Time) (entity.StatsFilter, error) {
+	filter := entity.StatsFilter{
+		To:        now,
+		Bucket:    q.Get("bucket"),
+		EventName: q.Get("event"),
+	}
+
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, http_errors.ErrInvalidStatsFilter
+		}
+		filter.To = t
+	}
+	filter.From = filter.To.Add(-DefaultRange)
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, http_errors.ErrInvalidStatsFilter
+		}
+		filter.From = t
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > MaxLimit {
+			return filter, http_errors.ErrInvalidStatsFilter
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
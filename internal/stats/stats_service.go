@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"context"
+	"time"
+
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"go.uber.org/zap"
+)
+
+const (
+	//DefaultRange is the range of stats when from is omitted
+	DefaultRange = 7 * 24 * time.Hour
+	DefaultLimit = 10
+	MaxLimit     = 100
+)
+
+//maxBuckets keeps hourly stats of a year from being computed by accident
+const maxBuckets = 24 * 31
+
+type Service interface {
+	GetStats(ctx context.Context, filter entity.StatsFilter) (*entity.Stats, error)
+}
+
+type statsService struct {
+	storage storage.DBStorage
+	logger  *zap.SugaredLogger
+}
+
+func NewStatsService(logger *zap.SugaredLogger, storage storage.DBStorage) Service {
+	return &statsService{logger: logger, storage: storage}
+}
+
+//GetStats returns stats of fires and deliveries of the tenant in ctx made in [filter.From, filter.To)
+func (s *statsService) GetStats(ctx context.Context, filter entity.StatsFilter) (*entity.Stats, error) {
+	if filter.Bucket == "" {
+		filter.Bucket = entity.BucketDay
+	}
+	if filter.Limit <= 0 || filter.Limit > MaxLimit {
+		filter.Limit = DefaultLimit
+	}
+
+	var bucket time.Duration
+	switch filter.Bucket {
+	case entity.BucketDay:
+		bucket = 24 * time.Hour
+	case entity.BucketHour:
+		bucket = time.Hour
+	default:
+		return nil, http_errors.ErrInvalidStatsFilter
+	}
+	if filter.To.After(filter.From) != true || filter.To.Sub(filter.From)/bucket > maxBuckets {
+		return nil, http_errors.ErrInvalidStatsFilter
+	}
+
+	stats, err := s.storage.GetStats(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Totals.SetRates()
+	for _, e := range stats.Events {
+		e.SetRates()
+	}
+	for _, c := range stats.Channels {
+		c.SetRates()
+	}
+	for _, b := range stats.Buckets {
+		b.SetRates()
+	}
+	//Empty lists are [] rather than null in response
+	if stats.Events == nil {
+		stats.Events = []*entity.EventStats{}
+	}
+	if stats.Channels == nil {
+		stats.Channels = []*entity.ChannelStats{}
+	}
+	if stats.TopFailingRecipients == nil {
+		stats.TopFailingRecipients = []*entity.FailingRecipient{}
+	}
+	return stats, nil
+}
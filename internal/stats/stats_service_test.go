@@ -0,0 +1,88 @@
+package stats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/stats"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+//statsStorage returns stats it's given and remembers the filter of the query
+type statsStorage struct {
+	storage.DBStorage
+	stats  entity.Stats
+	filter entity.StatsFilter
+}
+
+func (f *statsStorage) GetStats(_ context.Context, filter entity.StatsFilter) (*entity.Stats, error) {
+	f.filter = filter
+	s := f.stats
+	return &s, nil
+}
+
+func TestGetStatsFilter(t *testing.T) {
+	to := time.Date(2022, 11, 21, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		filter         entity.StatsFilter
+		expectedBucket string
+		expectedLimit  int
+		expectedErr    error
+	}{
+		{"defaults", entity.StatsFilter{From: to.Add(-stats.DefaultRange), To: to}, entity.BucketDay, stats.DefaultLimit, nil},
+		{"hourly", entity.StatsFilter{From: to.Add(-time.Hour * 24), To: to, Bucket: entity.BucketHour, Limit: 5}, entity.BucketHour, 5, nil},
+		{"limit over max", entity.StatsFilter{From: to.Add(-time.Hour), To: to, Limit: stats.MaxLimit + 1}, entity.BucketDay, stats.DefaultLimit, nil},
+		{"unknown bucket", entity.StatsFilter{From: to.Add(-time.Hour), To: to, Bucket: "week"}, "", 0, http_errors.ErrInvalidStatsFilter},
+		{"empty range", entity.StatsFilter{From: to, To: to}, "", 0, http_errors.ErrInvalidStatsFilter},
+		{"too many buckets", entity.StatsFilter{From: to.Add(-time.Hour * 24 * 32), To: to, Bucket: entity.BucketHour}, "", 0, http_errors.ErrInvalidStatsFilter},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &statsStorage{}
+			service := stats.NewStatsService(zap.NewNop().Sugar(), db)
+
+			_, err := service.GetStats(context.Background(), tc.filter)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedBucket, db.filter.Bucket)
+			assert.Equal(t, tc.expectedLimit, db.filter.Limit)
+		})
+	}
+}
+
+func TestGetStatsRates(t *testing.T) {
+	to := time.Date(2022, 11, 21, 0, 0, 0, 0, time.UTC)
+	db := &statsStorage{stats: entity.Stats{
+		Totals: entity.TotalStats{Fires: 2, DeliveryCounts: entity.DeliveryCounts{Sent: 3, Failed: 1, Suppressed: 10}},
+		Buckets: []*entity.BucketStats{
+			{Start: to.Add(-time.Hour * 24), DeliveryCounts: entity.DeliveryCounts{Suppressed: 1}},
+		},
+	}}
+	service := stats.NewStatsService(zap.NewNop().Sugar(), db)
+
+	s, err := service.GetStats(context.Background(), entity.StatsFilter{From: to.Add(-stats.DefaultRange), To: to})
+	require.NoError(t, err)
+
+	//Suppressed deliveries haven't been sent, so they don't count
+	require.NotNil(t, s.Totals.SuccessRate)
+	assert.InDelta(t, 0.75, *s.Totals.SuccessRate, 1e-9)
+	assert.InDelta(t, 0.25, *s.Totals.FailureRate, 1e-9)
+	assert.Nil(t, s.Buckets[0].SuccessRate)
+
+	//Empty lists are [] in response
+	assert.NotNil(t, s.Events)
+	assert.NotNil(t, s.Channels)
+	assert.NotNil(t, s.TopFailingRecipients)
+}
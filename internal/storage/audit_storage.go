@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+)
+
+func (p *PostgresStorage) CreateAuditEntry(ctx context.Context, e *entity.AuditEntry) error {
+	q := fmt.Sprintf(
+		`INSERT INTO %s (actor_type, actor_id, actor_name, action, target_type, target_id, before, after, request_id, tenant_id)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
+		auditLogTable)
+
+	//nil json is stored as NULL rather than 'null'
+	_, err := p.pool.Exec(ctx, q, e.ActorType, e.ActorID, e.ActorName, e.Action, e.TargetType, e.TargetID,
+		[]byte(e.Before), []byte(e.After), e.RequestID, tenantOf(ctx))
+	return err
+}
+
+func (p *PostgresStorage) GetAuditEntries(ctx context.Context, f entity.AuditFilter) ([]*entity.AuditEntry, error) {
+	var entries []*entity.AuditEntry
+	//Empty filters match every entry
+	q := fmt.Sprintf(
+		`SELECT * FROM %s
+				WHERE tenant_id = $10
+				AND ($1 = '' OR action = $1)
+				AND ($2 = '' OR actor_type = $2)
+				AND ($3 = '' OR actor_id = $3)
+				AND ($4 = '' OR target_type = $4)
+				AND ($5 = '' OR target_id = $5)
+				AND ($6::timestamptz IS NULL OR created_at >= $6)
+				AND ($7::timestamptz IS NULL OR created_at < $7)
+				AND ($8 = 0 OR audit_id < $8)
+				ORDER BY audit_id DESC LIMIT $9`,
+		auditLogTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, f.Action, f.ActorType, f.ActorID, f.TargetType, f.TargetID, f.Since, f.Until, f.Cursor, f.Limit,
+		tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&entries, rows)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
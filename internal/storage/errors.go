@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"github.com/jackc/pgconn"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+)
+
+//Codes of constraint violations, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+//pgError turns constraint violations into typed errors, so they're client errors rather than internal ones
+func pgError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) != true {
+		return err
+	}
+
+	switch pgErr.Code {
+	case uniqueViolation:
+		return http_errors.ErrAlreadyExists.With("", http_errors.Details{"constraint": pgErr.ConstraintName})
+	case foreignKeyViolation:
+		return http_errors.ErrInvalidReference.With("", http_errors.Details{"constraint": pgErr.ConstraintName})
+	default:
+		return err
+	}
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+)
+
+func (p *PostgresStorage) CreateEscalation(ctx context.Context, eventID uint64, text string, timeoutMinutes int) (uint64, error) {
+	var escalationID uint64
+	q := fmt.Sprintf(
+		`INSERT INTO %s (event_id, text, next_escalation_at, tenant_id) VALUES ($1, $2, now() + make_interval(mins => $3), $4)
+				RETURNING escalation_id`,
+		escalationsTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Release()
+
+	err = c.QueryRow(ctx, q, eventID, text, timeoutMinutes, tenantOf(ctx)).Scan(&escalationID)
+	if err != nil {
+		return 0, err
+	}
+	return escalationID, nil
+}
+
+func (p *PostgresStorage) GetEscalation(ctx context.Context, escalationID uint64) (*entity.Escalation, error) {
+	var esc entity.Escalation
+	q := fmt.Sprintf("SELECT * FROM %s WHERE tenant_id = $2 AND escalation_id = $1", escalationsTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, escalationID, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanOne(&esc, rows)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &esc, nil
+}
+
+func (p *PostgresStorage) GetEscalations(ctx context.Context, status string) ([]*entity.Escalation, error) {
+	var escs []*entity.Escalation
+	//Empty status matches every escalation
+	q := fmt.Sprintf(
+		"SELECT * FROM %s WHERE tenant_id = $2 AND ($1 = '' OR status = $1) ORDER BY created_at DESC",
+		escalationsTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, status, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&escs, rows)
+	if err != nil {
+		return nil, err
+	}
+	return escs, nil
+}
+
+func (p *PostgresStorage) GetDueEscalations(ctx context.Context) ([]*entity.Escalation, error) {
+	var escs []*entity.Escalation
+	q := fmt.Sprintf(
+		"SELECT * FROM %s WHERE tenant_id = $2 AND status = $1 AND next_escalation_at <= now() ORDER BY next_escalation_at ASC",
+		escalationsTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, entity.EscalationPending, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&escs, rows)
+	if err != nil {
+		return nil, err
+	}
+	return escs, nil
+}
+
+func (p *PostgresStorage) AcknowledgeEscalation(ctx context.Context, escalationID uint64, telegramID int64) (bool, error) {
+	q := fmt.Sprintf(
+		`UPDATE %s SET status = $1, acknowledged_by = $2, acknowledged_at = now(), next_escalation_at = NULL
+				WHERE tenant_id = $5 AND escalation_id = $3 AND status = $4`,
+		escalationsTable)
+
+	tag, err := p.pool.Exec(ctx, q, entity.EscalationAcknowledged, telegramID, escalationID, entity.EscalationPending, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+//PromoteEscalation moves escalation to the next tier. fromTier guards against concurrent workers promoting twice
+func (p *PostgresStorage) PromoteEscalation(ctx context.Context, escalationID uint64, fromTier int, timeoutMinutes int) (bool, error) {
+	q := fmt.Sprintf(
+		`UPDATE %s SET tier = tier + 1, next_escalation_at = now() + make_interval(mins => $1)
+				WHERE tenant_id = $5 AND escalation_id = $2 AND tier = $3 AND status = $4`,
+		escalationsTable)
+
+	tag, err := p.pool.Exec(ctx, q, timeoutMinutes, escalationID, fromTier, entity.EscalationPending, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) ExhaustEscalation(ctx context.Context, escalationID uint64, fromTier int) (bool, error) {
+	q := fmt.Sprintf(
+		`UPDATE %s SET status = $1, next_escalation_at = NULL
+				WHERE tenant_id = $5 AND escalation_id = $2 AND tier = $3 AND status = $4`,
+		escalationsTable)
+
+	tag, err := p.pool.Exec(ctx, q, entity.EscalationExhausted, escalationID, fromTier, entity.EscalationPending, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+//DeleteEscalation deletes escalation that is still pending, e.g. when the fire it's started by has reached nobody
+func (p *PostgresStorage) DeleteEscalation(ctx context.Context, escalationID uint64) (bool, error) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $3 AND escalation_id = $1 AND status = $2", escalationsTable)
+
+	tag, err := p.pool.Exec(ctx, q, escalationID, entity.EscalationPending, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/response_object"
+)
+
+//ExportSubscribers calls fn with every subscriber, ordered by phone number, as soon as it's read
+func (p *PostgresStorage) ExportSubscribers(ctx context.Context, fn func(*response_object.SubscriberExportRO) error) error {
+	q := fmt.Sprintf(
+		`SELECT sub.subscriber_id, sub.phone_number, sub.active,
+				(SELECT count(*) FROM %s subs WHERE subs.tenant_id = sub.tenant_id AND subs.subscriber_id = sub.subscriber_id)
+					as subscriptions,
+				(SELECT count(*) FROM %s tgsub WHERE tgsub.tenant_id = sub.tenant_id AND tgsub.subscriber_id = sub.subscriber_id)
+					as telegram_links,
+				(SELECT count(*) FROM %s tgsub WHERE tgsub.tenant_id = sub.tenant_id AND tgsub.subscriber_id = sub.subscriber_id
+					AND tgsub.enabled AND tgsub.active) as active_telegram_links,
+				(SELECT max(m.muted_until) FROM %s m WHERE m.tenant_id = sub.tenant_id AND m.subscriber_id = sub.subscriber_id
+					AND m.event_id IS NULL AND m.muted_until > now()) as muted_until
+				FROM %s sub WHERE sub.tenant_id = $1 ORDER BY sub.phone_number ASC`,
+		subscriptionsTable, telegramSubscribersTable, telegramSubscribersTable, mutesTable, subscribersTable)
+
+	var row response_object.SubscriberExportRO
+	return p.each(ctx, q, &row, func() error {
+		r := row
+		return fn(&r)
+	})
+}
+
+//ExportSubscriptions calls fn with every direct subscription, ordered by phone number and event, as soon as it's read
+func (p *PostgresStorage) ExportSubscriptions(ctx context.Context, fn func(*response_object.SubscriptionExportRO) error) error {
+	q := fmt.Sprintf(
+		`SELECT sub.phone_number, e.name as event_name, subs.subscription_id, subs.subscriber_id, subs.event_id
+				FROM %s subs
+				JOIN %s sub ON sub.tenant_id = subs.tenant_id AND sub.subscriber_id = subs.subscriber_id
+				JOIN %s e ON e.tenant_id = subs.tenant_id AND e.event_id = subs.event_id
+				WHERE subs.tenant_id = $1
+				ORDER BY sub.phone_number ASC, e.name ASC`,
+		subscriptionsTable, subscribersTable, eventsTable)
+
+	var row response_object.SubscriptionExportRO
+	return p.each(ctx, q, &row, func() error {
+		r := row
+		return fn(&r)
+	})
+}
+
+//ExportTelegramLinks calls fn with every telegram link, ordered by phone number, as soon as it's read
+func (p *PostgresStorage) ExportTelegramLinks(ctx context.Context, fn func(*response_object.TelegramLinkRO) error) error {
+	q := fmt.Sprintf(
+		`SELECT %s, sub.phone_number FROM %s tgsub
+				JOIN %s sub ON tgsub.tenant_id = sub.tenant_id AND tgsub.subscriber_id = sub.subscriber_id
+				WHERE tgsub.tenant_id = $1
+				ORDER BY sub.phone_number ASC, tgsub.link_id ASC`,
+		telegramLinkColumns, telegramSubscribersTable, subscribersTable)
+
+	var row response_object.TelegramLinkRO
+	return p.each(ctx, q, &row, func() error {
+		r := row
+		return fn(&r)
+	})
+}
+
+//each scans rows of q into dst one by one and calls fn after each row. q takes tenant of ctx as $1
+func (p *PostgresStorage) each(ctx context.Context, q string, dst interface{}, fn func() error) error {
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, tenantOf(ctx))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	rs := pgxscan.NewRowScanner(rows)
+	for rows.Next() {
+		if err := rs.Scan(dst); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+//ImportSubscriptions registers absent subscribers and subscribes them to events of rows in one transaction.
+//Status of every row is set to ImportSubscribed or ImportExists. Nothing is saved unless commit is true
+func (p *PostgresStorage) ImportSubscriptions(ctx context.Context, rows []*response_object.ImportRowRO, commit bool) error {
+	registerQ := fmt.Sprintf(
+		`WITH ins AS (
+					INSERT INTO %s (phone_number, tenant_id) VALUES ($1,$2) ON CONFLICT DO NOTHING RETURNING subscriber_id
+				)
+				SELECT subscriber_id, true FROM ins
+				UNION ALL
+				SELECT subscriber_id, false FROM %s WHERE tenant_id = $2 AND phone_number = $1
+				LIMIT 1`,
+		subscribersTable, subscribersTable)
+	subscribeQ := fmt.Sprintf(
+		"INSERT INTO %s (subscriber_id, event_id, tenant_id) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING",
+		subscriptionsTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, row := range rows {
+		var subscriberID uint64
+		if err := tx.QueryRow(ctx, registerQ, row.PhoneNumber, tenantOf(ctx)).Scan(&subscriberID, &row.Registered); err != nil {
+			return pgError(err)
+		}
+
+		tag, err := tx.Exec(ctx, subscribeQ, subscriberID, row.EventID, tenantOf(ctx))
+		if err != nil {
+			return pgError(err)
+		}
+		row.Status = response_object.ImportSubscribed
+		if tag.RowsAffected() == 0 {
+			row.Status = response_object.ImportExists
+		}
+	}
+
+	if commit != true {
+		return nil
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+)
+
+func (p *PostgresStorage) CreateGroup(ctx context.Context, name string) (uint64, error) {
+	var groupID uint64
+	q := fmt.Sprintf("INSERT INTO %s (name, tenant_id) VALUES ($1,$2) ON CONFLICT DO NOTHING RETURNING group_id", groupsTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Release()
+
+	err = c.QueryRow(ctx, q, name, tenantOf(ctx)).Scan(&groupID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return groupID, nil
+}
+
+func (p *PostgresStorage) GetGroups(ctx context.Context) ([]*entity.Group, error) {
+	var groups []*entity.Group
+	q := fmt.Sprintf("SELECT * FROM %s WHERE tenant_id = $1 ORDER BY name ASC", groupsTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&groups, rows)
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (p *PostgresStorage) GetGroup(ctx context.Context, groupID uint64) (*entity.Group, error) {
+	var group entity.Group
+	q := fmt.Sprintf("SELECT * FROM %s WHERE tenant_id = $2 AND group_id = $1", groupsTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, groupID, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanOne(&group, rows)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (p *PostgresStorage) GetGroupsByNames(ctx context.Context, names []string) ([]*entity.Group, error) {
+	var groups []*entity.Group
+	q := fmt.Sprintf("SELECT * FROM %s WHERE tenant_id = $2 AND name = ANY($1)", groupsTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, names, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&groups, rows)
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (p *PostgresStorage) DeleteGroup(ctx context.Context, groupID uint64) (bool, error) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $2 AND group_id = $1", groupsTable)
+
+	tag, err := p.pool.Exec(ctx, q, groupID, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) GetGroupMembers(ctx context.Context, groupID uint64) ([]*entity.Subscriber, error) {
+	var subs []*entity.Subscriber
+	q := fmt.Sprintf(
+		`SELECT sub.subscriber_id, sub.phone_number, sub.active, sub.deactivated_at FROM %s sub
+				JOIN %s gm ON sub.tenant_id = gm.tenant_id AND sub.subscriber_id = gm.subscriber_id
+				WHERE sub.tenant_id = $2 AND gm.group_id = $1 ORDER BY sub.phone_number ASC`,
+		subscribersTable, groupMembersTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, groupID, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&subs, rows)
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (p *PostgresStorage) GetGroupsMembers(ctx context.Context, groupNames []string) ([]*entity.Subscriber, error) {
+	var subs []*entity.Subscriber
+	q := fmt.Sprintf(
+		`SELECT DISTINCT sub.subscriber_id, sub.phone_number, sub.active, sub.deactivated_at FROM %s sub
+				JOIN %s gm ON sub.tenant_id = gm.tenant_id AND sub.subscriber_id = gm.subscriber_id
+				JOIN %s g ON gm.tenant_id = g.tenant_id AND gm.group_id = g.group_id
+				WHERE sub.tenant_id = $2 AND g.name = ANY($1)`,
+		subscribersTable, groupMembersTable, groupsTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, groupNames, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&subs, rows)
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (p *PostgresStorage) GetGroupEvents(ctx context.Context, groupID uint64) ([]*entity.Event, error) {
+	var events []*entity.Event
+	q := fmt.Sprintf(
+		`SELECT e.event_id, e.name, e.translate FROM %s e JOIN %s gs ON e.tenant_id = gs.tenant_id AND e.event_id = gs.event_id
+				WHERE e.tenant_id = $2 AND gs.group_id = $1 ORDER BY e.event_id ASC`,
+		eventsTable, groupSubscriptionsTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, groupID, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&events, rows)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (p *PostgresStorage) AddGroupMember(ctx context.Context, groupID uint64, subscriberID uint64) (bool, error) {
+	q := fmt.Sprintf("INSERT INTO %s (group_id, subscriber_id, tenant_id) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING", groupMembersTable)
+
+	tag, err := p.pool.Exec(ctx, q, groupID, subscriberID, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) RemoveGroupMember(ctx context.Context, groupID uint64, subscriberID uint64) (bool, error) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $3 AND group_id = $1 AND subscriber_id = $2", groupMembersTable)
+
+	tag, err := p.pool.Exec(ctx, q, groupID, subscriberID, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) SubscribeGroupToEvent(ctx context.Context, groupID uint64, eventID uint64) (bool, error) {
+	q := fmt.Sprintf("INSERT INTO %s (group_id, event_id, tenant_id) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING", groupSubscriptionsTable)
+
+	tag, err := p.pool.Exec(ctx, q, groupID, eventID, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) CancelGroupSubscription(ctx context.Context, groupID uint64, eventID uint64) (bool, error) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $3 AND group_id = $1 AND event_id = $2", groupSubscriptionsTable)
+
+	tag, err := p.pool.Exec(ctx, q, groupID, eventID, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
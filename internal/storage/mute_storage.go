@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+)
+
+//Mute creates or prolongs mute window of subscriber. Nil eventID mutes every event
+func (p *PostgresStorage) Mute(ctx context.Context, subscriberID uint64, eventID *uint64, until time.Time) error {
+	q := fmt.Sprintf(
+		`INSERT INTO %s (subscriber_id, event_id, muted_until, tenant_id) VALUES ($1,$2,$3,$4)
+				ON CONFLICT (subscriber_id, (COALESCE(event_id, 0))) DO UPDATE SET muted_until = EXCLUDED.muted_until`,
+		mutesTable)
+
+	_, err := p.pool.Exec(ctx, q, subscriberID, eventID, until, tenantOf(ctx))
+	return err
+}
+
+//Unmute removes mute window of subscriber for eventID. Nil eventID removes every mute window of subscriber
+func (p *PostgresStorage) Unmute(ctx context.Context, subscriberID uint64, eventID *uint64) (bool, error) {
+	q := fmt.Sprintf(
+		`DELETE FROM %s WHERE tenant_id = $3 AND subscriber_id = $1 AND ($2::integer IS NULL OR event_id = $2)
+				AND muted_until > now()`,
+		mutesTable)
+
+	tag, err := p.pool.Exec(ctx, q, subscriberID, eventID, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) GetActiveMutes(ctx context.Context, subscriberID uint64) ([]*entity.Mute, error) {
+	var mutes []*entity.Mute
+	q := fmt.Sprintf(
+		"SELECT * FROM %s WHERE tenant_id = $2 AND subscriber_id = $1 AND muted_until > now() ORDER BY event_id ASC NULLS FIRST",
+		mutesTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, subscriberID, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&mutes, rows)
+	if err != nil {
+		return nil, err
+	}
+	return mutes, nil
+}
+
+//GetMutedSubscribers returns ids of subscribers among subscriberIDs who muted eventID or every event
+func (p *PostgresStorage) GetMutedSubscribers(ctx context.Context, eventID uint64, subscriberIDs []uint64) ([]uint64, error) {
+	var muted []uint64
+	q := fmt.Sprintf(
+		`SELECT DISTINCT subscriber_id FROM %s WHERE tenant_id = $3 AND subscriber_id = ANY($1)
+				AND (event_id IS NULL OR event_id = $2) AND muted_until > now()`,
+		mutesTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, subscriberIDs, eventID, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&muted, rows)
+	if err != nil {
+		return nil, err
+	}
+	return muted, nil
+}
+
+func (p *PostgresStorage) RecordDeliveries(ctx context.Context, eventID uint64, subscriberIDs []uint64, status string) error {
+	q := fmt.Sprintf("INSERT INTO %s (event_id, subscriber_id, status, tenant_id) VALUES ($1,$2,$3,$4)", deliveriesTable)
+
+	batch := &pgx.Batch{}
+	for _, id := range subscriberIDs {
+		batch.Queue(q, eventID, id, status, tenantOf(ctx))
+	}
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+
+	br := c.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for range subscriberIDs {
+		if _, err := br.Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
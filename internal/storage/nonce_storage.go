@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+//ClaimNonce remembers nonce of signed request until expiresAt. Returns false if nonce is known and isn't expired by now.
+//Producers aren't tenants, so nonces are shared by every tenant
+func (p *PostgresStorage) ClaimNonce(ctx context.Context, producer string, nonce string, expiresAt time.Time, now time.Time) (bool, error) {
+	q := fmt.Sprintf(
+		`INSERT INTO %s (producer, nonce, expires_at) VALUES ($1,$2,$3)
+				ON CONFLICT (producer, nonce) DO UPDATE SET expires_at = EXCLUDED.expires_at
+				WHERE %s.expires_at <= $4`,
+		signatureNoncesTable, signatureNoncesTable)
+
+	tag, err := p.sharedPool.Exec(ctx, q, producer, nonce, expiresAt, now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) PurgeNonces(ctx context.Context, now time.Time) error {
+	q := fmt.Sprintf("DELETE FROM %s WHERE expires_at <= $1", signatureNoncesTable)
+
+	_, err := p.sharedPool.Exec(ctx, q, now)
+	return err
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+)
+
+//deliveryCounts are columns of entity.DeliveryCounts aggregated over deliveries aliased as d
+var deliveryCounts = fmt.Sprintf(`
+		count(d.delivery_id) FILTER (WHERE d.status = '%s') as sent,
+		count(d.delivery_id) FILTER (WHERE d.status = '%s') as failed,
+		count(d.delivery_id) FILTER (WHERE d.status = '%s') as unreachable,
+		count(d.delivery_id) FILTER (WHERE d.status = '%s') as suppressed,
+		percentile_cont(0.5) WITHIN GROUP (ORDER BY d.latency_ms) FILTER (WHERE d.status = '%s') as latency_p50,
+		percentile_cont(0.95) WITHIN GROUP (ORDER BY d.latency_ms) FILTER (WHERE d.status = '%s') as latency_p95`,
+	entity.DeliverySent, entity.DeliveryFailed, entity.DeliveryUnreachable, entity.DeliverySuppressed,
+	entity.DeliverySent, entity.DeliverySent)
+
+//RecordFire saves the fire and deliveries made by it at once
+func (p *PostgresStorage) RecordFire(ctx context.Context, f *entity.Fire, deliveries []*entity.Delivery) error {
+	fireQ := fmt.Sprintf(
+		`INSERT INTO %s (event_id, status, recipients, request_id, tenant_id) VALUES ($1,$2,$3,$4,$5) RETURNING fire_id, fired_at`,
+		firesTable)
+	deliveryQ := fmt.Sprintf(
+		`INSERT INTO %s (fire_id, event_id, subscriber_id, channel, telegram_id, status, error, latency_ms, tenant_id)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+		deliveriesTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Release()
+
+	return c.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, fireQ, f.EventID, f.Status, f.Recipients, f.RequestID, tenantOf(ctx)).Scan(&f.FireID, &f.FiredAt); err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		batch := &pgx.Batch{}
+		for _, d := range deliveries {
+			d.FireID = &f.FireID
+			batch.Queue(deliveryQ, d.FireID, d.EventID, d.SubscriberID, d.Channel, d.TelegramID, d.Status, d.Error, d.LatencyMs, tenantOf(ctx))
+		}
+
+		br := tx.SendBatch(ctx, batch)
+		defer br.Close()
+
+		for range deliveries {
+			if _, err := br.Exec(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//GetStats aggregates fires and deliveries matching f. Buckets are in UTC and cover the whole range, even empty ones
+func (p *PostgresStorage) GetStats(ctx context.Context, f entity.StatsFilter) (*entity.Stats, error) {
+	//Event ids are the same in every tenant, so events are joined by tenant and id.
+	//Tenant is $4 in every query but buckets and top failing recipients, where it's $5
+	firesQ := fmt.Sprintf(
+		`SELECT count(*) FROM %s f JOIN %s e ON e.tenant_id = f.tenant_id AND e.event_id = f.event_id
+				WHERE f.tenant_id = $%%d AND f.fired_at >= $1 AND f.fired_at < $2 AND ($3 = '' OR e.name = $3)`,
+		firesTable, eventsTable)
+
+	totalsQ := fmt.Sprintf(
+		`SELECT (%s) as fires, %s
+				FROM %s d JOIN %s e ON e.tenant_id = d.tenant_id AND e.event_id = d.event_id
+				WHERE d.tenant_id = $4 AND d.created_at >= $1 AND d.created_at < $2 AND ($3 = '' OR e.name = $3)`,
+		fmt.Sprintf(firesQ, 4), deliveryCounts, deliveriesTable, eventsTable)
+
+	eventsQ := fmt.Sprintf(
+		`SELECT e.name as event_name, coalesce(fc.fires, 0) as fires, %s
+				FROM %s e
+				LEFT JOIN (SELECT event_id, count(*) as fires FROM %s
+					WHERE tenant_id = $4 AND fired_at >= $1 AND fired_at < $2 GROUP BY event_id) fc ON fc.event_id = e.event_id
+				LEFT JOIN %s d ON d.tenant_id = e.tenant_id AND d.event_id = e.event_id
+					AND d.created_at >= $1 AND d.created_at < $2
+				WHERE e.tenant_id = $4 AND ($3 = '' OR e.name = $3)
+				GROUP BY e.name, fc.fires
+				HAVING coalesce(fc.fires, 0) > 0 OR count(d.delivery_id) > 0
+				ORDER BY e.name ASC`,
+		deliveryCounts, eventsTable, firesTable, deliveriesTable)
+
+	channelsQ := fmt.Sprintf(
+		`SELECT d.channel, %s
+				FROM %s d JOIN %s e ON e.tenant_id = d.tenant_id AND e.event_id = d.event_id
+				WHERE d.tenant_id = $4 AND d.created_at >= $1 AND d.created_at < $2 AND ($3 = '' OR e.name = $3)
+				GROUP BY d.channel ORDER BY d.channel ASC`,
+		deliveryCounts, deliveriesTable, eventsTable)
+
+	//$4 is day or hour, both are valid fields of date_trunc and units of interval
+	bucketsQ := fmt.Sprintf(
+		`WITH buckets AS (
+					SELECT generate_series(
+						date_trunc($4, $1::timestamptz AT TIME ZONE 'UTC'),
+						$2::timestamptz AT TIME ZONE 'UTC' - interval '1 microsecond',
+						('1 ' || $4)::interval) as start
+				)
+				SELECT b.start AT TIME ZONE 'UTC' as start,
+				(%s AND date_trunc($4, f.fired_at AT TIME ZONE 'UTC') = b.start) as fires,
+				%s
+				FROM buckets b
+				LEFT JOIN (%s d JOIN %s e ON e.tenant_id = d.tenant_id AND e.event_id = d.event_id AND ($3 = '' OR e.name = $3))
+					ON d.tenant_id = $5 AND d.created_at >= $1 AND d.created_at < $2
+					AND date_trunc($4, d.created_at AT TIME ZONE 'UTC') = b.start
+				GROUP BY b.start ORDER BY b.start ASC`,
+		fmt.Sprintf(firesQ, 5), deliveryCounts, deliveriesTable, eventsTable)
+
+	failingQ := fmt.Sprintf(
+		`SELECT d.subscriber_id, s.phone_number,
+				count(*) FILTER (WHERE d.status = '%s') as failed,
+				count(*) FILTER (WHERE d.status = '%s') as unreachable,
+				(array_agg(d.error ORDER BY d.created_at DESC))[1] as last_error,
+				max(d.created_at) as last_failed_at
+				FROM %s d
+				JOIN %s e ON e.tenant_id = d.tenant_id AND e.event_id = d.event_id
+				JOIN %s s ON s.tenant_id = d.tenant_id AND s.subscriber_id = d.subscriber_id
+				WHERE d.tenant_id = $5 AND d.status IN ('%s', '%s') AND d.created_at >= $1 AND d.created_at < $2 AND ($3 = '' OR e.name = $3)
+				GROUP BY d.subscriber_id, s.phone_number
+				ORDER BY count(*) DESC, max(d.created_at) DESC LIMIT $4`,
+		entity.DeliveryFailed, entity.DeliveryUnreachable, deliveriesTable, eventsTable, subscribersTable,
+		entity.DeliveryFailed, entity.DeliveryUnreachable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	stats := &entity.Stats{From: f.From, To: f.To, Bucket: f.Bucket}
+
+	tenantID := tenantOf(ctx)
+
+	rows, err := c.Query(ctx, totalsQ, f.From, f.To, f.EventName, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if err = pgxscan.ScanOne(&stats.Totals, rows); err != nil {
+		return nil, err
+	}
+
+	for _, part := range []struct {
+		dst  interface{}
+		q    string
+		args []interface{}
+	}{
+		{&stats.Events, eventsQ, []interface{}{f.From, f.To, f.EventName, tenantID}},
+		{&stats.Channels, channelsQ, []interface{}{f.From, f.To, f.EventName, tenantID}},
+		{&stats.Buckets, bucketsQ, []interface{}{f.From, f.To, f.EventName, f.Bucket, tenantID}},
+		{&stats.TopFailingRecipients, failingQ, []interface{}{f.From, f.To, f.EventName, f.Limit, tenantID}},
+	} {
+		rows, err := c.Query(ctx, part.q, part.args...)
+		if err != nil {
+			return nil, err
+		}
+		err = pgxscan.ScanAll(part.dst, rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
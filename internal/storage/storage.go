@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/georgysavva/scany/pgxscan"
 	"github.com/jackc/pgx/v4"
@@ -14,20 +15,88 @@ import (
 )
 
 type DBStorage interface {
-	GetSubscribersDataJoined(ctx context.Context) ([]*response_object.SubscriberRO, error)
+	GetSubscribersDataJoined(ctx context.Context, f entity.SubscriberFilter) ([]*response_object.SubscriberRO, error)
 	GetEventSubscribers(ctx context.Context, eventID uint64) ([]*entity.Subscriber, error)
-	GetSubscribersWithoutSubs(ctx context.Context) ([]*response_object.SubscriberRO, error)
+	GetSubscribersWithoutSubs(ctx context.Context, f entity.SubscriberFilter) ([]*response_object.SubscriberRO, error)
+	ExportSubscribers(ctx context.Context, fn func(*response_object.SubscriberExportRO) error) error
+	ExportSubscriptions(ctx context.Context, fn func(*response_object.SubscriptionExportRO) error) error
+	ExportTelegramLinks(ctx context.Context, fn func(*response_object.TelegramLinkRO) error) error
+	ImportSubscriptions(ctx context.Context, rows []*response_object.ImportRowRO, commit bool) error
 	GetSubscriberByPhone(ctx context.Context, phoneNumber string) (*entity.Subscriber, error)
+	GetSubscribersByPhones(ctx context.Context, phoneNumbers []string) ([]*entity.Subscriber, error)
+	GetSubscribersByIDs(ctx context.Context, subscriberIDs []uint64) ([]*entity.Subscriber, error)
 	GetSubscription(ctx context.Context, subscriberID uint64, eventID uint64) (*entity.Subscription, error)
-	GetTelegramSubscribers(ctx context.Context, phoneNumbers []string) ([]*entity.TelegramSubscriber, error)
-	GetTelegramSubscriber(ctx context.Context, phoneNumber string) (*entity.TelegramSubscriber, error)
+	GetTelegramSubscribers(ctx context.Context, botName string, phoneNumbers []string) ([]*entity.TelegramSubscriber, error)
+	GetTelegramLinksByPhone(ctx context.Context, phoneNumber string) ([]*response_object.TelegramLinkRO, error)
+	GetTelegramLinksByTelegramID(ctx context.Context, botName string, telegramID int64) ([]*response_object.TelegramLinkRO, error)
+	GetTelegramLink(ctx context.Context, linkID uint64) (*response_object.TelegramLinkRO, error)
+	SetTelegramLinkEnabled(ctx context.Context, linkID uint64, enabled bool) (bool, error)
+	DeleteTelegramLink(ctx context.Context, linkID uint64) (bool, error)
+	DeactivateTelegramChat(ctx context.Context, botName string, telegramID int64, reason string) (bool, error)
+	ReactivateTelegramChat(ctx context.Context, botName string, telegramID int64) (bool, error)
+	DeleteTelegramSubscriber(ctx context.Context, botName string, telegramID int64) (bool, error)
+	GetSubscriberEvents(ctx context.Context, subscriberID uint64) ([]*response_object.SubscriberEventRO, error)
+	CancelSubscriberSubscription(ctx context.Context, subscriberID uint64, eventID uint64) (bool, error)
 	RegisterSubscriber(ctx context.Context, phoneNumber string) (uint64, error)
-	RegisterTelegramSubscriber(ctx context.Context, telegramID int64, subscriberID uint64) (bool, error)
+	UpdateSubscriberPhone(ctx context.Context, subscriberID uint64, phoneNumber string) (bool, error)
+	SetSubscriberActive(ctx context.Context, subscriberID uint64, active bool) (bool, error)
+	EraseSubscriber(ctx context.Context, subscriberID uint64) (bool, error)
+	RegisterTelegramSubscriber(ctx context.Context, botName string, telegramID int64, subscriberID uint64) (bool, error)
+	RegisterTelegramChat(ctx context.Context, botName string, chatID int64, chatType string, threadID *int, subscriberID uint64) (bool, error)
+	MigrateTelegramChat(ctx context.Context, botName string, fromChatID int64, toChatID int64) (bool, error)
+	CreateLinkCode(ctx context.Context, code string, subscriberID uint64, threadID *int, ttlMinutes int) (*entity.LinkCode, error)
+	ConsumeLinkCode(ctx context.Context, code string) (*entity.LinkCode, error)
 	SubscribeToEvent(ctx context.Context, subscriberID uint64, eventID uint64) (uint64, error)
-	CancelSubscription(ctx context.Context, subscriptionID uint64) (bool, error)
+	CancelSubscription(ctx context.Context, subscriptionID uint64) (*entity.Subscription, error)
 	DoesExist(ctx context.Context, eventName string) (uint64, error)
 	GetAvailableEvents(ctx context.Context) ([]*entity.Event, error)
 	RegisterEvent(ctx context.Context, e entity.Event) error
+	CreateEscalation(ctx context.Context, eventID uint64, text string, timeoutMinutes int) (uint64, error)
+	GetEscalation(ctx context.Context, escalationID uint64) (*entity.Escalation, error)
+	GetEscalations(ctx context.Context, status string) ([]*entity.Escalation, error)
+	GetDueEscalations(ctx context.Context) ([]*entity.Escalation, error)
+	AcknowledgeEscalation(ctx context.Context, escalationID uint64, telegramID int64) (bool, error)
+	PromoteEscalation(ctx context.Context, escalationID uint64, fromTier int, timeoutMinutes int) (bool, error)
+	ExhaustEscalation(ctx context.Context, escalationID uint64, fromTier int) (bool, error)
+	DeleteEscalation(ctx context.Context, escalationID uint64) (bool, error)
+	CreateGroup(ctx context.Context, name string) (uint64, error)
+	GetGroups(ctx context.Context) ([]*entity.Group, error)
+	GetGroup(ctx context.Context, groupID uint64) (*entity.Group, error)
+	GetGroupsByNames(ctx context.Context, names []string) ([]*entity.Group, error)
+	DeleteGroup(ctx context.Context, groupID uint64) (bool, error)
+	GetGroupMembers(ctx context.Context, groupID uint64) ([]*entity.Subscriber, error)
+	GetGroupsMembers(ctx context.Context, groupNames []string) ([]*entity.Subscriber, error)
+	GetGroupEvents(ctx context.Context, groupID uint64) ([]*entity.Event, error)
+	AddGroupMember(ctx context.Context, groupID uint64, subscriberID uint64) (bool, error)
+	RemoveGroupMember(ctx context.Context, groupID uint64, subscriberID uint64) (bool, error)
+	SubscribeGroupToEvent(ctx context.Context, groupID uint64, eventID uint64) (bool, error)
+	CancelGroupSubscription(ctx context.Context, groupID uint64, eventID uint64) (bool, error)
+	ClaimIdempotencyKey(ctx context.Context, key string, eventID uint64) (bool, error)
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	SetIdempotencyKeyState(ctx context.Context, key string, state string) error
+	GetIdempotencyRecipients(ctx context.Context, key string) ([]uint64, error)
+	AddIdempotencyRecipient(ctx context.Context, key string, linkID uint64) error
+	Mute(ctx context.Context, subscriberID uint64, eventID *uint64, until time.Time) error
+	Unmute(ctx context.Context, subscriberID uint64, eventID *uint64) (bool, error)
+	GetActiveMutes(ctx context.Context, subscriberID uint64) ([]*entity.Mute, error)
+	GetMutedSubscribers(ctx context.Context, eventID uint64, subscriberIDs []uint64) ([]uint64, error)
+	RecordDeliveries(ctx context.Context, eventID uint64, subscriberIDs []uint64, status string) error
+	RecordFire(ctx context.Context, f *entity.Fire, deliveries []*entity.Delivery) error
+	GetStats(ctx context.Context, f entity.StatsFilter) (*entity.Stats, error)
+	GetTemplate(ctx context.Context, eventID uint64) (*entity.Template, error)
+	SetTemplate(ctx context.Context, eventID uint64, text string) error
+	DeleteTemplate(ctx context.Context, eventID uint64) (bool, error)
+	CreateTenant(ctx context.Context, name string) (uint64, error)
+	GetTenants(ctx context.Context) ([]*entity.Tenant, error)
+	GetTenant(ctx context.Context, tenantID uint64) (*entity.Tenant, error)
+	GetTenantByName(ctx context.Context, name string) (*entity.Tenant, error)
+	CreateAPIKey(ctx context.Context, tenantID uint64, name string, keyHash string, scopes []string) (*entity.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*entity.APIKey, error)
+	GetAPIKeys(ctx context.Context, tenantID uint64) ([]*entity.APIKey, error)
+	RevokeAPIKey(ctx context.Context, tenantID uint64, apiKeyID uint64) (*entity.APIKey, error)
+	TouchAPIKey(ctx context.Context, apiKeyID uint64) error
+	CreateAuditEntry(ctx context.Context, e *entity.AuditEntry) error
+	GetAuditEntries(ctx context.Context, f entity.AuditFilter) ([]*entity.AuditEntry, error)
 }
 
 const (
@@ -35,23 +104,88 @@ const (
 	subscriptionsTable       = "subscriptions"
 	telegramSubscribersTable = "telegram_subscribers"
 	eventsTable              = "events"
+	escalationsTable         = "escalations"
+	groupsTable              = "groups"
+	groupMembersTable        = "group_members"
+	groupSubscriptionsTable  = "group_subscriptions"
+	idempotencyKeysTable     = "fire_idempotency_keys"
+	idempotencyRecipientsTbl = "fire_idempotency_recipients"
+	mutesTable               = "mutes"
+	deliveriesTable          = "deliveries"
+	firesTable               = "fires"
+	linkCodesTable           = "link_codes"
+	templatesTable           = "templates"
+	tenantsTable             = "tenants"
+	apiKeysTable             = "api_keys"
+	auditLogTable            = "audit_log"
+	signatureNoncesTable     = "signature_nonces"
 )
 
 type PostgresStorage struct {
-	pool   *pgxpool.Pool
-	logger *zap.SugaredLogger
+	//pool queries data of tenant in ctx. sharedPool queries tables shared by tenants (tenants, api keys and nonces)
+	pool       *tenantPool
+	sharedPool *pgxpool.Pool
+	logger     *zap.SugaredLogger
 }
 
 func NewPostgresStorage(logger *zap.SugaredLogger, pool *pgxpool.Pool) *PostgresStorage {
-	return &PostgresStorage{pool: pool, logger: logger}
+	return &PostgresStorage{pool: &tenantPool{pool: pool}, sharedPool: pool, logger: logger}
 }
 
-func (p *PostgresStorage) GetSubscribersWithoutSubs(ctx context.Context) ([]*response_object.SubscriberRO, error) {
+//subscriberSorts are ORDER BY clauses of entity.SubscriberFilter sorts and conditions of rows after the cursor,
+//subscribers are aliased as sub. Sort columns are unique, so they're enough for the cursor
+var subscriberSorts = map[string]struct {
+	order string
+	after string
+}{
+	entity.SortPhoneNumber:     {order: "phone_number ASC", after: "sub.phone_number > $5"},
+	entity.SortPhoneNumberDesc: {order: "phone_number DESC", after: "sub.phone_number < $5"},
+	entity.SortSubscriberID:    {order: "subscriber_id ASC", after: "sub.subscriber_id > NULLIF($5, '')::bigint"},
+	entity.SortSubscriberDesc:  {order: "subscriber_id DESC", after: "sub.subscriber_id < NULLIF($5, '')::bigint"},
+}
+
+//subscribersPage selects a page of subscribers of filter f with subscriber_id, phone_number and telegram and mute columns
+//of response_object.SubscriberRO, ordered by f.Sort. Params are the ones of subscriberPageArgs
+func subscribersPage(f entity.SubscriberFilter, where string) (string, string) {
+	sort, ok := subscriberSorts[f.Sort]
+	if ok != true {
+		sort = subscriberSorts[entity.SortPhoneNumber]
+	}
+	//Empty filters match every subscriber
+	q := fmt.Sprintf(
+		`SELECT sub.subscriber_id, sub.phone_number,
+				EXISTS(SELECT 1 FROM %s tgsub WHERE tgsub.tenant_id = sub.tenant_id AND tgsub.subscriber_id = sub.subscriber_id
+					AND tgsub.enabled AND tgsub.active) as has_telegram_subscription,
+				(SELECT count(*) FROM %s tgsub WHERE tgsub.tenant_id = sub.tenant_id AND tgsub.subscriber_id = sub.subscriber_id
+					AND tgsub.active = false) as inactive_telegram_links,
+				(SELECT max(m.muted_until) FROM %s m WHERE m.tenant_id = sub.tenant_id AND m.subscriber_id = sub.subscriber_id
+					AND m.event_id IS NULL AND m.muted_until > now()) as muted_until
+				FROM %s sub
+				WHERE sub.tenant_id = $7
+				AND ($1 = '' OR left(sub.phone_number, length($1)) = $1)
+				AND ($2 = '' OR EXISTS(SELECT 1 FROM %s subs JOIN %s e ON subs.tenant_id = e.tenant_id AND subs.event_id = e.event_id
+					WHERE subs.tenant_id = sub.tenant_id AND subs.subscriber_id = sub.subscriber_id AND e.name = $2))
+				AND ($3::boolean IS NULL OR EXISTS(SELECT 1 FROM %s tgsub
+					WHERE tgsub.tenant_id = sub.tenant_id AND tgsub.subscriber_id = sub.subscriber_id AND tgsub.enabled AND tgsub.active) = $3)
+				AND ($4 = '' OR EXISTS(SELECT 1 FROM %s gm JOIN %s g ON gm.tenant_id = g.tenant_id AND gm.group_id = g.group_id
+					WHERE gm.tenant_id = sub.tenant_id AND gm.subscriber_id = sub.subscriber_id AND g.name = $4))
+				AND ($5 = '' OR %s)
+				%s
+				ORDER BY %s LIMIT $6`,
+		telegramSubscribersTable, telegramSubscribersTable, mutesTable, subscribersTable, subscriptionsTable, eventsTable,
+		telegramSubscribersTable, groupMembersTable, groupsTable, sort.after, where, sort.order)
+
+	return q, sort.order
+}
+
+func subscriberPageArgs(ctx context.Context, f entity.SubscriberFilter) []interface{} {
+	return []interface{}{f.PhonePrefix, f.EventName, f.HasTelegramSubscription, f.GroupName, f.After, f.Limit, tenantOf(ctx)}
+}
+
+//GetSubscribersWithoutSubs returns a page of subscribers matching f, without their subscriptions
+func (p *PostgresStorage) GetSubscribersWithoutSubs(ctx context.Context, f entity.SubscriberFilter) ([]*response_object.SubscriberRO, error) {
 
-	q := fmt.Sprintf(`
-		SELECT sub.phone_number, COALESCE(tgsub.subscriber_id, 0)::boolean as has_telegram_subscription
-		FROM %s sub LEFT JOIN %s tgsub ON sub.subscriber_id = tgsub.subscriber_id ORDER BY sub.phone_number ASC`,
-		subscribersTable, telegramSubscribersTable)
+	q, _ := subscribersPage(f, "")
 
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
@@ -59,7 +193,7 @@ func (p *PostgresStorage) GetSubscribersWithoutSubs(ctx context.Context) ([]*res
 	}
 	defer c.Release()
 
-	rows, err := c.Query(ctx, q)
+	rows, err := c.Query(ctx, q, subscriberPageArgs(ctx, f)...)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +203,8 @@ func (p *PostgresStorage) GetSubscribersWithoutSubs(ctx context.Context) ([]*res
 
 	for rows.Next() {
 		var subscriber response_object.SubscriberRO
-		err = rows.Scan(&subscriber.PhoneNumber, &subscriber.HasTelegramSubscription)
+		err = rows.Scan(&subscriber.SubscriberID, &subscriber.PhoneNumber, &subscriber.HasTelegramSubscription,
+			&subscriber.InactiveTelegramLinks, &subscriber.MutedUntil)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				return []*response_object.SubscriberRO{}, nil
@@ -83,16 +218,23 @@ func (p *PostgresStorage) GetSubscribersWithoutSubs(ctx context.Context) ([]*res
 	return subscribers, nil
 }
 
-func (p *PostgresStorage) GetSubscribersDataJoined(ctx context.Context) ([]*response_object.SubscriberRO, error) {
+//GetSubscribersDataJoined returns a page of subscribers matching f who have subscriptions, with their subscriptions
+func (p *PostgresStorage) GetSubscribersDataJoined(ctx context.Context, f entity.SubscriberFilter) ([]*response_object.SubscriberRO, error) {
+
+	page, order := subscribersPage(f, fmt.Sprintf(
+		"AND EXISTS(SELECT 1 FROM %s subs WHERE subs.tenant_id = sub.tenant_id AND subs.subscriber_id = sub.subscriber_id)",
+		subscriptionsTable))
 
 	q := fmt.Sprintf(
-		`SELECT sub.phone_number, COALESCE(tgsub.subscriber_id,0)::boolean as has_telegram_subscription,
-				subs.subscription_id, e.name, e.translate, e.event_id FROM %s sub
-				JOIN %s subs ON sub.subscriber_id = subs.subscriber_id
-				JOIN %s e ON subs.event_id = e.event_id
-				LEFT JOIN %s tgsub ON sub.subscriber_id = tgsub.subscriber_id
-				ORDER BY sub.phone_number ASC`,
-		subscribersTable, subscriptionsTable, eventsTable, telegramSubscribersTable)
+		`WITH page AS (%s)
+				SELECT page.subscriber_id, page.phone_number, page.has_telegram_subscription, page.inactive_telegram_links,
+				page.muted_until, subs.subscription_id, e.name, e.translate, e.event_id, em.muted_until FROM page
+				JOIN %s subs ON subs.tenant_id = $7 AND page.subscriber_id = subs.subscriber_id
+				JOIN %s e ON subs.tenant_id = e.tenant_id AND subs.event_id = e.event_id
+				LEFT JOIN %s em ON em.tenant_id = e.tenant_id AND page.subscriber_id = em.subscriber_id AND em.event_id = e.event_id
+					AND em.muted_until > now()
+				ORDER BY page.%s, e.name ASC`,
+		page, subscriptionsTable, eventsTable, mutesTable, order)
 
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
@@ -100,7 +242,7 @@ func (p *PostgresStorage) GetSubscribersDataJoined(ctx context.Context) ([]*resp
 	}
 	defer c.Release()
 
-	rows, err := c.Query(ctx, q)
+	rows, err := c.Query(ctx, q, subscriberPageArgs(ctx, f)...)
 	if err != nil {
 		return nil, err
 	}
@@ -113,13 +255,17 @@ func (p *PostgresStorage) GetSubscribersDataJoined(ctx context.Context) ([]*resp
 		var subscriptionRO response_object.SubscriptionRO
 
 		err = rows.Scan(
+			&subscriberRO.SubscriberID,
 			&subscriberRO.PhoneNumber,
 			&subscriberRO.HasTelegramSubscription,
+			&subscriberRO.InactiveTelegramLinks,
+			&subscriberRO.MutedUntil,
 
 			&subscriptionRO.SubscriptionID,
 			&subscriptionRO.Event.Name,
 			&subscriptionRO.Event.Translate,
 			&subscriptionRO.Event.EventID,
+			&subscriptionRO.MutedUntil,
 		)
 
 		if err != nil {
@@ -152,21 +298,21 @@ func (p *PostgresStorage) GetSubscribersDataJoined(ctx context.Context) ([]*resp
 func (p *PostgresStorage) SubscribeToEvent(ctx context.Context, subscriberID uint64, eventID uint64) (uint64, error) {
 	var subscriptionID uint64
 	q := fmt.Sprintf(
-		"INSERT INTO %s (subscriber_id, event_id) VALUES ($1,$2) ON CONFLICT DO NOTHING RETURNING subscription_id",
+		"INSERT INTO %s (subscriber_id, event_id, tenant_id) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING RETURNING subscription_id",
 		subscriptionsTable)
 
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
-		return 0, nil
+		return 0, err
 	}
 	defer c.Release()
 
-	err = c.QueryRow(ctx, q, subscriberID, eventID).Scan(&subscriptionID)
+	err = c.QueryRow(ctx, q, subscriberID, eventID, tenantOf(ctx)).Scan(&subscriptionID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, nil
 		}
-		return 0, err
+		return 0, pgError(err)
 	}
 	if subscriptionID == 0 {
 		return 0, nil
@@ -177,14 +323,14 @@ func (p *PostgresStorage) SubscribeToEvent(ctx context.Context, subscriberID uin
 func (p *PostgresStorage) GetSubscription(ctx context.Context, subscriberID uint64, eventID uint64) (*entity.Subscription, error) {
 	var sub entity.Subscription
 
-	q := fmt.Sprintf("SELECT * FROM %s WHERE subscriber_id = $1 AND event_id = $2", subscriptionsTable)
+	q := fmt.Sprintf("SELECT * FROM %s WHERE tenant_id = $3 AND subscriber_id = $1 AND event_id = $2", subscriptionsTable)
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer c.Release()
 
-	rows, err := c.Query(ctx, q, subscriberID, eventID)
+	rows, err := c.Query(ctx, q, subscriberID, eventID, tenantOf(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -203,7 +349,7 @@ func (p *PostgresStorage) GetSubscription(ctx context.Context, subscriberID uint
 
 func (p *PostgresStorage) RegisterSubscriber(ctx context.Context, phoneNumber string) (uint64, error) {
 	var subscriberID uint64
-	q := fmt.Sprintf("INSERT INTO %s (phone_number) VALUES ($1) RETURNING subscriber_id", subscribersTable)
+	q := fmt.Sprintf("INSERT INTO %s (phone_number, tenant_id) VALUES ($1,$2) RETURNING subscriber_id", subscribersTable)
 
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
@@ -211,18 +357,24 @@ func (p *PostgresStorage) RegisterSubscriber(ctx context.Context, phoneNumber st
 	}
 	defer c.Release()
 
-	err = c.QueryRow(ctx, q, phoneNumber).Scan(&subscriberID)
+	err = c.QueryRow(ctx, q, phoneNumber, tenantOf(ctx)).Scan(&subscriberID)
 	if err != nil {
-		return 0, err
+		return 0, pgError(err)
 	}
 	return subscriberID, nil
 }
 
 func (p *PostgresStorage) GetEventSubscribers(ctx context.Context, eventID uint64) ([]*entity.Subscriber, error) {
 	var subs []*entity.Subscriber
+	//Subscribers of the event directly or via membership in the group subscribed to the event
 	q := fmt.Sprintf(
-		`SELECT sub.subscriber_id, sub.phone_number FROM %s sub JOIN %s subs ON sub.subscriber_id = subs.subscriber_id WHERE subs.event_id = $1`,
-		subscribersTable, subscriptionsTable)
+		`SELECT sub.subscriber_id, sub.phone_number, sub.active, sub.deactivated_at FROM %s sub
+				WHERE sub.tenant_id = $2 AND sub.subscriber_id IN (
+				SELECT subs.subscriber_id FROM %s subs WHERE subs.tenant_id = $2 AND subs.event_id = $1
+				UNION
+				SELECT gm.subscriber_id FROM %s gm JOIN %s gs ON gm.tenant_id = gs.tenant_id AND gm.group_id = gs.group_id
+				WHERE gs.tenant_id = $2 AND gs.event_id = $1)`,
+		subscribersTable, subscriptionsTable, groupMembersTable, groupSubscriptionsTable)
 
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
@@ -230,7 +382,7 @@ func (p *PostgresStorage) GetEventSubscribers(ctx context.Context, eventID uint6
 	}
 	defer c.Release()
 
-	rows, err := c.Query(ctx, q, eventID)
+	rows, err := c.Query(ctx, q, eventID, tenantOf(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -249,7 +401,7 @@ func (p *PostgresStorage) GetEventSubscribers(ctx context.Context, eventID uint6
 
 func (p *PostgresStorage) GetSubscriberByPhone(ctx context.Context, phoneNumber string) (*entity.Subscriber, error) {
 	var sub entity.Subscriber
-	q := fmt.Sprintf("SELECT * FROM %s WHERE phone_number = $1", subscribersTable)
+	q := fmt.Sprintf("SELECT * FROM %s WHERE tenant_id = $2 AND phone_number = $1", subscribersTable)
 
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
@@ -257,7 +409,7 @@ func (p *PostgresStorage) GetSubscriberByPhone(ctx context.Context, phoneNumber
 	}
 	defer c.Release()
 
-	rows, err := c.Query(ctx, q, phoneNumber)
+	rows, err := c.Query(ctx, q, phoneNumber, tenantOf(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -273,11 +425,67 @@ func (p *PostgresStorage) GetSubscriberByPhone(ctx context.Context, phoneNumber
 	return &sub, nil
 }
 
-func (p *PostgresStorage) RegisterTelegramSubscriber(ctx context.Context, telegramID int64, subscriberID uint64) (bool, error) {
+func (p *PostgresStorage) GetSubscribersByPhones(ctx context.Context, phoneNumbers []string) ([]*entity.Subscriber, error) {
+	var subs []*entity.Subscriber
+	q := fmt.Sprintf(
+		"SELECT subscriber_id, phone_number, active, deactivated_at FROM %s WHERE tenant_id = $2 AND phone_number = ANY($1)",
+		subscribersTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, phoneNumbers, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&subs, rows)
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (p *PostgresStorage) GetSubscribersByIDs(ctx context.Context, subscriberIDs []uint64) ([]*entity.Subscriber, error) {
+	var subs []*entity.Subscriber
+	q := fmt.Sprintf(
+		"SELECT subscriber_id, phone_number, active, deactivated_at FROM %s WHERE tenant_id = $2 AND subscriber_id = ANY($1)",
+		subscribersTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, subscriberIDs, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&subs, rows)
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+//RegisterTelegramSubscriber links telegram account with subscriber. Disabled or inactive link is enabled again.
+//Returns false if the link already exists and is enabled
+func (p *PostgresStorage) RegisterTelegramSubscriber(ctx context.Context, botName string, telegramID int64, subscriberID uint64) (bool, error) {
 
 	q := fmt.Sprintf(
-		"INSERT INTO %s (telegram_id, subscriber_id) VALUES ($1,$2) ON CONFLICT DO NOTHING RETURNING telegram_id",
-		telegramSubscribersTable)
+		`INSERT INTO %s (telegram_id, subscriber_id, bot_name, tenant_id) VALUES ($1,$2,$3,$4)
+				ON CONFLICT (bot_name, telegram_id, subscriber_id) DO UPDATE
+				SET enabled = true, active = true, inactive_reason = NULL, inactive_since = NULL
+				WHERE %s.enabled = false OR %s.active = false
+				RETURNING telegram_id`,
+		telegramSubscribersTable, telegramSubscribersTable, telegramSubscribersTable)
 
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
@@ -285,7 +493,7 @@ func (p *PostgresStorage) RegisterTelegramSubscriber(ctx context.Context, telegr
 	}
 	defer c.Release()
 
-	rows, err := c.Query(ctx, q, telegramID, subscriberID)
+	rows, err := c.Query(ctx, q, telegramID, subscriberID, botName, tenantOf(ctx))
 	if err != nil {
 		return false, err
 	}
@@ -304,11 +512,31 @@ func (p *PostgresStorage) RegisterTelegramSubscriber(ctx context.Context, telegr
 	return true, nil
 }
 
-func (p *PostgresStorage) GetTelegramSubscriber(ctx context.Context, phoneNumber string) (*entity.TelegramSubscriber, error) {
+//DeleteTelegramSubscriber removes every link of telegram chat
+func (p *PostgresStorage) DeleteTelegramSubscriber(ctx context.Context, botName string, telegramID int64) (bool, error) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $3 AND telegram_id = $1 AND bot_name = $2", telegramSubscribersTable)
+
+	tag, err := p.pool.Exec(ctx, q, telegramID, botName, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+//GetSubscriberEvents returns events subscriber is subscribed to directly (empty group_name) and via groups
+func (p *PostgresStorage) GetSubscriberEvents(ctx context.Context, subscriberID uint64) ([]*response_object.SubscriberEventRO, error) {
 	q := fmt.Sprintf(
-		`SELECT tgsub.telegram_id, tgsub.subscriber_id FROM %s tgsub JOIN %s sub ON
-				tgsub.subscriber_id = sub.subscriber.id WHERE sub.phone_number = $1`,
-		telegramSubscribersTable, subscribersTable)
+		`SELECT e.event_id, e.name, e.translate, '' AS group_name FROM %s e
+				JOIN %s subs ON e.tenant_id = subs.tenant_id AND e.event_id = subs.event_id
+				WHERE e.tenant_id = $2 AND subs.subscriber_id = $1
+				UNION ALL
+				SELECT e.event_id, e.name, e.translate, g.name AS group_name FROM %s e
+				JOIN %s gs ON e.tenant_id = gs.tenant_id AND e.event_id = gs.event_id
+				JOIN %s g ON gs.tenant_id = g.tenant_id AND gs.group_id = g.group_id
+				JOIN %s gm ON g.tenant_id = gm.tenant_id AND g.group_id = gm.group_id
+				WHERE e.tenant_id = $2 AND gm.subscriber_id = $1
+				ORDER BY event_id ASC, group_name ASC`,
+		eventsTable, subscriptionsTable, eventsTable, groupSubscriptionsTable, groupsTable, groupMembersTable)
 
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
@@ -316,38 +544,41 @@ func (p *PostgresStorage) GetTelegramSubscriber(ctx context.Context, phoneNumber
 	}
 	defer c.Release()
 
-	rows, err := c.Query(ctx, q, phoneNumber)
+	rows, err := c.Query(ctx, q, subscriberID, tenantOf(ctx))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var tgsub entity.TelegramSubscriber
+	var events []*response_object.SubscriberEventRO
 
-	err = pgxscan.ScanOne(&tgsub, rows)
+	err = pgxscan.ScanAll(&events, rows)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
 		return nil, err
 	}
-
-	return &tgsub, nil
+	return events, nil
 }
 
-func (p *PostgresStorage) GetTelegramSubscribers(ctx context.Context, phoneNumbers []string) ([]*entity.TelegramSubscriber, error) {
-	whereq := ""
-	for i, ph := range phoneNumbers {
-		if i != len(phoneNumbers)-1 {
-			whereq += fmt.Sprintf("sub.phone_number = '%s' or ", ph)
-		} else {
-			whereq += fmt.Sprintf("sub.phone_number = '%s'", ph)
-		}
+func (p *PostgresStorage) CancelSubscriberSubscription(ctx context.Context, subscriberID uint64, eventID uint64) (bool, error) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $3 AND subscriber_id = $1 AND event_id = $2", subscriptionsTable)
+
+	tag, err := p.pool.Exec(ctx, q, subscriberID, eventID, tenantOf(ctx))
+	if err != nil {
+		return false, err
 	}
+	return tag.RowsAffected() != 0, nil
+}
 
-	mainq := fmt.Sprintf(
-		"SELECT tgsub.telegram_id, tgsub.subscriber_id FROM %s tgsub JOIN %s sub ON tgsub.subscriber_id = sub.subscriber_id WHERE %s",
-		telegramSubscribersTable, subscribersTable, whereq)
+//GetTelegramSubscribers returns enabled and active links of active subscribers made with the bot.
+//Chat linked with several of them is returned once
+func (p *PostgresStorage) GetTelegramSubscribers(ctx context.Context, botName string, phoneNumbers []string) ([]*entity.TelegramSubscriber, error) {
+	q := fmt.Sprintf(
+		`SELECT DISTINCT ON (tgsub.telegram_id, COALESCE(tgsub.thread_id, 0)) %s FROM %s tgsub
+				JOIN %s sub ON tgsub.tenant_id = sub.tenant_id AND tgsub.subscriber_id = sub.subscriber_id
+				WHERE tgsub.tenant_id = $3 AND sub.phone_number = ANY($1) AND sub.active
+				AND tgsub.bot_name = $2 AND tgsub.enabled AND tgsub.active
+				ORDER BY tgsub.telegram_id, COALESCE(tgsub.thread_id, 0), tgsub.link_id`,
+		telegramLinkColumns, telegramSubscribersTable, subscribersTable)
 
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
@@ -355,7 +586,7 @@ func (p *PostgresStorage) GetTelegramSubscribers(ctx context.Context, phoneNumbe
 	}
 	defer c.Release()
 
-	rows, err := c.Query(ctx, mainq)
+	rows, err := c.Query(ctx, q, phoneNumbers, botName, tenantOf(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -375,30 +606,38 @@ func (p *PostgresStorage) GetTelegramSubscribers(ctx context.Context, phoneNumbe
 
 }
 
-func (p *PostgresStorage) CancelSubscription(ctx context.Context, subscriptionID uint64) (bool, error) {
-	q := fmt.Sprintf("DELETE FROM %s WHERE subscription_id = $1 RETURNING subscription_id", subscriptionsTable)
+//CancelSubscription returns deleted subscription, nil if there's no such subscription
+func (p *PostgresStorage) CancelSubscription(ctx context.Context, subscriptionID uint64) (*entity.Subscription, error) {
+	var subscription entity.Subscription
+	q := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $2 AND subscription_id = $1 RETURNING *", subscriptionsTable)
 
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	defer c.Release()
 
-	rows, err := c.Query(ctx, q, subscriptionID)
+	rows, err := c.Query(ctx, q, subscriptionID, tenantOf(ctx))
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	defer rows.Close()
 
-	return rows.Next(), nil
-
+	err = pgxscan.ScanOne(&subscription, rows)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &subscription, nil
 }
 
 func (p *PostgresStorage) DoesExist(ctx context.Context, eventName string) (uint64, error) {
 	var eventID uint64
-	q := fmt.Sprintf("SELECT event_Id FROM %s WHERE name = $1", eventsTable)
-	err := p.pool.QueryRow(ctx, q, eventName).Scan(&eventID)
+	q := fmt.Sprintf("SELECT event_Id FROM %s WHERE tenant_id = $2 AND name = $1", eventsTable)
+	err := p.pool.QueryRow(ctx, q, eventName, tenantOf(ctx)).Scan(&eventID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, nil
@@ -409,8 +648,8 @@ func (p *PostgresStorage) DoesExist(ctx context.Context, eventName string) (uint
 }
 
 func (p *PostgresStorage) RegisterEvent(ctx context.Context, ev entity.Event) error {
-	q := fmt.Sprintf("INSERT INTO %s (event_id, name,translate) VALUES($1,$2,$3) ON CONFLICT DO NOTHING", eventsTable)
-	_, err := p.pool.Exec(ctx, q, ev.EventID, ev.Name, ev.Translate)
+	q := fmt.Sprintf("INSERT INTO %s (event_id, name, translate, tenant_id) VALUES($1,$2,$3,$4) ON CONFLICT DO NOTHING", eventsTable)
+	_, err := p.pool.Exec(ctx, q, ev.EventID, ev.Name, ev.Translate, tenantOf(ctx))
 	if err != nil {
 		return err
 	}
@@ -418,7 +657,7 @@ func (p *PostgresStorage) RegisterEvent(ctx context.Context, ev entity.Event) er
 }
 
 func (p *PostgresStorage) GetAvailableEvents(ctx context.Context) ([]*entity.Event, error) {
-	q := fmt.Sprintf(`SELECT * FROM %s`, eventsTable)
+	q := fmt.Sprintf(`SELECT * FROM %s WHERE tenant_id = $1`, eventsTable)
 
 	c, err := p.pool.Acquire(ctx)
 	if err != nil {
@@ -426,7 +665,7 @@ func (p *PostgresStorage) GetAvailableEvents(ctx context.Context) ([]*entity.Eve
 	}
 	defer c.Release()
 
-	rows, err := c.Query(ctx, q)
+	rows, err := c.Query(ctx, q, tenantOf(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -443,3 +682,57 @@ func (p *PostgresStorage) GetAvailableEvents(ctx context.Context) ([]*entity.Eve
 
 	return events, nil
 }
+
+//ClaimIdempotencyKey claims new key or the key of partially failed fire. Key of fire that's in progress or done isn't claimed
+func (p *PostgresStorage) ClaimIdempotencyKey(ctx context.Context, key string, eventID uint64) (bool, error) {
+	q := fmt.Sprintf(`INSERT INTO %s (tenant_id, key, event_id, state) VALUES ($1,$2,$3,$4)
+		ON CONFLICT (tenant_id, key) DO UPDATE SET state = $4 WHERE %s.state = $5`, idempotencyKeysTable, idempotencyKeysTable)
+
+	tag, err := p.pool.Exec(ctx, q, tenantOf(ctx), key, eventID, entity.IdempotencyPending, entity.IdempotencyPartial)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	q := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $2 AND key = $1", idempotencyKeysTable)
+
+	_, err := p.pool.Exec(ctx, q, key, tenantOf(ctx))
+	return err
+}
+
+func (p *PostgresStorage) SetIdempotencyKeyState(ctx context.Context, key string, state string) error {
+	q := fmt.Sprintf("UPDATE %s SET state = $1 WHERE tenant_id = $2 AND key = $3", idempotencyKeysTable)
+
+	_, err := p.pool.Exec(ctx, q, state, tenantOf(ctx), key)
+	return err
+}
+
+//GetIdempotencyRecipients returns link ids reached by the fire with key
+func (p *PostgresStorage) GetIdempotencyRecipients(ctx context.Context, key string) ([]uint64, error) {
+	q := fmt.Sprintf("SELECT link_id FROM %s WHERE tenant_id = $1 AND key = $2", idempotencyRecipientsTbl)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	var linkIDs []uint64
+	rows, err := c.Query(ctx, q, tenantOf(ctx), key)
+	if err != nil {
+		return nil, err
+	}
+	if err := pgxscan.ScanAll(&linkIDs, rows); err != nil {
+		return nil, err
+	}
+	return linkIDs, nil
+}
+
+func (p *PostgresStorage) AddIdempotencyRecipient(ctx context.Context, key string, linkID uint64) error {
+	q := fmt.Sprintf("INSERT INTO %s (tenant_id, key, link_id) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING", idempotencyRecipientsTbl)
+
+	_, err := p.pool.Exec(ctx, q, tenantOf(ctx), key, linkID)
+	return err
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+//UpdateSubscriberPhone changes phone number of the subscriber. Subscriptions and telegram links refer to subscriber id,
+//so they're kept
+func (p *PostgresStorage) UpdateSubscriberPhone(ctx context.Context, subscriberID uint64, phoneNumber string) (bool, error) {
+	q := fmt.Sprintf("UPDATE %s SET phone_number = $2 WHERE tenant_id = $3 AND subscriber_id = $1", subscribersTable)
+
+	tag, err := p.pool.Exec(ctx, q, subscriberID, phoneNumber, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) SetSubscriberActive(ctx context.Context, subscriberID uint64, active bool) (bool, error) {
+	q := fmt.Sprintf(
+		`UPDATE %s SET active = $2, deactivated_at = CASE WHEN $2 THEN NULL ELSE COALESCE(deactivated_at, now()) END
+				WHERE tenant_id = $3 AND subscriber_id = $1`,
+		subscribersTable)
+
+	tag, err := p.pool.Exec(ctx, q, subscriberID, active, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+//EraseSubscriber removes personal data of the subscriber in one transaction: the subscriber with subscriptions,
+//memberships, mutes and link codes (see ON DELETE CASCADE), telegram links, acknowledgements and audited changes
+//made from private chats. Deliveries are kept without subscriber, chat and error, so stats stay the same
+func (p *PostgresStorage) EraseSubscriber(ctx context.Context, subscriberID uint64) (bool, error) {
+	anonymizeQ := fmt.Sprintf(
+		"UPDATE %s SET subscriber_id = NULL, telegram_id = NULL, error = '' WHERE tenant_id = $2 AND subscriber_id = $1",
+		deliveriesTable)
+	acknowledgementsQ := fmt.Sprintf(
+		`UPDATE %s SET acknowledged_by = NULL WHERE tenant_id = $2 AND acknowledged_by IN (
+					SELECT tgsub.telegram_id FROM %s tgsub
+					WHERE tgsub.tenant_id = $2 AND tgsub.subscriber_id = $1 AND tgsub.chat_type = 'private')`,
+		escalationsTable, telegramSubscribersTable)
+	//Audit log is append-only, see erase_audit_actors
+	auditQ := fmt.Sprintf(
+		`SELECT erase_audit_actors(ARRAY(
+					SELECT tgsub.telegram_id FROM %s tgsub
+					WHERE tgsub.tenant_id = $2 AND tgsub.subscriber_id = $1 AND tgsub.chat_type = 'private'))`,
+		telegramSubscribersTable)
+	linksQ := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $2 AND subscriber_id = $1", telegramSubscribersTable)
+	subscriberQ := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $2 AND subscriber_id = $1", subscribersTable)
+	tenantID := tenantOf(ctx)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer c.Release()
+
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, q := range []string{anonymizeQ, acknowledgementsQ, auditQ, linksQ} {
+		if _, err := tx.Exec(ctx, q, subscriberID, tenantID); err != nil {
+			return false, err
+		}
+	}
+	tag, err := tx.Exec(ctx, subscriberQ, subscriberID, tenantID)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	return true, tx.Commit(ctx)
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+)
+
+func (p *PostgresStorage) RegisterTelegramChat(ctx context.Context, botName string, chatID int64, chatType string, threadID *int, subscriberID uint64) (bool, error) {
+	q := fmt.Sprintf(
+		"INSERT INTO %s (telegram_id, subscriber_id, chat_type, thread_id, bot_name, tenant_id) VALUES ($1,$2,$3,$4,$5,$6) ON CONFLICT DO NOTHING",
+		telegramSubscribersTable)
+
+	tag, err := p.pool.Exec(ctx, q, chatID, subscriberID, chatType, threadID, botName, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+//MigrateTelegramChat moves link of group to supergroup it was upgraded to
+func (p *PostgresStorage) MigrateTelegramChat(ctx context.Context, botName string, fromChatID int64, toChatID int64) (bool, error) {
+	q := fmt.Sprintf(
+		"UPDATE %s SET telegram_id = $1, chat_type = 'supergroup' WHERE tenant_id = $4 AND telegram_id = $2 AND bot_name = $3",
+		telegramSubscribersTable)
+
+	tag, err := p.pool.Exec(ctx, q, toChatID, fromChatID, botName, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) CreateLinkCode(ctx context.Context, code string, subscriberID uint64, threadID *int, ttlMinutes int) (*entity.LinkCode, error) {
+	var lc entity.LinkCode
+	q := fmt.Sprintf(
+		`INSERT INTO %s (code, subscriber_id, thread_id, expires_at, tenant_id) VALUES ($1,$2,$3, now() + make_interval(mins => $4), $5)
+				RETURNING *`,
+		linkCodesTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, code, subscriberID, threadID, ttlMinutes, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanOne(&lc, rows)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	return &lc, nil
+}
+
+//ConsumeLinkCode deletes code and returns it, if it's not expired. Code can be used only once
+func (p *PostgresStorage) ConsumeLinkCode(ctx context.Context, code string) (*entity.LinkCode, error) {
+	var lc entity.LinkCode
+	q := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $2 AND code = $1 AND expires_at > now() RETURNING *", linkCodesTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, code, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanOne(&lc, rows)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &lc, nil
+}
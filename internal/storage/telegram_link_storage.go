@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/response_object"
+)
+
+//telegramLinkColumns are columns of entity.TelegramSubscriber, telegram_subscribers is aliased as tgsub
+const telegramLinkColumns = `tgsub.link_id, tgsub.subscriber_id, tgsub.telegram_id, tgsub.chat_type,
+				tgsub.bot_name, COALESCE(tgsub.thread_id, 0) AS thread_id, tgsub.enabled, tgsub.active,
+				COALESCE(tgsub.inactive_reason, '') AS inactive_reason, tgsub.inactive_since, tgsub.created_at`
+
+func (p *PostgresStorage) GetTelegramLinksByPhone(ctx context.Context, phoneNumber string) ([]*response_object.TelegramLinkRO, error) {
+	q := fmt.Sprintf(
+		`SELECT %s, sub.phone_number FROM %s tgsub
+				JOIN %s sub ON tgsub.tenant_id = sub.tenant_id AND tgsub.subscriber_id = sub.subscriber_id
+				WHERE tgsub.tenant_id = $2 AND sub.phone_number = $1 ORDER BY tgsub.link_id ASC`,
+		telegramLinkColumns, telegramSubscribersTable, subscribersTable)
+
+	return p.getTelegramLinks(ctx, q, phoneNumber, tenantOf(ctx))
+}
+
+//GetTelegramLinksByTelegramID returns links of telegram chat made with the bot, the oldest first
+func (p *PostgresStorage) GetTelegramLinksByTelegramID(ctx context.Context, botName string, telegramID int64) ([]*response_object.TelegramLinkRO, error) {
+	q := fmt.Sprintf(
+		`SELECT %s, sub.phone_number FROM %s tgsub
+				JOIN %s sub ON tgsub.tenant_id = sub.tenant_id AND tgsub.subscriber_id = sub.subscriber_id
+				WHERE tgsub.tenant_id = $3 AND tgsub.telegram_id = $1 AND tgsub.bot_name = $2 ORDER BY tgsub.link_id ASC`,
+		telegramLinkColumns, telegramSubscribersTable, subscribersTable)
+
+	return p.getTelegramLinks(ctx, q, telegramID, botName, tenantOf(ctx))
+}
+
+func (p *PostgresStorage) getTelegramLinks(ctx context.Context, q string, args ...interface{}) ([]*response_object.TelegramLinkRO, error) {
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*response_object.TelegramLinkRO
+
+	err = pgxscan.ScanAll(&links, rows)
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (p *PostgresStorage) GetTelegramLink(ctx context.Context, linkID uint64) (*response_object.TelegramLinkRO, error) {
+	var link response_object.TelegramLinkRO
+	q := fmt.Sprintf(
+		`SELECT %s, sub.phone_number FROM %s tgsub
+				JOIN %s sub ON tgsub.tenant_id = sub.tenant_id AND tgsub.subscriber_id = sub.subscriber_id
+				WHERE tgsub.tenant_id = $2 AND tgsub.link_id = $1`,
+		telegramLinkColumns, telegramSubscribersTable, subscribersTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, linkID, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanOne(&link, rows)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (p *PostgresStorage) SetTelegramLinkEnabled(ctx context.Context, linkID uint64, enabled bool) (bool, error) {
+	q := fmt.Sprintf("UPDATE %s SET enabled = $1 WHERE tenant_id = $3 AND link_id = $2", telegramSubscribersTable)
+
+	tag, err := p.pool.Exec(ctx, q, enabled, linkID, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) DeleteTelegramLink(ctx context.Context, linkID uint64) (bool, error) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $2 AND link_id = $1", telegramSubscribersTable)
+
+	tag, err := p.pool.Exec(ctx, q, linkID, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+//DeactivateTelegramChat marks every link of the chat inactive, so nothing is sent there until it's reactivated
+func (p *PostgresStorage) DeactivateTelegramChat(ctx context.Context, botName string, telegramID int64, reason string) (bool, error) {
+	q := fmt.Sprintf(
+		`UPDATE %s SET active = false, inactive_reason = $1, inactive_since = now()
+				WHERE tenant_id = $4 AND telegram_id = $2 AND bot_name = $3 AND active`,
+		telegramSubscribersTable)
+
+	tag, err := p.pool.Exec(ctx, q, reason, telegramID, botName, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+func (p *PostgresStorage) ReactivateTelegramChat(ctx context.Context, botName string, telegramID int64) (bool, error) {
+	q := fmt.Sprintf(
+		`UPDATE %s SET active = true, inactive_reason = NULL, inactive_since = NULL
+				WHERE tenant_id = $3 AND telegram_id = $1 AND bot_name = $2 AND active = false`,
+		telegramSubscribersTable)
+
+	tag, err := p.pool.Exec(ctx, q, telegramID, botName, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+)
+
+//GetTemplate returns template override of the tenant, nil if the tenant uses one from templates.json
+func (p *PostgresStorage) GetTemplate(ctx context.Context, eventID uint64) (*entity.Template, error) {
+	var t entity.Template
+	q := fmt.Sprintf("SELECT event_id, text FROM %s WHERE tenant_id = $2 AND event_id = $1", templatesTable)
+
+	c, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, eventID, tenantOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanOne(&t, rows)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (p *PostgresStorage) SetTemplate(ctx context.Context, eventID uint64, text string) error {
+	q := fmt.Sprintf(
+		`INSERT INTO %s (event_id, text, tenant_id) VALUES ($1,$2,$3)
+				ON CONFLICT (tenant_id, event_id) DO UPDATE SET text = EXCLUDED.text, updated_at = now()`,
+		templatesTable)
+
+	_, err := p.pool.Exec(ctx, q, eventID, text, tenantOf(ctx))
+	return err
+}
+
+func (p *PostgresStorage) DeleteTemplate(ctx context.Context, eventID uint64) (bool, error) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $2 AND event_id = $1", templatesTable)
+
+	tag, err := p.pool.Exec(ctx, q, eventID, tenantOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+)
+
+//tenantPool refuses to query tenant data without tenant in ctx.
+//Rows of the tenant are picked by row level security policies of the session (see postgres.New)
+//and by tenant_id conditions of the queries (see tenantOf)
+type tenantPool struct {
+	pool *pgxpool.Pool
+}
+
+func (t *tenantPool) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	if _, ok := tenancy.FromContext(ctx); ok != true {
+		return nil, tenancy.ErrNoTenant
+	}
+	return t.pool.Acquire(ctx)
+}
+
+func (t *tenantPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if _, ok := tenancy.FromContext(ctx); ok != true {
+		return nil, tenancy.ErrNoTenant
+	}
+	tag, err := t.pool.Exec(ctx, sql, args...)
+	return tag, pgError(err)
+}
+
+func (t *tenantPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if _, ok := tenancy.FromContext(ctx); ok != true {
+		return errRow{err: tenancy.ErrNoTenant}
+	}
+	return t.pool.QueryRow(ctx, sql, args...)
+}
+
+//tenantOf returns tenant of ctx for tenant_id conditions of queries. Those keep rows of other tenants out
+//even if row level security doesn't apply to the session. Tenant is 0 without tenant in ctx, which matches no rows
+func tenantOf(ctx context.Context) uint64 {
+	tenantID, _ := tenancy.FromContext(ctx)
+	return tenantID
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+)
+
+func (p *PostgresStorage) CreateTenant(ctx context.Context, name string) (uint64, error) {
+	var tenantID uint64
+	q := fmt.Sprintf("INSERT INTO %s (name) VALUES ($1) ON CONFLICT DO NOTHING RETURNING tenant_id", tenantsTable)
+
+	err := p.sharedPool.QueryRow(ctx, q, name).Scan(&tenantID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return tenantID, nil
+}
+
+func (p *PostgresStorage) GetTenants(ctx context.Context) ([]*entity.Tenant, error) {
+	var tenants []*entity.Tenant
+	q := fmt.Sprintf("SELECT * FROM %s ORDER BY tenant_id ASC", tenantsTable)
+
+	c, err := p.sharedPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&tenants, rows)
+	if err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+func (p *PostgresStorage) GetTenant(ctx context.Context, tenantID uint64) (*entity.Tenant, error) {
+	q := fmt.Sprintf("SELECT * FROM %s WHERE tenant_id = $1", tenantsTable)
+	return p.getTenant(ctx, q, tenantID)
+}
+
+func (p *PostgresStorage) GetTenantByName(ctx context.Context, name string) (*entity.Tenant, error) {
+	q := fmt.Sprintf("SELECT * FROM %s WHERE name = $1", tenantsTable)
+	return p.getTenant(ctx, q, name)
+}
+
+func (p *PostgresStorage) getTenant(ctx context.Context, q string, arg interface{}) (*entity.Tenant, error) {
+	var t entity.Tenant
+
+	c, err := p.sharedPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanOne(&t, rows)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+const apiKeyColumns = "api_key_id, tenant_id, name, scopes, last_used_at, revoked_at, created_at"
+
+func (p *PostgresStorage) CreateAPIKey(ctx context.Context, tenantID uint64, name string, keyHash string, scopes []string) (*entity.APIKey, error) {
+	var key entity.APIKey
+	q := fmt.Sprintf(
+		"INSERT INTO %s (tenant_id, name, key_hash, scopes) VALUES ($1,$2,$3,$4) RETURNING %s",
+		apiKeysTable, apiKeyColumns)
+
+	c, err := p.sharedPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, tenantID, name, keyHash, scopes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanOne(&key, rows)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+//GetAPIKeyByHash returns not revoked key with keyHash
+func (p *PostgresStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	var key entity.APIKey
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE key_hash = $1 AND revoked_at IS NULL", apiKeyColumns, apiKeysTable)
+
+	c, err := p.sharedPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanOne(&key, rows)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (p *PostgresStorage) GetAPIKeys(ctx context.Context, tenantID uint64) ([]*entity.APIKey, error) {
+	var keys []*entity.APIKey
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE tenant_id = $1 ORDER BY api_key_id ASC", apiKeyColumns, apiKeysTable)
+
+	c, err := p.sharedPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanAll(&keys, rows)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+//RevokeAPIKey returns revoked key, nil if there's no such not revoked key
+func (p *PostgresStorage) RevokeAPIKey(ctx context.Context, tenantID uint64, apiKeyID uint64) (*entity.APIKey, error) {
+	var key entity.APIKey
+	q := fmt.Sprintf(
+		"UPDATE %s SET revoked_at = now() WHERE api_key_id = $1 AND tenant_id = $2 AND revoked_at IS NULL RETURNING %s",
+		apiKeysTable, apiKeyColumns)
+
+	c, err := p.sharedPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+
+	rows, err := c.Query(ctx, q, apiKeyID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	err = pgxscan.ScanOne(&key, rows)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+//TouchAPIKey records usage of the key. It's written at most once a minute, so busy keys don't cost a write per request
+func (p *PostgresStorage) TouchAPIKey(ctx context.Context, apiKeyID uint64) error {
+	q := fmt.Sprintf(
+		`UPDATE %s SET last_used_at = now()
+				WHERE api_key_id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`,
+		apiKeysTable)
+
+	_, err := p.sharedPool.Exec(ctx, q, apiKeyID)
+	return err
+}
@@ -1,5 +1,10 @@
 package dto
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type SubscribeToEventInp struct {
 	PhoneNumber string `json:"phone_number" validate:"required"`
 	EventName   string `json:"event_name" validate:"required"`
@@ -8,3 +13,60 @@ type SubscribeToEventInp struct {
 type RegisterSubscriberDto struct {
 	PhoneNumber string `json:"phone_number" validate:"required"`
 }
+
+const (
+	RecipientsReplace   = "replace"
+	RecipientsIntersect = "intersect"
+)
+
+//FireRecipients narrows down fire to explicit recipients. Comes within fire payload under "recipients" key
+type FireRecipients struct {
+	PhoneNumbers  []string `json:"phone_numbers"`
+	SubscriberIDs []uint64 `json:"subscriber_ids"`
+	Groups        []string `json:"groups"`
+	//Mode is either replace (default) or intersect with event subscribers
+	Mode string `json:"mode"`
+}
+
+type FireTargetInp struct {
+	Recipients *FireRecipients `json:"recipients"`
+}
+
+type FireBatchItemInp struct {
+	EventName      string          `json:"event_name"`
+	Payload        json.RawMessage `json:"payload"`
+	IdempotencyKey string          `json:"idempotency_key"`
+}
+
+//MuteInp mutes every event or the one with EventName. Either Duration (e.g. "2h", "until tomorrow") or Until is required
+type MuteInp struct {
+	PhoneNumber string     `json:"phone_number" validate:"required"`
+	EventName   string     `json:"event_name"`
+	Duration    string     `json:"duration"`
+	Until       *time.Time `json:"until"`
+}
+
+type UnmuteInp struct {
+	PhoneNumber string `json:"phone_number" validate:"required"`
+	EventName   string `json:"event_name"`
+}
+
+//IssueLinkCodeInp issues code to link group chat with subscriber. ThreadID is forum topic notifications are sent to
+type IssueLinkCodeInp struct {
+	PhoneNumber string `json:"phone_number" validate:"required"`
+	ThreadID    *int   `json:"thread_id"`
+}
+
+type SetTelegramLinkInp struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
+
+type SetTemplateInp struct {
+	Text string `json:"text" validate:"required"`
+}
+
+//UpdateSubscriberInp changes phone number and/or deactivates subscriber. Absent fields stay the same
+type UpdateSubscriberInp struct {
+	PhoneNumber *string `json:"phone_number"`
+	Active      *bool   `json:"active"`
+}
@@ -0,0 +1,149 @@
+package subscription
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/response_object"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/response"
+)
+
+const (
+	maxImportBytes = 5 << 20
+	maxImportRows  = 10000
+)
+
+func (s *subscriptionTransport) ExportSubscribers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s.export(w, r, "subscribers", response_object.SubscriberExportHeader, func(write func(response.Row) error) error {
+		return s.subscriptionService.ExportSubscribers(r.Context(), func(row *response_object.SubscriberExportRO) error {
+			return write(row)
+		})
+	})
+}
+
+func (s *subscriptionTransport) ExportSubscriptions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s.export(w, r, "subscriptions", response_object.SubscriptionExportHeader, func(write func(response.Row) error) error {
+		return s.subscriptionService.ExportSubscriptions(r.Context(), func(row *response_object.SubscriptionExportRO) error {
+			return write(row)
+		})
+	})
+}
+
+func (s *subscriptionTransport) ExportTelegramLinks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s.export(w, r, "telegram_links", response_object.TelegramLinkExportHeader, func(write func(response.Row) error) error {
+		return s.subscriptionService.ExportTelegramLinks(r.Context(), func(row *response_object.TelegramLinkRO) error {
+			return write(row)
+		})
+	})
+}
+
+//export streams rows produced by each in format of ?format=csv|ndjson, csv by default
+func (s *subscriptionTransport) export(w http.ResponseWriter, r *http.Request, name string, header []string,
+	each func(write func(response.Row) error) error) {
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = response.FormatCSV
+	}
+	if response.IsStreamFormat(format) != true {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidExportFormat)
+		logging.FromContext(r.Context(), s.logger).Debug(http_errors.ErrInvalidExportFormat.Error())
+		return
+	}
+
+	stream, err := response.NewStream(w, format, name, header)
+	if err != nil {
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+	//Status is already sent, so failed export is only cut short
+	if err := each(stream.Write); err != nil {
+		logging.FromContext(r.Context(), s.logger).Errorf("export of %s is cut short. %s", name, err.Error())
+	}
+	if err := stream.Flush(); err != nil {
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+	}
+}
+
+//ImportSubscriptions subscribes phones to events from csv of phone,event_name rows. Header row is optional.
+//?dry_run=true reports what would be done without saving anything. Import with invalid rows is rejected as a whole
+func (s *subscriptionTransport) ImportSubscriptions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	rows, err := readImportRows(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Debug(err.Error())
+		return
+	}
+
+	result, err := s.subscriptionService.ImportSubscriptions(r.Context(), rows, dryRun)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	code := http.StatusOK
+	if dryRun != true && result.Invalid > 0 {
+		code = http.StatusUnprocessableEntity
+	}
+	response.Json(s.logger, w, code, response.JSON{
+		"import": result,
+	})
+}
+
+//readImportRows reads csv of phone,event_name rows. Records of wrong length are invalid rows rather than error,
+//so every mistake is reported at once
+func readImportRows(body io.Reader) ([]*response_object.ImportRowRO, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []*response_object.ImportRowRO
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, http_errors.NewErrInvalidImport(err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		//Header is the first row only
+		if len(rows) == 0 && line == 1 && isImportHeader(record) {
+			continue
+		}
+		if len(rows) == maxImportRows {
+			return nil, http_errors.NewErrInvalidImport(errors.Errorf("more than %d rows", maxImportRows))
+		}
+
+		row := &response_object.ImportRowRO{Row: line}
+		if len(record) != 2 {
+			row.Status, row.Error = response_object.ImportInvalid, "row must be phone,event_name"
+			rows = append(rows, row)
+			continue
+		}
+		row.PhoneNumber, row.EventName = strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, http_errors.NewErrInvalidImport(errors.New("no rows"))
+	}
+	return rows, nil
+}
+
+func isImportHeader(record []string) bool {
+	first := strings.ToLower(strings.TrimSpace(record[0]))
+	return first == "phone" || first == "phone_number"
+}
@@ -0,0 +1,292 @@
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/delivery-service/pkg/binder"
+	"github.com/sonyamoonglade/delivery-service/pkg/validation"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/events/payload"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/dto"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/response_object"
+	"github.com/sonyamoonglade/notification-service/pkg/alert"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/requestid"
+	"github.com/sonyamoonglade/notification-service/pkg/telegram_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+	"github.com/sonyamoonglade/notification-service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//fireOutcome is what the fire has reached
+type fireOutcome struct {
+	//EscalationID is set if the event has escalation policy
+	EscalationID uint64
+	//Delivered is number of recipients telegram has accepted the message for
+	Delivered int
+	//Failed is number of recipients telegram has refused the message for, unreachable chats aside
+	Failed int
+}
+
+//fire is the pipeline shared by single and batch fire: resolves recipients, formats template and notifies in telegram.
+//Recipients reached by the fire with idempotencyKey are remembered and skipped on retry.
+//Failed recipient doesn't stop the fire: ErrPartialDelivery is returned if others are notified, error of the last failed send if nobody is.
+//ErrNoSubscriptions and ErrNoTelegramSubscribers are returned when there's nobody to notify
+func (s *subscriptionTransport) fire(ctx context.Context, eventID uint64, body []byte, idempotencyKey string) (out fireOutcome, err error) {
+
+	rec := newFireRecord(ctx, eventID)
+	//Every fire is recorded, whatever it ends with
+	defer func() {
+		rec.fire.Status = fireResult(err)
+		if err := s.subscriptionService.RecordFire(ctx, &rec.fire, rec.deliveries); err != nil {
+			logging.FromContext(ctx, s.logger).Errorf("could not record fire of event %d. %s", eventID, err.Error())
+		}
+	}()
+
+	var target dto.FireTargetInp
+	if err := json.Unmarshal(body, &target); err != nil {
+		return out, http_errors.NewErrInvalidPayload(err)
+	}
+
+	var subscribers []*entity.Subscriber
+	//Explicit recipients either replace or intersect with event subscribers
+	if target.Recipients != nil {
+		subscribers, err = s.subscriptionService.ResolveRecipients(ctx, eventID, target.Recipients)
+	} else {
+		subscribers, err = s.subscriptionService.GetEventSubscribers(ctx, eventID)
+	}
+	if err != nil {
+		return out, err
+	}
+	//No actual subscribers whatsoever, so the rest of the code is a waste
+	if len(subscribers) == 0 {
+		return out, http_errors.ErrNoSubscriptions
+	}
+
+	subscribers, err = s.subscriptionService.SuppressMuted(ctx, eventID, subscribers)
+	if err != nil {
+		return out, err
+	}
+	//Everyone has muted the event
+	if len(subscribers) == 0 {
+		return out, http_errors.ErrNoSubscriptions
+	}
+
+	//Event is sent by the bot it's assigned to, so only links made with this bot are reachable
+	tenantID, _ := tenancy.FromContext(ctx)
+	eventBot := s.bots.ForEvent(tenantID, eventID)
+
+	subsPhones := s.subscriptionService.SelectPhones(subscribers)
+	telegramSubs, err := s.subscriptionService.GetTelegramSubscribers(ctx, eventBot.Name(), subsPhones)
+	if err != nil {
+		return out, err
+	}
+
+	//No actual subscribers in telegram, so the rest of the code is a waste
+	if len(telegramSubs) == 0 {
+		return out, http_errors.ErrNoTelegramSubscribers
+	}
+	if idempotencyKey != "" {
+		telegramSubs, err = s.subscriptionService.SkipFiredRecipients(ctx, idempotencyKey, telegramSubs)
+		if err != nil {
+			return out, err
+		}
+		//Previous attempts have reached everyone
+		if len(telegramSubs) == 0 {
+			return out, nil
+		}
+	}
+	rec.fire.Recipients = len(telegramSubs)
+
+	renderCtx, span := tracing.Start(ctx, "template render", trace.WithAttributes(attribute.Int64("event.id", int64(eventID))))
+	fmtTmpl, err := s.formatPayload(renderCtx, eventID, body)
+	tracing.End(span, err)
+	if err != nil {
+		//Invalid payload is a mistake of producer, missing template is ours
+		if http_errors.StatusOf(err) >= http.StatusInternalServerError {
+			s.alerter.Alert(alert.KindTemplate, err)
+		}
+		return out, err
+	}
+
+	//Critical events must be acknowledged by someone, otherwise they're escalated (see escalations.json)
+	if _, ok := s.escalationService.GetPolicy(ctx, eventID); ok {
+		out.EscalationID, err = s.escalationService.Start(ctx, eventID, fmtTmpl)
+		if err != nil {
+			return out, err
+		}
+	}
+
+	var sendErr error
+	//Range over subscriber's associated telegram id's
+	for _, sub := range telegramSubs {
+		//Notify subscribers in telegram here with fmtTmpl text
+		if out.EscalationID != 0 {
+			err = eventBot.NotifyWithKeyboard(ctx, sub.TelegramID, sub.ThreadID, fmtTmpl, eventBot.AckKeyboard(out.EscalationID))
+		} else {
+			err = eventBot.Notify(ctx, sub.TelegramID, sub.ThreadID, fmtTmpl)
+		}
+		rec.add(sub, err)
+		if err == nil {
+			out.Delivered++
+			if idempotencyKey != "" {
+				if err := s.subscriptionService.RecordFiredRecipient(ctx, idempotencyKey, sub.LinkID); err != nil {
+					logging.FromContext(ctx, s.logger).Errorf("could not record recipient of fire %s. %s", idempotencyKey, err.Error())
+				}
+			}
+			continue
+		}
+		//User has blocked the bot or deleted account. Skip the chat from now on instead of failing whole broadcast
+		if errors.Is(err, telegram_errors.ErrChatUnreachable) {
+			logging.FromContext(ctx, s.logger).Warnf("telegram chat %d is unreachable: %s", sub.TelegramID, err.Error())
+			if err := s.subscriptionService.DeactivateTelegramChat(ctx, eventBot.Name(), sub.TelegramID, telegram_errors.Reason(err)); err != nil {
+				logging.FromContext(ctx, s.logger).Error(err.Error())
+			}
+			continue
+		}
+		//Admins are alerted by the bot (see bot.SetAlerter). The rest are notified anyway
+		logging.FromContext(ctx, s.logger).Errorf("could not notify telegram chat %d. %s", sub.TelegramID, err.Error())
+		out.Failed++
+		sendErr = err
+	}
+
+	//Nobody has the keyboard to acknowledge the escalation with
+	if out.EscalationID != 0 && out.Delivered == 0 {
+		if err := s.escalationService.Cancel(ctx, out.EscalationID); err != nil {
+			logging.FromContext(ctx, s.logger).Errorf("could not cancel escalation %d. %s", out.EscalationID, err.Error())
+		}
+		out.EscalationID = 0
+	}
+
+	switch true {
+	case out.Failed == 0:
+		return out, nil
+	case out.Delivered == 0:
+		return out, sendErr
+	default:
+		return out, http_errors.ErrPartialDelivery
+	}
+}
+
+//fireRecord collects outcome of a fire for stats
+type fireRecord struct {
+	fire       entity.Fire
+	deliveries []*entity.Delivery
+	start      time.Time
+}
+
+func newFireRecord(ctx context.Context, eventID uint64) *fireRecord {
+	return &fireRecord{
+		fire:  entity.Fire{EventID: eventID, RequestID: requestid.FromContext(ctx)},
+		start: time.Now(),
+	}
+}
+
+//add records delivery to sub. Latency is time from the start of the fire to telegram accepting the message
+func (r *fireRecord) add(sub *entity.TelegramSubscriber, err error) {
+	subscriberID, telegramID := sub.SubscriberID, sub.TelegramID
+	d := &entity.Delivery{
+		EventID:      r.fire.EventID,
+		SubscriberID: &subscriberID,
+		Channel:      entity.ChannelTelegram,
+		TelegramID:   &telegramID,
+		Status:       entity.DeliverySent,
+	}
+	switch true {
+	case err == nil:
+		latency := time.Since(r.start).Milliseconds()
+		d.LatencyMs = &latency
+	case errors.Is(err, telegram_errors.ErrChatUnreachable):
+		d.Status = entity.DeliveryUnreachable
+		d.Error = err.Error()
+	default:
+		d.Status = entity.DeliveryFailed
+		d.Error = err.Error()
+	}
+	r.deliveries = append(r.deliveries, d)
+}
+
+//fireResult maps error of fire to result of fire batch item, so single and batch fires are counted alike
+func fireResult(err error) string {
+	switch true {
+	case err == nil, errors.Is(err, http_errors.ErrNoSubscriptions), errors.Is(err, http_errors.ErrNoTelegramSubscribers):
+		return response_object.FireAccepted
+	case errors.Is(err, http_errors.ErrPartialDelivery):
+		return response_object.FirePartial
+	case http_errors.StatusOf(err) < http.StatusInternalServerError:
+		return response_object.FireInvalid
+	default:
+		return response_object.FireFailed
+	}
+}
+
+//formatPayload binds body into payload type of the event and formats the template of the event with it.
+//Binding and validation errors are ErrInvalidPayload
+func (s *subscriptionTransport) formatPayload(ctx context.Context, eventID uint64, body []byte) (string, error) {
+	tmpl, err := s.eventsService.GetTemplate(ctx, eventID)
+	if err != nil {
+		return "", err
+	}
+	//tmpl will be formatted into fmtTmpl passing ...args in the switch-case below
+	var fmtTmpl string
+
+	//Find payload type assigned to eventID
+	payloadType := payload.GetProvider().MustGetType(eventID)
+
+	//Iterate over payload types, assigned to events in payload package
+	//Format the template, assigned to event in templates.json
+	//Keep in mind, formatter.Format func is Variadic
+	switch payloadType {
+	case reflect.TypeOf(payload.WorkerLoginPayload{}):
+		var p payload.WorkerLoginPayload
+		err := binder.Bind(bytes.NewReader(body), &p)
+		if err != nil {
+			return "", http_errors.NewErrInvalidPayload(err)
+		}
+		fmtTmpl = s.formatter.Format(
+			tmpl,
+			p.Username,
+			s.formatter.FormatTime(p.LoginAt, p.TimeOffset))
+
+		break
+	case reflect.TypeOf(payload.UserOrderCreatePayload{}):
+		var p payload.UserOrderCreatePayload
+		err := binder.Bind(bytes.NewReader(body), &p)
+		if err != nil {
+			return "", http_errors.NewErrInvalidPayload(err)
+		}
+		fmtTmpl = s.formatter.Format(tmpl,
+			p.OrderID,
+			p.Amount)
+
+		break
+	case reflect.TypeOf(payload.MasterOrderCreatePayload{}):
+		var p payload.MasterOrderCreatePayload
+		err := binder.Bind(bytes.NewReader(body), &p)
+		if err != nil {
+			return "", http_errors.NewErrInvalidPayload(err)
+		}
+
+		ok := validation.ValidatePhoneNumber(p.PhoneNumber)
+		if ok != true {
+			return "", http_errors.ErrInvalidPayload
+		}
+		fmtTmpl = s.formatter.Format(tmpl,
+			p.OrderID,
+			p.Username,
+			p.PhoneNumber,
+			p.Amount)
+
+		break
+	}
+
+	return fmtTmpl, nil
+}
@@ -0,0 +1,122 @@
+package subscription_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/internal/subscription"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+//idempotencyStorage keeps idempotency keys the way fire_idempotency_keys and fire_idempotency_recipients do
+type idempotencyStorage struct {
+	storage.DBStorage
+	states     map[string]string
+	recipients map[string][]uint64
+}
+
+func newIdempotencyStorage() *idempotencyStorage {
+	return &idempotencyStorage{states: map[string]string{}, recipients: map[string][]uint64{}}
+}
+
+func (f *idempotencyStorage) ClaimIdempotencyKey(_ context.Context, key string, _ uint64) (bool, error) {
+	state, ok := f.states[key]
+	if ok && state != entity.IdempotencyPartial {
+		return false, nil
+	}
+	f.states[key] = entity.IdempotencyPending
+	return true, nil
+}
+
+func (f *idempotencyStorage) ReleaseIdempotencyKey(_ context.Context, key string) error {
+	delete(f.states, key)
+	delete(f.recipients, key)
+	return nil
+}
+
+func (f *idempotencyStorage) SetIdempotencyKeyState(_ context.Context, key string, state string) error {
+	f.states[key] = state
+	return nil
+}
+
+func (f *idempotencyStorage) GetIdempotencyRecipients(_ context.Context, key string) ([]uint64, error) {
+	return f.recipients[key], nil
+}
+
+func (f *idempotencyStorage) AddIdempotencyRecipient(_ context.Context, key string, linkID uint64) error {
+	f.recipients[key] = append(f.recipients[key], linkID)
+	return nil
+}
+
+func linkIDs(subs []*entity.TelegramSubscriber) []uint64 {
+	ids := make([]uint64, 0, len(subs))
+	for _, sub := range subs {
+		ids = append(ids, sub.LinkID)
+	}
+	return ids
+}
+
+func TestRetryOfPartiallyFailedFire(t *testing.T) {
+	ctx := context.Background()
+	db := newIdempotencyStorage()
+	service := subscription.NewSubscriptionService(zap.NewNop().Sugar(), db, &fakeAuditService{})
+
+	const key = "order-42"
+	subs := []*entity.TelegramSubscriber{{LinkID: 1}, {LinkID: 2}, {LinkID: 3}}
+	sendErr := errors.New("telegram: Internal Server Error")
+
+	require.NoError(t, service.ClaimFire(ctx, key, 1))
+	//Fire in progress isn't claimed twice
+	assert.ErrorIs(t, service.ClaimFire(ctx, key, 1), http_errors.ErrDuplicateFire)
+
+	//First attempt reaches the first recipient only
+	rest, err := service.SkipFiredRecipients(ctx, key, subs)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, linkIDs(rest))
+	require.NoError(t, service.RecordFiredRecipient(ctx, key, 1))
+	require.NoError(t, service.SettleFire(ctx, key, 1, sendErr))
+	assert.Equal(t, entity.IdempotencyPartial, db.states[key])
+
+	//Retry reaches nobody, but the key is kept for the first recipient's sake
+	require.NoError(t, service.ClaimFire(ctx, key, 1))
+	rest, err = service.SkipFiredRecipients(ctx, key, subs)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2, 3}, linkIDs(rest))
+	require.NoError(t, service.SettleFire(ctx, key, 0, sendErr))
+	assert.Equal(t, entity.IdempotencyPartial, db.states[key])
+
+	//Last retry reaches the rest
+	require.NoError(t, service.ClaimFire(ctx, key, 1))
+	rest, err = service.SkipFiredRecipients(ctx, key, subs)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2, 3}, linkIDs(rest))
+	require.NoError(t, service.RecordFiredRecipient(ctx, key, 2))
+	require.NoError(t, service.RecordFiredRecipient(ctx, key, 3))
+	require.NoError(t, service.SettleFire(ctx, key, 2, nil))
+
+	assert.ErrorIs(t, service.ClaimFire(ctx, key, 1), http_errors.ErrDuplicateFire)
+}
+
+func TestFireReachingNobodyIsReleased(t *testing.T) {
+	ctx := context.Background()
+	db := newIdempotencyStorage()
+	service := subscription.NewSubscriptionService(zap.NewNop().Sugar(), db, &fakeAuditService{})
+
+	const key = "order-43"
+	require.NoError(t, service.ClaimFire(ctx, key, 1))
+	require.NoError(t, service.SettleFire(ctx, key, 0, http_errors.ErrInvalidPayload))
+
+	_, ok := db.states[key]
+	assert.False(t, ok)
+	require.NoError(t, service.ClaimFire(ctx, key, 1))
+
+	//Nobody to notify is a finished fire
+	require.NoError(t, service.SettleFire(ctx, key, 0, http_errors.ErrNoSubscriptions))
+	assert.ErrorIs(t, service.ClaimFire(ctx, key, 1), http_errors.ErrDuplicateFire)
+}
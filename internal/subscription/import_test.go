@@ -0,0 +1,117 @@
+package subscription_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/internal/subscription"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/response_object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+//importStorage knows worker_login event and subscriber +79990000001 subscribed to it
+type importStorage struct {
+	storage.DBStorage
+	imported  []*response_object.ImportRowRO
+	committed bool
+}
+
+func (f *importStorage) DoesExist(_ context.Context, eventName string) (uint64, error) {
+	if eventName == "worker_login" {
+		return 3, nil
+	}
+	return 0, nil
+}
+
+func (f *importStorage) ImportSubscriptions(_ context.Context, rows []*response_object.ImportRowRO, commit bool) error {
+	for _, row := range rows {
+		row.Status = response_object.ImportSubscribed
+		row.Registered = true
+		if row.PhoneNumber == "+79990000001" {
+			row.Status, row.Registered = response_object.ImportExists, false
+		}
+	}
+	f.imported, f.committed = rows, commit
+	return nil
+}
+
+func importCSV(t *testing.T, db *importStorage, query string, body string) (int, response_object.ImportRO) {
+	service := subscription.NewSubscriptionService(zap.NewNop().Sugar(), db, &fakeAuditService{})
+	transport := subscription.NewSubscriptionTransport(zap.NewNop().Sugar(), service, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/subscriptions/import"+query, strings.NewReader(body))
+	transport.ImportSubscriptions(w, r, nil)
+
+	var resp struct {
+		Import response_object.ImportRO `json:"import"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return w.Code, resp.Import
+}
+
+func TestImportSubscriptions(t *testing.T) {
+	const valid = "+79990000001,worker_login\n+79990000002, worker_login\n"
+
+	t.Run("header is skipped", func(t *testing.T) {
+		db := &importStorage{}
+		code, result := importCSV(t, db, "", "phone_number,event_name\n"+valid)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.True(t, result.Committed)
+		assert.True(t, db.committed)
+		assert.Equal(t, 1, result.Subscribed)
+		assert.Equal(t, 1, result.Exists)
+		require.Len(t, result.Rows, 2)
+		//Rows are lines of csv, header included
+		assert.Equal(t, 2, result.Rows[0].Row)
+		assert.Equal(t, "worker_login", result.Rows[1].EventName)
+	})
+
+	t.Run("header is optional", func(t *testing.T) {
+		db := &importStorage{}
+		code, result := importCSV(t, db, "", valid)
+
+		assert.Equal(t, http.StatusOK, code)
+		require.Len(t, result.Rows, 2)
+		assert.Equal(t, 1, result.Rows[0].Row)
+	})
+
+	t.Run("dry run saves nothing", func(t *testing.T) {
+		db := &importStorage{}
+		code, result := importCSV(t, db, "?dry_run=true", valid)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.True(t, result.DryRun)
+		assert.False(t, result.Committed)
+		assert.False(t, db.committed)
+		//Statuses are the ones import would end with
+		assert.Equal(t, 1, result.Subscribed)
+		assert.Equal(t, 1, result.Exists)
+	})
+
+	t.Run("invalid rows reject import as a whole", func(t *testing.T) {
+		db := &importStorage{}
+		body := valid + "79990000003,worker_login\n+79990000004,unknown_event\n+79990000005\nphone,event_name\n"
+		code, result := importCSV(t, db, "", body)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, code)
+		assert.False(t, result.Committed)
+		assert.False(t, db.committed)
+		assert.Equal(t, 4, result.Invalid)
+		require.Len(t, result.Rows, 6)
+		for _, row := range result.Rows[2:] {
+			assert.Equal(t, response_object.ImportInvalid, row.Status)
+			assert.NotEmpty(t, row.Error)
+		}
+		//Valid rows still get their statuses
+		assert.Equal(t, response_object.ImportExists, result.Rows[0].Status)
+	})
+}
@@ -0,0 +1,131 @@
+package subscription_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/internal/subscription"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/dto"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var (
+	anna  = &entity.Subscriber{SubscriberID: 1, PhoneNumber: "+79990000001"}
+	boris = &entity.Subscriber{SubscriberID: 2, PhoneNumber: "+79990000002"}
+	vera  = &entity.Subscriber{SubscriberID: 3, PhoneNumber: "+79990000003"}
+)
+
+//groupStorage keeps groups and subscribers of event 1
+type groupStorage struct {
+	storage.DBStorage
+	groups map[string][]*entity.Subscriber
+}
+
+func newGroupStorage() *groupStorage {
+	return &groupStorage{groups: map[string][]*entity.Subscriber{
+		"managers": {anna, boris},
+		"cooks":    {boris, vera},
+	}}
+}
+
+func (f *groupStorage) GetGroupsByNames(_ context.Context, names []string) ([]*entity.Group, error) {
+	var groups []*entity.Group
+	for _, name := range names {
+		if _, ok := f.groups[name]; ok {
+			groups = append(groups, &entity.Group{Name: name})
+		}
+	}
+	return groups, nil
+}
+
+//GetGroupsMembers returns a member of several groups as many times, the way join of group_members does
+func (f *groupStorage) GetGroupsMembers(_ context.Context, names []string) ([]*entity.Subscriber, error) {
+	var members []*entity.Subscriber
+	for _, name := range names {
+		members = append(members, f.groups[name]...)
+	}
+	return members, nil
+}
+
+func (f *groupStorage) GetSubscribersByPhones(_ context.Context, phoneNumbers []string) ([]*entity.Subscriber, error) {
+	var subs []*entity.Subscriber
+	for _, sub := range []*entity.Subscriber{anna, boris, vera} {
+		for _, ph := range phoneNumbers {
+			if sub.PhoneNumber == ph {
+				subs = append(subs, sub)
+			}
+		}
+	}
+	return subs, nil
+}
+
+func (f *groupStorage) GetEventSubscribers(_ context.Context, _ uint64) ([]*entity.Subscriber, error) {
+	return []*entity.Subscriber{anna, vera}, nil
+}
+
+func subscriberIDs(subs []*entity.Subscriber) []uint64 {
+	ids := make([]uint64, 0, len(subs))
+	for _, sub := range subs {
+		ids = append(ids, sub.SubscriberID)
+	}
+	return ids
+}
+
+func TestResolveRecipientsOfGroups(t *testing.T) {
+	service := subscription.NewSubscriptionService(zap.NewNop().Sugar(), newGroupStorage(), &fakeAuditService{})
+
+	tests := []struct {
+		name        string
+		recipients  dto.FireRecipients
+		expectedIDs []uint64
+		expectedErr error
+	}{
+		{
+			name:        "member of several groups is notified once",
+			recipients:  dto.FireRecipients{Groups: []string{"managers", "cooks"}},
+			expectedIDs: []uint64{1, 2, 3},
+		},
+		{
+			name:        "group names are case and space insensitive",
+			recipients:  dto.FireRecipients{Groups: []string{" Managers "}},
+			expectedIDs: []uint64{1, 2},
+		},
+		{
+			name:        "phone of a group member",
+			recipients:  dto.FireRecipients{PhoneNumbers: []string{vera.PhoneNumber}, Groups: []string{"managers"}},
+			expectedIDs: []uint64{3, 1, 2},
+		},
+		{
+			name:        "intersect keeps subscribers of the event",
+			recipients:  dto.FireRecipients{Groups: []string{"managers"}, Mode: dto.RecipientsIntersect},
+			expectedIDs: []uint64{1},
+		},
+		{
+			name:        "unknown group",
+			recipients:  dto.FireRecipients{Groups: []string{"managers", "waiters"}},
+			expectedErr: http_errors.ErrUnknownRecipients,
+		},
+		{
+			name:        "unknown mode",
+			recipients:  dto.FireRecipients{Groups: []string{"managers"}, Mode: "union"},
+			expectedErr: http_errors.ErrInvalidRecipientsMode,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			subs, err := service.ResolveRecipients(context.Background(), 1, &tc.recipients)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedIDs, subscriberIDs(subs))
+		})
+	}
+}
@@ -1,14 +1,175 @@
 package response_object
 
-import "github.com/sonyamoonglade/notification-service/internal/entity"
+import (
+	"strconv"
+	"time"
+
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+)
 
 type SubscriptionRO struct {
 	SubscriptionID uint64       `json:"subscription_id"`
 	Event          entity.Event `json:"event"`
+	MutedUntil     *time.Time   `json:"muted_until,omitempty"`
+}
+
+//SubscriberEventRO is the event subscriber receives. GroupName is set when it's received via group
+type SubscriberEventRO struct {
+	entity.Event
+	GroupName string `json:"group_name,omitempty" db:"group_name"`
+}
+
+//TelegramLinkRO is the link of telegram chat with subscriber's phone number
+type TelegramLinkRO struct {
+	entity.TelegramSubscriber
+	PhoneNumber string `json:"phone_number" db:"phone_number"`
+}
+
+//SubscriberDetailRO is subscriber with everything it's notified through: events, telegram links and active mutes
+type SubscriberDetailRO struct {
+	entity.Subscriber
+	Events        []*SubscriberEventRO `json:"events"`
+	TelegramLinks []*TelegramLinkRO    `json:"telegram_links"`
+	Mutes         []*entity.Mute       `json:"mutes"`
 }
 
+//SubscriberRO is subscriber in listings. HasTelegramSubscription is true when notifications can be delivered
+//to at least one telegram chat, InactiveTelegramLinks counts chats telegram refuses to deliver to
 type SubscriberRO struct {
+	SubscriberID            uint64           `json:"subscriber_id" db:"subscriber_id"`
 	PhoneNumber             string           `json:"phone_number" db:"phone_number"`
 	HasTelegramSubscription bool             `json:"has_telegram_subscription" db:"has_telegram_subscription"`
+	InactiveTelegramLinks   int              `json:"inactive_telegram_links" db:"inactive_telegram_links"`
+	MutedUntil              *time.Time       `json:"muted_until,omitempty" db:"muted_until"`
 	Subscriptions           []SubscriptionRO `json:"subscriptions,omitempty" db:"subscriptions"`
 }
+
+const (
+	FireAccepted     = "accepted"
+	FireInvalid      = "invalid"
+	FireUnknownEvent = "unknown_event"
+	FireDuplicate    = "duplicate"
+	FireFailed       = "failed"
+	//FirePartial is the fire that has notified some recipients, but failed to notify the others
+	FirePartial = "partial"
+)
+
+type FireBatchResultRO struct {
+	Index          int    `json:"index"`
+	EventName      string `json:"event_name"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	Status         string `json:"status"`
+	EscalationID   uint64 `json:"escalation_id,omitempty"`
+	Delivered      int    `json:"delivered,omitempty"`
+	Failed         int    `json:"failed,omitempty"`
+	Error          string `json:"error,omitempty"`
+	//Code is stable code of the error, see http_errors.Error
+	Code string `json:"code,omitempty"`
+}
+
+//SubscriberExportHeader is csv header of SubscriberExportRO
+var SubscriberExportHeader = []string{"subscriber_id", "phone_number", "active", "subscriptions", "telegram_links",
+	"active_telegram_links", "muted_until"}
+
+//SubscriberExportRO is a row of subscribers export. MutedUntil is mute of every event
+type SubscriberExportRO struct {
+	SubscriberID        uint64     `json:"subscriber_id" db:"subscriber_id"`
+	PhoneNumber         string     `json:"phone_number" db:"phone_number"`
+	Active              bool       `json:"active" db:"active"`
+	Subscriptions       int        `json:"subscriptions" db:"subscriptions"`
+	TelegramLinks       int        `json:"telegram_links" db:"telegram_links"`
+	ActiveTelegramLinks int        `json:"active_telegram_links" db:"active_telegram_links"`
+	MutedUntil          *time.Time `json:"muted_until" db:"muted_until"`
+}
+
+func (r *SubscriberExportRO) Record() []string {
+	return []string{
+		strconv.FormatUint(r.SubscriberID, 10),
+		r.PhoneNumber,
+		strconv.FormatBool(r.Active),
+		strconv.Itoa(r.Subscriptions),
+		strconv.Itoa(r.TelegramLinks),
+		strconv.Itoa(r.ActiveTelegramLinks),
+		formatTime(r.MutedUntil),
+	}
+}
+
+//SubscriptionExportHeader is csv header of SubscriptionExportRO. phone_number and event_name columns are
+//the same as of import, so export might be imported into another tenant
+var SubscriptionExportHeader = []string{"phone_number", "event_name", "subscription_id", "subscriber_id", "event_id"}
+
+//SubscriptionExportRO is a row of subscriptions export. Subscriptions via groups aren't exported
+type SubscriptionExportRO struct {
+	PhoneNumber    string `json:"phone_number" db:"phone_number"`
+	EventName      string `json:"event_name" db:"event_name"`
+	SubscriptionID uint64 `json:"subscription_id" db:"subscription_id"`
+	SubscriberID   uint64 `json:"subscriber_id" db:"subscriber_id"`
+	EventID        uint64 `json:"event_id" db:"event_id"`
+}
+
+func (r *SubscriptionExportRO) Record() []string {
+	return []string{
+		r.PhoneNumber,
+		r.EventName,
+		strconv.FormatUint(r.SubscriptionID, 10),
+		strconv.FormatUint(r.SubscriberID, 10),
+		strconv.FormatUint(r.EventID, 10),
+	}
+}
+
+//TelegramLinkExportHeader is csv header of TelegramLinkRO
+var TelegramLinkExportHeader = []string{"link_id", "phone_number", "bot_name", "telegram_id", "chat_type", "thread_id",
+	"enabled", "active", "inactive_reason", "inactive_since", "created_at"}
+
+func (r *TelegramLinkRO) Record() []string {
+	return []string{
+		strconv.FormatUint(r.LinkID, 10),
+		r.PhoneNumber,
+		r.BotName,
+		strconv.FormatInt(r.TelegramID, 10),
+		r.ChatType,
+		strconv.Itoa(r.ThreadID),
+		strconv.FormatBool(r.Enabled),
+		strconv.FormatBool(r.Active),
+		r.InactiveReason,
+		formatTime(r.InactiveSince),
+		formatTime(&r.CreatedAt),
+	}
+}
+
+//formatTime formats t for csv. Absent time is empty
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+//Statuses of import rows. Rows are subscribed (or would be on dry run) unless any of them is invalid
+const (
+	ImportSubscribed = "subscribed"
+	ImportExists     = "exists"
+	ImportInvalid    = "invalid"
+)
+
+//ImportRowRO is the result of a row of subscriptions import. Row is the line number of the row in csv
+type ImportRowRO struct {
+	Row         int    `json:"row"`
+	PhoneNumber string `json:"phone_number"`
+	EventName   string `json:"event_name"`
+	EventID     uint64 `json:"-"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	//Registered is true when subscriber with PhoneNumber hasn't existed before
+	Registered bool `json:"registered,omitempty"`
+}
+
+type ImportRO struct {
+	DryRun bool `json:"dry_run"`
+	//Committed is false on dry run or when any row is invalid
+	Committed  bool           `json:"committed"`
+	Subscribed int            `json:"subscribed"`
+	Exists     int            `json:"exists"`
+	Invalid    int            `json:"invalid"`
+	Rows       []*ImportRowRO `json:"rows"`
+}
@@ -0,0 +1,87 @@
+package subscription
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/delivery-service/pkg/binder"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/dto"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/response"
+)
+
+func (s *subscriptionTransport) GetSubscriber(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	subscriberID, err := strconv.ParseUint(params.ByName("subscriberId"), 10, 64)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidSubscriberID)
+		logging.FromContext(r.Context(), s.logger).Debug(err.Error())
+		return
+	}
+
+	subscriber, err := s.subscriptionService.GetSubscriberDetail(r.Context(), subscriberID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(s.logger, w, http.StatusOK, response.JSON{
+		"subscriber": subscriber,
+	})
+}
+
+//UpdateSubscriber changes phone number and/or deactivates subscriber, e.g. {"active": false} for someone who has left
+func (s *subscriptionTransport) UpdateSubscriber(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	subscriberID, err := strconv.ParseUint(params.ByName("subscriberId"), 10, 64)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidSubscriberID)
+		logging.FromContext(r.Context(), s.logger).Debug(err.Error())
+		return
+	}
+
+	var inp dto.UpdateSubscriberInp
+	if err := binder.Bind(r.Body, &inp); err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), s.logger).Debug(err.Error())
+		return
+	}
+	if inp.PhoneNumber == nil && inp.Active == nil {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidPayload)
+		logging.FromContext(r.Context(), s.logger).Debug("nothing to update")
+		return
+	}
+
+	subscriber, err := s.subscriptionService.UpdateSubscriber(r.Context(), subscriberID, inp)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(s.logger, w, http.StatusOK, response.JSON{
+		"subscriber": subscriber,
+	})
+}
+
+//EraseSubscriber deletes subscriber with personal data for good. Use UpdateSubscriber to deactivate instead
+func (s *subscriptionTransport) EraseSubscriber(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	subscriberID, err := strconv.ParseUint(params.ByName("subscriberId"), 10, 64)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidSubscriberID)
+		logging.FromContext(r.Context(), s.logger).Debug(err.Error())
+		return
+	}
+
+	if err := s.subscriptionService.EraseSubscriber(r.Context(), subscriberID); err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.NoContent(w)
+}
@@ -0,0 +1,79 @@
+package subscription_test
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/internal/subscription"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/response_object"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+//pageStorage pages subscribers 1..5 by subscriber id the way keyset query does
+type pageStorage struct {
+	storage.DBStorage
+}
+
+func (f *pageStorage) GetSubscribersWithoutSubs(_ context.Context, filter entity.SubscriberFilter) ([]*response_object.SubscriberRO, error) {
+	var subscribers []*response_object.SubscriberRO
+	for id := uint64(1); id <= 5; id++ {
+		subscribers = append(subscribers, &response_object.SubscriberRO{SubscriberID: id})
+	}
+	if filter.Sort == entity.SortSubscriberDesc {
+		sort.Slice(subscribers, func(i, j int) bool { return subscribers[i].SubscriberID > subscribers[j].SubscriberID })
+	}
+
+	var page []*response_object.SubscriberRO
+	for _, sub := range subscribers {
+		if filter.After != "" {
+			after, _ := strconv.ParseUint(filter.After, 10, 64)
+			if (filter.Sort == entity.SortSubscriberID && sub.SubscriberID <= after) ||
+				(filter.Sort == entity.SortSubscriberDesc && sub.SubscriberID >= after) {
+				continue
+			}
+		}
+		if len(page) < filter.Limit {
+			page = append(page, sub)
+		}
+	}
+	return page, nil
+}
+
+func TestGetSubscribersCursor(t *testing.T) {
+	service := subscription.NewSubscriptionService(zap.NewNop().Sugar(), &pageStorage{}, &fakeAuditService{})
+	ctx := context.Background()
+
+	//Cursor of every page leads to the next one, the last page has none
+	var ids []uint64
+	filter := entity.SubscriberFilter{Sort: entity.SortSubscriberID, Limit: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+		subscribers, cursor, err := service.GetSubscribersWithoutSubs(ctx, filter)
+		require.NoError(t, err)
+		for _, sub := range subscribers {
+			ids = append(ids, sub.SubscriberID)
+		}
+		if cursor == "" {
+			break
+		}
+		filter.Cursor = cursor
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, ids)
+
+	_, cursor, err := service.GetSubscribersWithoutSubs(ctx, entity.SubscriberFilter{Sort: entity.SortSubscriberID, Limit: 2})
+	require.NoError(t, err)
+
+	//Cursor is of the sort it was given with
+	_, _, err = service.GetSubscribersWithoutSubs(ctx, entity.SubscriberFilter{Sort: entity.SortSubscriberDesc, Cursor: cursor})
+	assert.ErrorIs(t, err, http_errors.ErrInvalidSubscriberFilter)
+
+	_, _, err = service.GetSubscribersWithoutSubs(ctx, entity.SubscriberFilter{Sort: entity.SortSubscriberID, Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, http_errors.ErrInvalidSubscriberFilter)
+}
@@ -1,100 +1,162 @@
 package subscription
 
 import (
+	"context"
+	"encoding/json"
+	"io"
 	"net/http"
-	"reflect"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 	"github.com/sonyamoonglade/delivery-service/pkg/binder"
 	"github.com/sonyamoonglade/delivery-service/pkg/validation"
 	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/escalation"
 	"github.com/sonyamoonglade/notification-service/internal/events"
 	"github.com/sonyamoonglade/notification-service/internal/events/middlewares"
-	"github.com/sonyamoonglade/notification-service/internal/events/payload"
 	"github.com/sonyamoonglade/notification-service/internal/subscription/dto"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/response_object"
+	"github.com/sonyamoonglade/notification-service/internal/tenant/middlewares"
+	"github.com/sonyamoonglade/notification-service/pkg/alert"
 	"github.com/sonyamoonglade/notification-service/pkg/bot"
 	"github.com/sonyamoonglade/notification-service/pkg/formatter"
 	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/metrics"
 	"github.com/sonyamoonglade/notification-service/pkg/response"
-	"github.com/sonyamoonglade/notification-service/pkg/template"
+	"github.com/sonyamoonglade/notification-service/pkg/server"
+	"github.com/sonyamoonglade/notification-service/pkg/snooze"
 	"go.uber.org/zap"
 )
 
+const maxBatchSize = 100
+
 type Transport interface {
 	Fire(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	FireBatch(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	Subscribe(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	Cancel(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	RegisterSubscriber(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	GetSubscribersJoined(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	GetAvailableEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	GetSubscribersWithoutSubs(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	ExportSubscribers(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	ExportSubscriptions(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	ExportTelegramLinks(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	ImportSubscriptions(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	GetSubscriber(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	UpdateSubscriber(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	EraseSubscriber(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	Mute(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	IssueLinkCode(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	Unmute(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	GetTelegramLinks(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	SetTelegramLink(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	DeleteTelegramLink(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	SetTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	ResetTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	InitRoutes(router *httprouter.Router)
 }
 
 type subscriptionTransport struct {
 	subscriptionService Service
 	eventsService       events.Service
-	templateProvider    template.Provider
+	escalationService   escalation.Service
 	formatter           formatter.Formatter
 	de                  *event_middlewares.DoesExist
+	signed              *event_middlewares.Signed
+	auth                *tenant_middlewares.APIKey
 	logger              *zap.SugaredLogger
-	bot                 bot.Bot
+	bots                *bot.Registry
+	loc                 *time.Location
+	//alerter alerts admins about fires failing on the side of the service, e.g. broken template
+	alerter alert.Alerter
 }
 
 func (s *subscriptionTransport) InitRoutes(router *httprouter.Router) {
-	router.POST("/api/events/fire/:eventName", s.de.Check(s.Fire))
-	router.GET("/api/events", s.GetAvailableEvents)
-	router.POST("/api/subscriptions", s.Subscribe)
-	router.DELETE("/api/subscriptions/:subscriptionId", s.Cancel)
-	router.GET("/api/subscriptions/subscribers/joined", s.GetSubscribersJoined)
-	router.GET("/api/subscriptions/subscribers", s.GetSubscribersWithoutSubs)
-	router.POST("/api/subscriptions/subscribers", s.RegisterSubscriber)
+	router.POST("/api/events/fire/:eventName", s.auth.Require(entity.ScopeEventsFire, s.signed.Check(s.de.Check(s.Fire))))
+	server.HandleVerb(router, http.MethodPost, "/api/events/fire:batch", s.auth.Require(entity.ScopeEventsFire, s.signed.Check(s.FireBatch)))
+	router.GET("/api/events", s.auth.Require(entity.ScopeEventsRead, s.GetAvailableEvents))
+	router.POST("/api/subscriptions", s.auth.Require(entity.ScopeSubscriptionsWrite, s.Subscribe))
+	router.DELETE("/api/subscriptions/:subscriptionId", s.auth.Require(entity.ScopeSubscriptionsWrite, s.Cancel))
+	router.GET("/api/subscriptions/subscribers/joined", s.auth.Require(entity.ScopeSubscribersRead, s.GetSubscribersJoined))
+	router.GET("/api/subscriptions/subscribers", s.auth.Require(entity.ScopeSubscribersRead, s.GetSubscribersWithoutSubs))
+	router.GET("/api/subscribers/:subscriberId", s.auth.Require(entity.ScopeSubscribersRead, s.GetSubscriber))
+	router.PATCH("/api/subscribers/:subscriberId", s.auth.Require(entity.ScopeSubscribersWrite, s.UpdateSubscriber))
+	router.DELETE("/api/subscribers/:subscriberId", s.auth.Require(entity.ScopeSubscribersWrite, s.EraseSubscriber))
+	router.GET("/api/subscriptions/export/subscribers", s.auth.Require(entity.ScopeSubscribersRead, s.ExportSubscribers))
+	router.GET("/api/subscriptions/export/subscriptions", s.auth.Require(entity.ScopeSubscribersRead, s.ExportSubscriptions))
+	router.GET("/api/subscriptions/export/links", s.auth.Require(entity.ScopeSubscribersRead, s.ExportTelegramLinks))
+	//Import registers absent subscribers as well
+	router.POST("/api/subscriptions/import", s.auth.Require(entity.ScopeSubscriptionsWrite,
+		s.auth.Require(entity.ScopeSubscribersWrite, s.ImportSubscriptions)))
+	router.POST("/api/subscriptions/subscribers", s.auth.Require(entity.ScopeSubscribersWrite, s.RegisterSubscriber))
+	router.POST("/api/subscriptions/subscribers/mute", s.auth.Require(entity.ScopeSubscribersWrite, s.Mute))
+	router.POST("/api/subscriptions/subscribers/unmute", s.auth.Require(entity.ScopeSubscribersWrite, s.Unmute))
+	router.POST("/api/subscriptions/subscribers/link-codes", s.auth.Require(entity.ScopeSubscribersWrite, s.IssueLinkCode))
+	router.GET("/api/subscriptions/subscribers/links", s.auth.Require(entity.ScopeSubscribersRead, s.GetTelegramLinks))
+	//Links are addressed outside of /api/subscriptions, since DELETE /api/subscriptions/:subscriptionId takes the segment
+	router.PATCH("/api/telegram-links/:linkId", s.auth.Require(entity.ScopeSubscribersWrite, s.SetTelegramLink))
+	router.DELETE("/api/telegram-links/:linkId", s.auth.Require(entity.ScopeSubscribersWrite, s.DeleteTelegramLink))
+	router.GET("/api/templates/:eventName", s.auth.Require(entity.ScopeEventsRead, s.de.Check(s.GetTemplate)))
+	router.PUT("/api/templates/:eventName", s.auth.Require(entity.ScopeTemplatesWrite, s.de.Check(s.SetTemplate)))
+	router.DELETE("/api/templates/:eventName", s.auth.Require(entity.ScopeTemplatesWrite, s.de.Check(s.ResetTemplate)))
 }
 
 func NewSubscriptionTransport(logger *zap.SugaredLogger,
 	service Service,
 	de *event_middlewares.DoesExist,
+	signed *event_middlewares.Signed,
+	auth *tenant_middlewares.APIKey,
 	eventsService events.Service,
-	templateProvider template.Provider,
+	escalationService escalation.Service,
 	formatter formatter.Formatter,
-	bot bot.Bot) Transport {
+	bots *bot.Registry,
+	alerter alert.Alerter,
+	loc *time.Location) Transport {
 
 	return &subscriptionTransport{
 		logger:              logger,
 		subscriptionService: service,
 		de:                  de,
+		signed:              signed,
+		auth:                auth,
 		eventsService:       eventsService,
-		templateProvider:    templateProvider,
-		bot:                 bot,
+		escalationService:   escalationService,
+		bots:                bots,
+		alerter:             alerter,
 		formatter:           formatter,
+		loc:                 loc,
 	}
 }
 
 func (s *subscriptionTransport) RegisterSubscriber(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	s.logger.Debug("register subscriber")
+	logging.FromContext(r.Context(), s.logger).Debug("register subscriber")
 
 	var inp dto.RegisterSubscriberDto
 
 	err := binder.Bind(r.Body, &inp)
 	if err != nil {
-		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
 		return
 	}
 
 	ok := validation.ValidatePhoneNumber(inp.PhoneNumber)
 	if ok != true {
 		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidPayload)
-		s.logger.Debug("invalid phone number")
+		logging.FromContext(r.Context(), s.logger).Debug("invalid phone number")
 		return
 	}
 
 	_, err = s.subscriptionService.RegisterSubscriber(r.Context(), inp.PhoneNumber)
 	if err != nil {
 		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
 		return
 	}
 
@@ -103,29 +165,81 @@ func (s *subscriptionTransport) RegisterSubscriber(w http.ResponseWriter, r *htt
 
 func (s *subscriptionTransport) GetSubscribersWithoutSubs(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 
-	s.logger.Debug("get subscribers without subs")
+	logging.FromContext(r.Context(), s.logger).Debug("get subscribers without subs")
 
-	subscribers, err := s.subscriptionService.GetSubscribersWithoutSubs(r.Context())
+	filter, err := parseSubscriberFilter(r.URL.Query())
 	if err != nil {
 		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+		logging.FromContext(r.Context(), s.logger).Debug(err.Error())
 		return
 	}
 
-	response.Json(s.logger, w, http.StatusOK, response.JSON{
-		"subscribers": subscribers,
-	})
+	subscribers, next, err := s.subscriptionService.GetSubscribersWithoutSubs(r.Context(), filter)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(s.logger, w, http.StatusOK, subscribersPage(subscribers, next))
 	return
 }
 
+//subscribersPage is the envelope of subscriber listings. Absent next_cursor means the last page
+func subscribersPage(subscribers []*response_object.SubscriberRO, next string) response.JSON {
+	var nextCursor *string
+	if next != "" {
+		nextCursor = &next
+	}
+	return response.JSON{
+		"subscribers": subscribers,
+		"next_cursor": nextCursor,
+	}
+}
+
+//parseSubscriberFilter reads filter from query, e.g. ?phone_prefix=7999&event=worker_login&sort=-subscriber_id&cursor=...
+func parseSubscriberFilter(q url.Values) (entity.SubscriberFilter, error) {
+	filter := entity.SubscriberFilter{
+		PhonePrefix: q.Get("phone_prefix"),
+		EventName:   q.Get("event"),
+		GroupName:   q.Get("group"),
+		Sort:        q.Get("sort"),
+		Cursor:      q.Get("cursor"),
+	}
+
+	switch filter.Sort {
+	case "", entity.SortPhoneNumber, entity.SortPhoneNumberDesc, entity.SortSubscriberID, entity.SortSubscriberDesc:
+	default:
+		return filter, http_errors.ErrInvalidSubscriberFilter
+	}
+
+	if v := q.Get("has_telegram_subscription"); v != "" {
+		has, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, http_errors.ErrInvalidSubscriberFilter
+		}
+		filter.HasTelegramSubscription = &has
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > MaxSubscribersLimit {
+			return filter, http_errors.ErrInvalidSubscriberFilter
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
 func (s *subscriptionTransport) GetAvailableEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 
-	s.logger.Debug("get available events")
+	logging.FromContext(r.Context(), s.logger).Debug("get available events")
 
 	evnts, err := s.eventsService.GetAvailableEvents(r.Context())
 	if err != nil {
 		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
 		return
 	}
 
@@ -135,116 +249,165 @@ func (s *subscriptionTransport) GetAvailableEvents(w http.ResponseWriter, r *htt
 
 }
 
-func (s *subscriptionTransport) Fire(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+func (s *subscriptionTransport) Fire(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	ctx := r.Context()
 	eventID := ctx.Value("eventId").(uint64)
+	eventName := params.ByName("eventName")
 
-	subscribers, err := s.subscriptionService.GetEventSubscribers(ctx, eventID)
+	//Body is read at once, because it's parsed twice: for recipients and for the payload of the event
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
-		return
-	}
-	//No actual subscribers whatsoever, so the rest of the code is a waste
-	if len(subscribers) == 0 {
-		response.NoContent(w)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
 		return
 	}
 
-	subsPhones := s.subscriptionService.SelectPhones(subscribers)
-	telegramSubs, err := s.subscriptionService.GetTelegramSubscribers(ctx, subsPhones)
+	out, err := s.fire(ctx, eventID, body, "")
+	metrics.Fires.WithLabelValues(eventName, fireResult(err)).Inc()
 	if err != nil {
+		//Nobody to notify, so it's not an error
+		if errors.Is(err, http_errors.ErrNoSubscriptions) || errors.Is(err, http_errors.ErrNoTelegramSubscribers) {
+			response.NoContent(w)
+			return
+		}
+		//Some recipients are notified, so the producer is told who's not instead of failing the whole fire
+		if errors.Is(err, http_errors.ErrPartialDelivery) {
+			logging.FromContext(r.Context(), s.logger).Warnf("fire of event %s has failed for %d recipients", eventName, out.Failed)
+			response.Json(s.logger, w, http.StatusMultiStatus, response.JSON{
+				"escalation_id": out.EscalationID,
+				"delivered":     out.Delivered,
+				"failed":        out.Failed,
+			})
+			return
+		}
 		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
 		return
 	}
 
-	//No actual subscribers in telegram, so the rest of the code is a waste
-	if len(telegramSubs) == 0 {
-		response.NoContent(w)
+	if out.EscalationID != 0 {
+		response.Json(s.logger, w, http.StatusOK, response.JSON{
+			"escalation_id": out.EscalationID,
+		})
 		return
 	}
 
-	tmpl, err := s.templateProvider.Find(eventID)
-	if err != nil {
-		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+	response.Ok(w)
+	return
+}
+
+//FireBatch fires every item independently through the same pipeline as Fire.
+//Responds with 207 and per-item results, since some items might be accepted while others are not
+func (s *subscriptionTransport) FireBatch(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	var items []dto.FireBatchItemInp
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidPayload)
+		logging.FromContext(r.Context(), s.logger).Debug(err.Error())
 		return
 	}
-	//tmpl will be formatted into fmtTmpl passing ...args in the switch-case below
-	var fmtTmpl string
 
-	//Find payload type assigned to eventID
-	payloadType := payload.GetProvider().MustGetType(eventID)
+	if len(items) > maxBatchSize {
+		http_errors.MakeErrorResponse(w, http_errors.ErrBatchTooLarge)
+		logging.FromContext(r.Context(), s.logger).Debugf("batch of %d items is too large", len(items))
+		return
+	}
 
-	//Iterate over payload types, assigned to events in payload package
-	//Format the template, assigned to event in templates.json
-	//Keep in mind, formatter.Format func is Variadic
-	switch payloadType {
-	case reflect.TypeOf(payload.WorkerLoginPayload{}):
-		var p payload.WorkerLoginPayload
-		err := binder.Bind(r.Body, &p)
-		if err != nil {
-			s.logger.Error(err.Error())
-			http_errors.MakeErrorResponse(w, err)
-			return
+	results := make([]response_object.FireBatchResultRO, 0, len(items))
+	for i, item := range items {
+		result := s.fireBatchItem(r.Context(), i, item)
+		//Names of unknown events aren't labels, otherwise any producer could blow up cardinality
+		eventName := item.EventName
+		if result.Status == response_object.FireUnknownEvent {
+			eventName = ""
 		}
-		fmtTmpl = s.formatter.Format(
-			tmpl,
-			p.Username,
-			s.formatter.FormatTime(p.LoginAt, p.TimeOffset))
-
-		break
-	case reflect.TypeOf(payload.UserOrderCreatePayload{}):
-		var p payload.UserOrderCreatePayload
-		err := binder.Bind(r.Body, &p)
-		if err != nil {
-			s.logger.Error(err.Error())
-			http_errors.MakeErrorResponse(w, err)
-			return
+		metrics.Fires.WithLabelValues(eventName, result.Status).Inc()
+		results = append(results, result)
+	}
+
+	response.Json(s.logger, w, http.StatusMultiStatus, response.JSON{
+		"results": results,
+	})
+}
+
+func (s *subscriptionTransport) fireBatchItem(ctx context.Context, idx int, item dto.FireBatchItemInp) response_object.FireBatchResultRO {
+	result := response_object.FireBatchResultRO{
+		Index:          idx,
+		EventName:      item.EventName,
+		IdempotencyKey: item.IdempotencyKey,
+	}
+
+	if item.EventName == "" || len(item.Payload) == 0 {
+		result.Status = response_object.FireInvalid
+		result.Error = http_errors.ErrInvalidPayload.Error()
+		result.Code = http_errors.ErrInvalidPayload.Code
+		return result
+	}
+
+	eventID, err := s.eventsService.DoesExist(ctx, item.EventName)
+	if err != nil {
+		if errors.Is(err, http_errors.ErrEventDoesNotExist) {
+			result.Status = response_object.FireUnknownEvent
+			result.Error = err.Error()
+			result.Code = http_errors.CodeOf(err)
+			return result
 		}
-		fmtTmpl = s.formatter.Format(tmpl,
-			p.OrderID,
-			p.Amount)
-
-		break
-	case reflect.TypeOf(payload.MasterOrderCreatePayload{}):
-		var p payload.MasterOrderCreatePayload
-		err := binder.Bind(r.Body, &p)
+		logging.FromContext(ctx, s.logger).Error(err.Error())
+		result.Status = response_object.FireFailed
+		result.Error = http_errors.ErrInternalError.Error()
+		result.Code = http_errors.ErrInternalError.Code
+		return result
+	}
+
+	if item.IdempotencyKey != "" {
+		err = s.subscriptionService.ClaimFire(ctx, item.IdempotencyKey, eventID)
 		if err != nil {
-			s.logger.Error(err.Error())
-			http_errors.MakeErrorResponse(w, err)
-			return
+			if errors.Is(err, http_errors.ErrDuplicateFire) {
+				result.Status = response_object.FireDuplicate
+				return result
+			}
+			logging.FromContext(ctx, s.logger).Error(err.Error())
+			result.Status = response_object.FireFailed
+			result.Error = http_errors.ErrInternalError.Error()
+			result.Code = http_errors.ErrInternalError.Code
+			return result
 		}
+	}
 
-		ok := validation.ValidatePhoneNumber(p.PhoneNumber)
-		if ok != true {
-			http_errors.MakeErrorResponse(w, http_errors.ErrInvalidPayload)
-			return
+	out, err := s.fire(ctx, eventID, item.Payload, item.IdempotencyKey)
+	//Let the producer retry with the same key, reaching only those the fire hasn't
+	if item.IdempotencyKey != "" {
+		if err := s.subscriptionService.SettleFire(ctx, item.IdempotencyKey, out.Delivered, err); err != nil {
+			logging.FromContext(ctx, s.logger).Error(err.Error())
 		}
-		fmtTmpl = s.formatter.Format(tmpl,
-			p.OrderID,
-			p.Username,
-			p.PhoneNumber,
-			p.Amount)
-
-		break
 	}
-
-	//Range over subscriber's associated telegram id's
-	for _, sub := range telegramSubs {
-		//Notify subscribers in telegram here with fmtTmpl text
-		err := s.bot.Notify(sub.TelegramID, fmtTmpl)
-		if err != nil {
-			//todo: if err occurs there bot must send warning msg to admin
-			http_errors.MakeErrorResponse(w, err)
-			s.logger.Error(err.Error())
-			return
+	result.Delivered, result.Failed = out.Delivered, out.Failed
+	if errors.Is(err, http_errors.ErrPartialDelivery) {
+		result.Status = response_object.FirePartial
+		result.EscalationID = out.EscalationID
+		result.Error = err.Error()
+		result.Code = http_errors.CodeOf(err)
+		return result
+	}
+	if err != nil && !errors.Is(err, http_errors.ErrNoSubscriptions) && !errors.Is(err, http_errors.ErrNoTelegramSubscribers) {
+		if errors.Is(err, http_errors.ErrInvalidPayload) ||
+			errors.Is(err, http_errors.ErrUnknownRecipients) ||
+			errors.Is(err, http_errors.ErrInvalidRecipientsMode) {
+			result.Status = response_object.FireInvalid
+			result.Error = err.Error()
+			result.Code = http_errors.CodeOf(err)
+			return result
 		}
+		logging.FromContext(ctx, s.logger).Error(err.Error())
+		result.Status = response_object.FireFailed
+		result.Error = http_errors.ErrInternalError.Error()
+		result.Code = http_errors.ErrInternalError.Code
+		return result
 	}
 
-	response.Ok(w)
-	return
+	result.Status = response_object.FireAccepted
+	result.EscalationID = out.EscalationID
+	return result
 }
 
 func (s *subscriptionTransport) Subscribe(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -254,8 +417,8 @@ func (s *subscriptionTransport) Subscribe(w http.ResponseWriter, r *http.Request
 
 	err := binder.Bind(r.Body, &inp)
 	if err != nil {
-		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
 		return
 	}
 
@@ -263,14 +426,14 @@ func (s *subscriptionTransport) Subscribe(w http.ResponseWriter, r *http.Request
 	if ok != true {
 		err = http_errors.ErrInvalidPayload
 		http_errors.MakeErrorResponse(w, err)
-		s.logger.Debug(err.Error())
+		logging.FromContext(r.Context(), s.logger).Debug(err.Error())
 		return
 	}
 
 	eventID, err := s.eventsService.DoesExist(ctx, inp.EventName)
 	if err != nil {
 		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
 		return
 	}
 
@@ -279,17 +442,17 @@ func (s *subscriptionTransport) Subscribe(w http.ResponseWriter, r *http.Request
 		//If any internal error not SubscriberDoesNotExist
 		if !errors.Is(err, http_errors.ErrSubscriberDoesNotExist) {
 			http_errors.MakeErrorResponse(w, err)
-			s.logger.Error(err.Error())
+			logging.FromContext(r.Context(), s.logger).Error(err.Error())
 			return
 		}
 		//Register subscriber
 		regSubID, err := s.subscriptionService.RegisterSubscriber(ctx, inp.PhoneNumber)
 		if err != nil {
 			http_errors.MakeErrorResponse(w, err)
-			s.logger.Error(err.Error())
+			logging.FromContext(r.Context(), s.logger).Error(err.Error())
 			return
 		}
-		s.logger.Debug("registered subscriber")
+		logging.FromContext(r.Context(), s.logger).Debug("registered subscriber")
 		//Assign newly registered regSubID and phoneNumber to subscriber
 		regSub := entity.Subscriber{
 			SubscriberID: regSubID,
@@ -302,10 +465,10 @@ func (s *subscriptionTransport) Subscribe(w http.ResponseWriter, r *http.Request
 	err = s.subscriptionService.SubscribeToEvent(ctx, subscriber.SubscriberID, eventID)
 	if err != nil {
 		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
 		return
 	}
-	s.logger.Debugf("subscriber with phone %s has subscribed to event %d", inp.PhoneNumber, eventID)
+	logging.FromContext(r.Context(), s.logger).Debugf("subscriber with phone %s has subscribed to event %d", inp.PhoneNumber, eventID)
 
 	response.Created(w)
 	return
@@ -317,21 +480,21 @@ func (s *subscriptionTransport) Cancel(w http.ResponseWriter, r *http.Request, p
 
 	if subscriptionIDstr == "" {
 		http_errors.MakeErrorResponse(w, http_errors.ErrNoSubscriptionID)
-		s.logger.Debug("missing subscription id")
+		logging.FromContext(r.Context(), s.logger).Debug("missing subscription id")
 		return
 	}
 
 	subscriptionID, err := strconv.ParseUint(subscriptionIDstr, 10, 64)
 	if err != nil {
 		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
 		return
 	}
 
 	err = s.subscriptionService.CancelSubscription(r.Context(), subscriptionID)
 	if err != nil {
 		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
 		return
 	}
 
@@ -340,17 +503,310 @@ func (s *subscriptionTransport) Cancel(w http.ResponseWriter, r *http.Request, p
 }
 
 func (s *subscriptionTransport) GetSubscribersJoined(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	s.logger.Debug("get all subscribers joined")
+	logging.FromContext(r.Context(), s.logger).Debug("get all subscribers joined")
 
-	subscribersData, err := s.subscriptionService.GetSubscribersDataJoined(r.Context())
+	filter, err := parseSubscriberFilter(r.URL.Query())
 	if err != nil {
 		http_errors.MakeErrorResponse(w, err)
-		s.logger.Error(err.Error())
+		logging.FromContext(r.Context(), s.logger).Debug(err.Error())
+		return
+	}
+
+	subscribersData, next, err := s.subscriptionService.GetSubscribersDataJoined(r.Context(), filter)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(s.logger, w, http.StatusOK, subscribersPage(subscribersData, next))
+	return
+}
+
+func (s *subscriptionTransport) Mute(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	var inp dto.MuteInp
+	ctx := r.Context()
+
+	err := binder.Bind(r.Body, &inp)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
 		return
 	}
 
+	var until time.Time
+	switch true {
+	case inp.Until != nil:
+		until = *inp.Until
+	case inp.Duration != "":
+		until, err = snooze.Until(inp.Duration, time.Now(), s.loc)
+		if err != nil {
+			http_errors.MakeErrorResponse(w, http_errors.ErrInvalidMuteWindow)
+			logging.FromContext(r.Context(), s.logger).Debug(err.Error())
+			return
+		}
+	default:
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidMuteWindow)
+		logging.FromContext(r.Context(), s.logger).Debug("missing mute duration")
+		return
+	}
+
+	subscriber, eventID, err := s.muteTarget(r, inp.PhoneNumber, inp.EventName)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	err = s.subscriptionService.Mute(ctx, subscriber.SubscriberID, eventID, until)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+	logging.FromContext(r.Context(), s.logger).Debugf("subscriber with phone %s is muted until %s", inp.PhoneNumber, until)
+
 	response.Json(s.logger, w, http.StatusOK, response.JSON{
-		"subscribers": subscribersData,
+		"muted_until": until,
 	})
-	return
+}
+
+func (s *subscriptionTransport) Unmute(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	var inp dto.UnmuteInp
+
+	err := binder.Bind(r.Body, &inp)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	subscriber, eventID, err := s.muteTarget(r, inp.PhoneNumber, inp.EventName)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	err = s.subscriptionService.Unmute(r.Context(), subscriber.SubscriberID, eventID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Ok(w)
+}
+
+//muteTarget resolves subscriber and optional event of mute. Nil eventID means every event
+func (s *subscriptionTransport) muteTarget(r *http.Request, phoneNumber string, eventName string) (*entity.Subscriber, *uint64, error) {
+	ctx := r.Context()
+
+	ok := validation.ValidatePhoneNumber(phoneNumber)
+	if ok != true {
+		return nil, nil, http_errors.ErrInvalidPayload
+	}
+
+	subscriber, err := s.subscriptionService.GetSubscriberByPhone(ctx, phoneNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if eventName == "" {
+		return subscriber, nil, nil
+	}
+
+	eventID, err := s.eventsService.DoesExist(ctx, eventName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return subscriber, &eventID, nil
+}
+
+//IssueLinkCode issues code for admin to send "/link <code>" in group, supergroup or channel chat
+func (s *subscriptionTransport) IssueLinkCode(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	var inp dto.IssueLinkCodeInp
+	ctx := r.Context()
+
+	err := binder.Bind(r.Body, &inp)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	ok := validation.ValidatePhoneNumber(inp.PhoneNumber)
+	if ok != true {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidPayload)
+		logging.FromContext(r.Context(), s.logger).Debug("invalid phone number")
+		return
+	}
+
+	subscriber, err := s.subscriptionService.GetSubscriberByPhone(ctx, inp.PhoneNumber)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	lc, err := s.subscriptionService.IssueLinkCode(ctx, subscriber.SubscriberID, inp.ThreadID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(s.logger, w, http.StatusCreated, response.JSON{
+		"code":       lc.Code,
+		"expires_at": lc.ExpiresAt,
+	})
+}
+
+//GetTelegramLinks returns telegram chats linked with subscriber, e.g. ?phone_number=%2B79999999999
+func (s *subscriptionTransport) GetTelegramLinks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	phoneNumber := r.URL.Query().Get("phone_number")
+	ok := validation.ValidatePhoneNumber(phoneNumber)
+	if ok != true {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidPayload)
+		logging.FromContext(r.Context(), s.logger).Debug("invalid phone number")
+		return
+	}
+
+	links, err := s.subscriptionService.GetTelegramLinks(r.Context(), phoneNumber)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	if links == nil {
+		links = []*response_object.TelegramLinkRO{}
+	}
+
+	response.Json(s.logger, w, http.StatusOK, response.JSON{
+		"links": links,
+	})
+}
+
+//SetTelegramLink enables or disables the link. Nothing is sent through disabled link
+func (s *subscriptionTransport) SetTelegramLink(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	linkID, err := parseLinkID(params)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	var inp dto.SetTelegramLinkInp
+
+	err = binder.Bind(r.Body, &inp)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	if inp.Enabled == nil {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidPayload)
+		logging.FromContext(r.Context(), s.logger).Debug("missing enabled")
+		return
+	}
+
+	err = s.subscriptionService.SetTelegramLinkEnabled(r.Context(), linkID, *inp.Enabled)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Ok(w)
+}
+
+func (s *subscriptionTransport) DeleteTelegramLink(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	linkID, err := parseLinkID(params)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	err = s.subscriptionService.UnlinkTelegramLink(r.Context(), linkID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Ok(w)
+}
+
+//GetTemplate returns template of the event used by tenant of the request
+func (s *subscriptionTransport) GetTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	eventID := ctx.Value("eventId").(uint64)
+
+	text, err := s.eventsService.GetTemplate(ctx, eventID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(s.logger, w, http.StatusOK, response.JSON{
+		"event_id": eventID,
+		"text":     text,
+	})
+}
+
+//SetTemplate overrides template of templates.json for tenant of the request
+func (s *subscriptionTransport) SetTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	eventID := ctx.Value("eventId").(uint64)
+
+	var inp dto.SetTemplateInp
+
+	err := binder.Bind(r.Body, &inp)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	err = s.eventsService.SetTemplate(ctx, eventID, inp.Text)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Ok(w)
+}
+
+//ResetTemplate removes override, so template of templates.json is used again
+func (s *subscriptionTransport) ResetTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	eventID := ctx.Value("eventId").(uint64)
+
+	err := s.eventsService.DeleteTemplate(ctx, eventID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), s.logger).Error(err.Error())
+		return
+	}
+
+	response.Ok(w)
+}
+
+func parseLinkID(params httprouter.Params) (uint64, error) {
+	linkID, err := strconv.ParseUint(params.ByName("linkId"), 10, 64)
+	if err != nil {
+		return 0, http_errors.ErrInvalidLinkID
+	}
+	return linkID, nil
 }
@@ -2,45 +2,163 @@ package subscription
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/delivery-service/pkg/validation"
+	"github.com/sonyamoonglade/notification-service/internal/audit"
 	"github.com/sonyamoonglade/notification-service/internal/entity"
 	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/dto"
 	"github.com/sonyamoonglade/notification-service/internal/subscription/response_object"
 	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/metrics"
 	"github.com/sonyamoonglade/notification-service/pkg/telegram_errors"
 	"go.uber.org/zap"
 )
 
 type Service interface {
-	GetSubscribersDataJoined(ctx context.Context) ([]*response_object.SubscriberRO, error)
+	GetSubscribersDataJoined(ctx context.Context, filter entity.SubscriberFilter) ([]*response_object.SubscriberRO, string, error)
 	GetEventSubscribers(ctx context.Context, eventID uint64) ([]*entity.Subscriber, error)
 	GetSubscriberByPhone(ctx context.Context, phoneNumber string) (*entity.Subscriber, error)
-	GetTelegramSubscribers(ctx context.Context, phoneNumbers []string) ([]*entity.TelegramSubscriber, error)
+	GetSubscriberByID(ctx context.Context, subscriberID uint64) (*entity.Subscriber, error)
+	GetTelegramSubscribers(ctx context.Context, botName string, phoneNumbers []string) ([]*entity.TelegramSubscriber, error)
 	GetSubscription(ctx context.Context, subscriberID uint64, eventID uint64) (*entity.Subscription, error)
-	GetTelegramSubscriber(ctx context.Context, phoneNumber string) (*entity.TelegramSubscriber, error)
-	GetSubscribersWithoutSubs(ctx context.Context) ([]*response_object.SubscriberRO, error)
+	GetTelegramLinks(ctx context.Context, phoneNumber string) ([]*response_object.TelegramLinkRO, error)
+	GetTelegramLinksByTelegramID(ctx context.Context, botName string, telegramID int64) ([]*response_object.TelegramLinkRO, error)
+	GetTelegramLink(ctx context.Context, linkID uint64) (*response_object.TelegramLinkRO, error)
+	SetTelegramLinkEnabled(ctx context.Context, linkID uint64, enabled bool) error
+	UnlinkTelegramLink(ctx context.Context, linkID uint64) error
+	UnlinkTelegramSubscriber(ctx context.Context, botName string, telegramID int64) error
+	DeactivateTelegramChat(ctx context.Context, botName string, telegramID int64, reason string) error
+	ReactivateTelegramChat(ctx context.Context, botName string, telegramID int64) error
+	IssueLinkCode(ctx context.Context, subscriberID uint64, threadID *int) (*entity.LinkCode, error)
+	LinkTelegramChat(ctx context.Context, botName string, code string, chatID int64, chatType string) (*entity.Subscriber, error)
+	MigrateTelegramChat(ctx context.Context, botName string, fromChatID int64, toChatID int64) error
+	GetSubscriberEvents(ctx context.Context, subscriberID uint64) ([]*response_object.SubscriberEventRO, error)
+	Unsubscribe(ctx context.Context, subscriberID uint64, eventID uint64) error
+	GetSubscribersWithoutSubs(ctx context.Context, filter entity.SubscriberFilter) ([]*response_object.SubscriberRO, string, error)
+	ExportSubscribers(ctx context.Context, fn func(*response_object.SubscriberExportRO) error) error
+	ExportSubscriptions(ctx context.Context, fn func(*response_object.SubscriptionExportRO) error) error
+	ExportTelegramLinks(ctx context.Context, fn func(*response_object.TelegramLinkRO) error) error
+	ImportSubscriptions(ctx context.Context, rows []*response_object.ImportRowRO, dryRun bool) (*response_object.ImportRO, error)
 	RegisterSubscriber(ctx context.Context, phoneNumber string) (uint64, error)
-	RegisterTelegramSubscriber(ctx context.Context, telegramID int64, subscriberID uint64) error
+	GetSubscriberDetail(ctx context.Context, subscriberID uint64) (*response_object.SubscriberDetailRO, error)
+	UpdateSubscriber(ctx context.Context, subscriberID uint64, inp dto.UpdateSubscriberInp) (*entity.Subscriber, error)
+	EraseSubscriber(ctx context.Context, subscriberID uint64) error
+	RegisterTelegramSubscriber(ctx context.Context, botName string, telegramID int64, subscriberID uint64) error
 	SubscribeToEvent(ctx context.Context, subscriberID uint64, eventID uint64) error
+	ResolveRecipients(ctx context.Context, eventID uint64, recipients *dto.FireRecipients) ([]*entity.Subscriber, error)
 	SelectPhones(subs []*entity.Subscriber) []string
+	ClaimFire(ctx context.Context, idempotencyKey string, eventID uint64) error
+	Mute(ctx context.Context, subscriberID uint64, eventID *uint64, until time.Time) error
+	Unmute(ctx context.Context, subscriberID uint64, eventID *uint64) error
+	GetActiveMutes(ctx context.Context, subscriberID uint64) ([]*entity.Mute, error)
+	SuppressMuted(ctx context.Context, eventID uint64, subs []*entity.Subscriber) ([]*entity.Subscriber, error)
+	SkipFiredRecipients(ctx context.Context, idempotencyKey string, subs []*entity.TelegramSubscriber) ([]*entity.TelegramSubscriber, error)
+	RecordFiredRecipient(ctx context.Context, idempotencyKey string, linkID uint64) error
+	SettleFire(ctx context.Context, idempotencyKey string, delivered int, fireErr error) error
+	RecordFire(ctx context.Context, f *entity.Fire, deliveries []*entity.Delivery) error
 	CancelSubscription(ctx context.Context, subscriptionID uint64) error
 }
 
+//linkCodeTTL is minutes admin has to send /link <code> in the chat
+const linkCodeTTL = 60
+
+const (
+	DefaultSubscribersLimit = 50
+	MaxSubscribersLimit     = 200
+)
+
 type subscriptionService struct {
-	storage storage.DBStorage
-	logger  *zap.SugaredLogger
+	storage      storage.DBStorage
+	auditService audit.Service
+	logger       *zap.SugaredLogger
 }
 
-func NewSubscriptionService(logger *zap.SugaredLogger, storage storage.DBStorage) Service {
-	return &subscriptionService{logger: logger, storage: storage}
+func NewSubscriptionService(logger *zap.SugaredLogger, storage storage.DBStorage, auditService audit.Service) Service {
+	return &subscriptionService{logger: logger, storage: storage, auditService: auditService}
 }
 
-func (s *subscriptionService) GetSubscribersWithoutSubs(ctx context.Context) ([]*response_object.SubscriberRO, error) {
-	return s.storage.GetSubscribersWithoutSubs(ctx)
+//GetSubscribersWithoutSubs returns a page of subscribers matching filter and cursor of the next page.
+//Cursor is empty on the last page
+func (s *subscriptionService) GetSubscribersWithoutSubs(ctx context.Context, filter entity.SubscriberFilter) ([]*response_object.SubscriberRO, string, error) {
+	return s.subscribersPage(ctx, filter, s.storage.GetSubscribersWithoutSubs)
 }
 
-func (s *subscriptionService) GetSubscribersDataJoined(ctx context.Context) ([]*response_object.SubscriberRO, error) {
-	return s.storage.GetSubscribersDataJoined(ctx)
+//GetSubscribersDataJoined is GetSubscribersWithoutSubs of subscribers having subscriptions, with their subscriptions
+func (s *subscriptionService) GetSubscribersDataJoined(ctx context.Context, filter entity.SubscriberFilter) ([]*response_object.SubscriberRO, string, error) {
+	return s.subscribersPage(ctx, filter, s.storage.GetSubscribersDataJoined)
+}
+
+func (s *subscriptionService) subscribersPage(ctx context.Context, filter entity.SubscriberFilter,
+	get func(ctx context.Context, f entity.SubscriberFilter) ([]*response_object.SubscriberRO, error)) ([]*response_object.SubscriberRO, string, error) {
+
+	if filter.Sort == "" {
+		filter.Sort = entity.SortPhoneNumber
+	}
+	if filter.Limit <= 0 || filter.Limit > MaxSubscribersLimit {
+		filter.Limit = DefaultSubscribersLimit
+	}
+	if filter.Cursor != "" {
+		after, err := decodeSubscribersCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.After = after
+	}
+	limit := filter.Limit
+	//One more subscriber tells whether there's the next page
+	filter.Limit++
+
+	subscribers, err := get(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if subscribers == nil {
+		return []*response_object.SubscriberRO{}, "", nil
+	}
+	if len(subscribers) <= limit {
+		return subscribers, "", nil
+	}
+
+	subscribers = subscribers[:limit]
+	last := subscribers[limit-1]
+	after := last.PhoneNumber
+	if filter.Sort == entity.SortSubscriberID || filter.Sort == entity.SortSubscriberDesc {
+		after = strconv.FormatUint(last.SubscriberID, 10)
+	}
+	return subscribers, encodeSubscribersCursor(filter.Sort, after), nil
+}
+
+//Cursor of subscriber listings is sort and the sort value of the last subscriber of the page,
+//so it can't be used with another sort
+func encodeSubscribersCursor(sort string, after string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(sort + ":" + after))
+}
+
+func decodeSubscribersCursor(cursor string, sort string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", http_errors.ErrInvalidSubscriberFilter
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 || parts[0] != sort || parts[1] == "" {
+		return "", http_errors.ErrInvalidSubscriberFilter
+	}
+	if sort == entity.SortSubscriberID || sort == entity.SortSubscriberDesc {
+		if _, err := strconv.ParseUint(parts[1], 10, 64); err != nil {
+			return "", http_errors.ErrInvalidSubscriberFilter
+		}
+	}
+	return parts[1], nil
 }
 
 func (s *subscriptionService) SubscribeToEvent(ctx context.Context, subscriberID uint64, eventID uint64) error {
@@ -48,10 +166,16 @@ func (s *subscriptionService) SubscribeToEvent(ctx context.Context, subscriberID
 	if err != nil {
 		return err
 	}
-	s.logger.Debugf("%d", subscriptionID)
+	logging.FromContext(ctx, s.logger).Debugf("%d", subscriptionID)
 	if subscriptionID == 0 {
 		return http_errors.ErrSubscriptionAlreadyExists
 	}
+
+	s.auditService.Record(ctx, audit.ActionCreate, audit.TargetSubscription, subscriptionID, nil, entity.Subscription{
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		SubscriberID:   subscriberID,
+	})
 	return nil
 }
 
@@ -88,8 +212,226 @@ func (s *subscriptionService) GetSubscriberByPhone(ctx context.Context, phoneNum
 	return sub, nil
 }
 
+func (s *subscriptionService) GetSubscriberByID(ctx context.Context, subscriberID uint64) (*entity.Subscriber, error) {
+	subs, err := s.storage.GetSubscribersByIDs(ctx, []uint64{subscriberID})
+	if err != nil {
+		return nil, err
+	}
+	//No such subscriber
+	if len(subs) == 0 {
+		return nil, http_errors.ErrSubscriberDoesNotExist
+	}
+	return subs[0], nil
+}
+
 func (s *subscriptionService) RegisterSubscriber(ctx context.Context, phoneNumber string) (uint64, error) {
-	return s.storage.RegisterSubscriber(ctx, phoneNumber)
+	subscriberID, err := s.storage.RegisterSubscriber(ctx, phoneNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	s.auditService.Record(ctx, audit.ActionCreate, audit.TargetSubscriber, subscriberID, nil,
+		subscriberFields(&entity.Subscriber{SubscriberID: subscriberID, Active: true}))
+	return subscriberID, nil
+}
+
+func (s *subscriptionService) GetSubscriberDetail(ctx context.Context, subscriberID uint64) (*response_object.SubscriberDetailRO, error) {
+	sub, err := s.GetSubscriberByID(ctx, subscriberID)
+	if err != nil {
+		return nil, err
+	}
+	detail := &response_object.SubscriberDetailRO{Subscriber: *sub}
+
+	if detail.Events, err = s.storage.GetSubscriberEvents(ctx, subscriberID); err != nil {
+		return nil, err
+	}
+	if detail.TelegramLinks, err = s.storage.GetTelegramLinksByPhone(ctx, sub.PhoneNumber); err != nil {
+		return nil, err
+	}
+	if detail.Mutes, err = s.storage.GetActiveMutes(ctx, subscriberID); err != nil {
+		return nil, err
+	}
+	//Empty lists are [] rather than null in response
+	if detail.Events == nil {
+		detail.Events = []*response_object.SubscriberEventRO{}
+	}
+	if detail.TelegramLinks == nil {
+		detail.TelegramLinks = []*response_object.TelegramLinkRO{}
+	}
+	if detail.Mutes == nil {
+		detail.Mutes = []*entity.Mute{}
+	}
+	return detail, nil
+}
+
+//UpdateSubscriber changes phone number and deactivates or reactivates the subscriber.
+//Inactive subscriber is skipped in fan-out, but keeps subscriptions and telegram links
+func (s *subscriptionService) UpdateSubscriber(ctx context.Context, subscriberID uint64, inp dto.UpdateSubscriberInp) (*entity.Subscriber, error) {
+	before, err := s.GetSubscriberByID(ctx, subscriberID)
+	if err != nil {
+		return nil, err
+	}
+
+	if inp.PhoneNumber != nil && *inp.PhoneNumber != before.PhoneNumber {
+		if validation.ValidatePhoneNumber(*inp.PhoneNumber) != true {
+			return nil, http_errors.ErrInvalidPayload
+		}
+		ok, err := s.storage.UpdateSubscriberPhone(ctx, subscriberID, *inp.PhoneNumber)
+		if err != nil {
+			if errors.Is(err, http_errors.ErrAlreadyExists) {
+				return nil, http_errors.ErrSubscriberAlreadyExists
+			}
+			return nil, err
+		}
+		if ok != true {
+			return nil, http_errors.ErrSubscriberDoesNotExist
+		}
+	}
+
+	if inp.Active != nil && *inp.Active != before.Active {
+		ok, err := s.storage.SetSubscriberActive(ctx, subscriberID, *inp.Active)
+		if err != nil {
+			return nil, err
+		}
+		if ok != true {
+			return nil, http_errors.ErrSubscriberDoesNotExist
+		}
+	}
+
+	after, err := s.GetSubscriberByID(ctx, subscriberID)
+	if err != nil {
+		return nil, err
+	}
+
+	if after.PhoneNumber != before.PhoneNumber || after.Active != before.Active {
+		fields := subscriberFields(after)
+		if after.PhoneNumber != before.PhoneNumber {
+			fields["phone_number_changed"] = true
+		}
+		s.auditService.Record(ctx, audit.ActionUpdate, audit.TargetSubscriber, subscriberID, subscriberFields(before), fields)
+	}
+	return after, nil
+}
+
+//EraseSubscriber removes personal data of the subscriber for good (see storage.EraseSubscriber).
+//Deliveries are kept anonymous, so stats stay the same. Audit entry of the erasure holds no personal data
+func (s *subscriptionService) EraseSubscriber(ctx context.Context, subscriberID uint64) error {
+	ok, err := s.storage.EraseSubscriber(ctx, subscriberID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return http_errors.ErrSubscriberDoesNotExist
+	}
+
+	s.auditService.Record(ctx, audit.ActionErase, audit.TargetSubscriber, subscriberID, nil, nil)
+	return nil
+}
+
+//ResolveRecipients turns explicit recipients of the fire into subscribers.
+//Every recipient must be known, otherwise ErrUnknownRecipients is returned listing the unknown ones
+func (s *subscriptionService) ResolveRecipients(ctx context.Context, eventID uint64, recipients *dto.FireRecipients) ([]*entity.Subscriber, error) {
+	mode := recipients.Mode
+	if mode == "" {
+		mode = dto.RecipientsReplace
+	}
+	if mode != dto.RecipientsReplace && mode != dto.RecipientsIntersect {
+		return nil, http_errors.ErrInvalidRecipientsMode
+	}
+
+	var targeted []*entity.Subscriber
+	var unknown []string
+	seen := make(map[uint64]bool)
+
+	add := func(subs []*entity.Subscriber) {
+		for _, sub := range subs {
+			if seen[sub.SubscriberID] {
+				continue
+			}
+			seen[sub.SubscriberID] = true
+			targeted = append(targeted, sub)
+		}
+	}
+
+	if len(recipients.PhoneNumbers) != 0 {
+		subs, err := s.storage.GetSubscribersByPhones(ctx, recipients.PhoneNumbers)
+		if err != nil {
+			return nil, err
+		}
+		known := make(map[string]bool, len(subs))
+		for _, sub := range subs {
+			known[sub.PhoneNumber] = true
+		}
+		for _, ph := range recipients.PhoneNumbers {
+			if known[ph] != true {
+				unknown = append(unknown, ph)
+			}
+		}
+		add(subs)
+	}
+
+	if len(recipients.SubscriberIDs) != 0 {
+		subs, err := s.storage.GetSubscribersByIDs(ctx, recipients.SubscriberIDs)
+		if err != nil {
+			return nil, err
+		}
+		known := make(map[uint64]bool, len(subs))
+		for _, sub := range subs {
+			known[sub.SubscriberID] = true
+		}
+		for _, id := range recipients.SubscriberIDs {
+			if known[id] != true {
+				unknown = append(unknown, strconv.FormatUint(id, 10))
+			}
+		}
+		add(subs)
+	}
+
+	if len(recipients.Groups) != 0 {
+		names := make([]string, 0, len(recipients.Groups))
+		for _, name := range recipients.Groups {
+			names = append(names, strings.ToLower(strings.TrimSpace(name)))
+		}
+		groups, err := s.storage.GetGroupsByNames(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		known := make(map[string]bool, len(groups))
+		for _, g := range groups {
+			known[g.Name] = true
+		}
+		for _, name := range names {
+			if known[name] != true {
+				unknown = append(unknown, name)
+			}
+		}
+		members, err := s.storage.GetGroupsMembers(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		add(members)
+	}
+
+	if len(unknown) != 0 {
+		return nil, http_errors.NewErrUnknownRecipients(unknown)
+	}
+
+	if mode == dto.RecipientsReplace {
+		return targeted, nil
+	}
+
+	//Intersect keeps only those targeted, who are subscribed to the event (directly or via group)
+	eventSubs, err := s.storage.GetEventSubscribers(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	var subs []*entity.Subscriber
+	for _, sub := range eventSubs {
+		if seen[sub.SubscriberID] {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
 }
 
 func (s *subscriptionService) SelectPhones(subs []*entity.Subscriber) []string {
@@ -100,8 +442,10 @@ func (s *subscriptionService) SelectPhones(subs []*entity.Subscriber) []string {
 	return ph
 }
 
-func (s *subscriptionService) RegisterTelegramSubscriber(ctx context.Context, telegramID int64, subscriberID uint64) error {
-	ok, err := s.storage.RegisterTelegramSubscriber(ctx, telegramID, subscriberID)
+//RegisterTelegramSubscriber links telegram account with subscriber. Account might be linked with many subscribers
+//and vice versa. ErrTgSubscriberAlreadyExists is returned when exactly this link already exists
+func (s *subscriptionService) RegisterTelegramSubscriber(ctx context.Context, botName string, telegramID int64, subscriberID uint64) error {
+	ok, err := s.storage.RegisterTelegramSubscriber(ctx, botName, telegramID, subscriberID)
 	if err != nil {
 		return err
 	}
@@ -109,32 +453,530 @@ func (s *subscriptionService) RegisterTelegramSubscriber(ctx context.Context, te
 		return telegram_errors.ErrTgSubscriberAlreadyExists
 	}
 
+	s.auditService.Record(ctx, audit.ActionCreate, audit.TargetTelegramLink, subscriberID, nil, linkFields(&entity.TelegramSubscriber{
+		SubscriberID: subscriberID,
+		ChatType:     "private",
+		BotName:      botName,
+		Enabled:      true,
+		Active:       true,
+	}))
 	return nil
 }
 
-func (s *subscriptionService) GetTelegramSubscriber(ctx context.Context, phoneNumber string) (*entity.TelegramSubscriber, error) {
-	tgsub, err := s.storage.GetTelegramSubscriber(ctx, phoneNumber)
+//GetTelegramLinks returns every link of subscriber with phoneNumber, disabled ones included
+func (s *subscriptionService) GetTelegramLinks(ctx context.Context, phoneNumber string) ([]*response_object.TelegramLinkRO, error) {
+	_, err := s.GetSubscriberByPhone(ctx, phoneNumber)
 	if err != nil {
 		return nil, err
 	}
-	if tgsub == nil {
+	return s.storage.GetTelegramLinksByPhone(ctx, phoneNumber)
+}
+
+//GetTelegramLinksByTelegramID returns links of telegram chat made with the bot, the oldest first
+func (s *subscriptionService) GetTelegramLinksByTelegramID(ctx context.Context, botName string, telegramID int64) ([]*response_object.TelegramLinkRO, error) {
+	links, err := s.storage.GetTelegramLinksByTelegramID(ctx, botName, telegramID)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
 		return nil, telegram_errors.ErrNoSuchTelegramSubscriber
 	}
 
-	return tgsub, nil
+	return links, nil
 }
 
-func (s *subscriptionService) GetTelegramSubscribers(ctx context.Context, phoneNumbers []string) ([]*entity.TelegramSubscriber, error) {
-	return s.storage.GetTelegramSubscribers(ctx, phoneNumbers)
+func (s *subscriptionService) GetTelegramLink(ctx context.Context, linkID uint64) (*response_object.TelegramLinkRO, error) {
+	link, err := s.storage.GetTelegramLink(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return nil, http_errors.ErrTelegramLinkDoesNotExist
+	}
+	return link, nil
 }
 
-func (s *subscriptionService) CancelSubscription(ctx context.Context, subscriptionID uint64) error {
-	ok, err := s.storage.CancelSubscription(ctx, subscriptionID)
+func (s *subscriptionService) SetTelegramLinkEnabled(ctx context.Context, linkID uint64, enabled bool) error {
+	before, err := s.GetTelegramLink(ctx, linkID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.storage.SetTelegramLinkEnabled(ctx, linkID, enabled)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return http_errors.ErrTelegramLinkDoesNotExist
+	}
+
+	after := before.TelegramSubscriber
+	after.Enabled = enabled
+	s.auditService.Record(ctx, audit.ActionUpdate, audit.TargetTelegramLink, linkID,
+		linkFields(&before.TelegramSubscriber), linkFields(&after))
+	return nil
+}
+
+func (s *subscriptionService) UnlinkTelegramLink(ctx context.Context, linkID uint64) error {
+	before, err := s.GetTelegramLink(ctx, linkID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.storage.DeleteTelegramLink(ctx, linkID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return http_errors.ErrTelegramLinkDoesNotExist
+	}
+
+	s.auditService.Record(ctx, audit.ActionDelete, audit.TargetTelegramLink, linkID, linkFields(&before.TelegramSubscriber), nil)
+	return nil
+}
+
+//DeactivateTelegramChat stops fan-outs to the chat telegram refuses to deliver to. See telegram_errors.Reason
+func (s *subscriptionService) DeactivateTelegramChat(ctx context.Context, botName string, telegramID int64, reason string) error {
+	links, err := s.storage.GetTelegramLinksByTelegramID(ctx, botName, telegramID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.storage.DeactivateTelegramChat(ctx, botName, telegramID, reason)
+	if err != nil {
+		return err
+	}
+	if ok {
+		logging.FromContext(ctx, s.logger).Infof("telegram chat %d of bot %s is deactivated: %s", telegramID, botName, reason)
+		for _, link := range links {
+			if link.Active != true {
+				continue
+			}
+			after := link.TelegramSubscriber
+			after.Active = false
+			after.InactiveReason = reason
+			s.auditService.Record(ctx, audit.ActionDeactivate, audit.TargetTelegramLink, link.LinkID,
+				linkFields(&link.TelegramSubscriber), linkFields(&after))
+		}
+	}
+	return nil
+}
+
+//ReactivateTelegramChat resumes fan-outs to the chat, e.g. when user unblocks the bot
+func (s *subscriptionService) ReactivateTelegramChat(ctx context.Context, botName string, telegramID int64) error {
+	links, err := s.storage.GetTelegramLinksByTelegramID(ctx, botName, telegramID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.storage.ReactivateTelegramChat(ctx, botName, telegramID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return telegram_errors.ErrNoSuchTelegramSubscriber
+	}
+	logging.FromContext(ctx, s.logger).Infof("telegram chat %d of bot %s is reactivated", telegramID, botName)
+	for _, link := range links {
+		if link.Active {
+			continue
+		}
+		after := link.TelegramSubscriber
+		after.Active = true
+		after.InactiveReason = ""
+		s.auditService.Record(ctx, audit.ActionReactivate, audit.TargetTelegramLink, link.LinkID,
+			linkFields(&link.TelegramSubscriber), linkFields(&after))
+	}
+	return nil
+}
+
+//UnlinkTelegramSubscriber removes every link of telegram chat
+func (s *subscriptionService) UnlinkTelegramSubscriber(ctx context.Context, botName string, telegramID int64) error {
+	before, err := s.GetTelegramLinksByTelegramID(ctx, botName, telegramID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.storage.DeleteTelegramSubscriber(ctx, botName, telegramID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return telegram_errors.ErrNoSuchTelegramSubscriber
+	}
+
+	for _, link := range before {
+		s.auditService.Record(ctx, audit.ActionDelete, audit.TargetTelegramLink, link.LinkID, linkFields(&link.TelegramSubscriber), nil)
+	}
+	return nil
+}
+
+func (s *subscriptionService) GetSubscriberEvents(ctx context.Context, subscriberID uint64) ([]*response_object.SubscriberEventRO, error) {
+	return s.storage.GetSubscriberEvents(ctx, subscriberID)
+}
+
+//Unsubscribe cancels direct subscription. Subscriptions via groups stay untouched
+func (s *subscriptionService) Unsubscribe(ctx context.Context, subscriberID uint64, eventID uint64) error {
+	subscription, err := s.GetSubscription(ctx, subscriberID, eventID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.storage.CancelSubscriberSubscription(ctx, subscriberID, eventID)
 	if err != nil {
 		return err
 	}
 	if ok != true {
 		return http_errors.ErrSubscriptionDoesNotExist
 	}
+
+	s.auditService.Record(ctx, audit.ActionDelete, audit.TargetSubscription, subscription.SubscriptionID, subscription, nil)
 	return nil
 }
+
+func (s *subscriptionService) GetTelegramSubscribers(ctx context.Context, botName string, phoneNumbers []string) ([]*entity.TelegramSubscriber, error) {
+	return s.storage.GetTelegramSubscribers(ctx, botName, phoneNumbers)
+}
+
+func (s *subscriptionService) CancelSubscription(ctx context.Context, subscriptionID uint64) error {
+	subscription, err := s.storage.CancelSubscription(ctx, subscriptionID)
+	if err != nil {
+		return err
+	}
+	if subscription == nil {
+		return http_errors.ErrSubscriptionDoesNotExist
+	}
+
+	s.auditService.Record(ctx, audit.ActionDelete, audit.TargetSubscription, subscriptionID, subscription, nil)
+	return nil
+}
+
+//ClaimFire makes sure the fire with idempotencyKey happens once
+func (s *subscriptionService) ClaimFire(ctx context.Context, idempotencyKey string, eventID uint64) error {
+	ok, err := s.storage.ClaimIdempotencyKey(ctx, idempotencyKey, eventID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return http_errors.ErrDuplicateFire
+	}
+	return nil
+}
+
+//SkipFiredRecipients drops subs reached by previous attempts of the fire with idempotencyKey
+func (s *subscriptionService) SkipFiredRecipients(ctx context.Context, idempotencyKey string, subs []*entity.TelegramSubscriber) ([]*entity.TelegramSubscriber, error) {
+	linkIDs, err := s.storage.GetIdempotencyRecipients(ctx, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(linkIDs) == 0 {
+		return subs, nil
+	}
+
+	fired := make(map[uint64]struct{}, len(linkIDs))
+	for _, linkID := range linkIDs {
+		fired[linkID] = struct{}{}
+	}
+	rest := make([]*entity.TelegramSubscriber, 0, len(subs))
+	for _, sub := range subs {
+		if _, ok := fired[sub.LinkID]; ok {
+			continue
+		}
+		rest = append(rest, sub)
+	}
+	return rest, nil
+}
+
+//RecordFiredRecipient remembers the link reached by the fire with idempotencyKey, so retry won't notify it twice
+func (s *subscriptionService) RecordFiredRecipient(ctx context.Context, idempotencyKey string, linkID uint64) error {
+	return s.storage.AddIdempotencyRecipient(ctx, idempotencyKey, linkID)
+}
+
+//SettleFire is called once the fire with idempotencyKey is over with fireErr.
+//The key is released when nobody's been reached, so the producer could retry, e.g. after invalid payload.
+//Otherwise the key is kept and the retry of failed fire reaches only those left
+func (s *subscriptionService) SettleFire(ctx context.Context, idempotencyKey string, delivered int, fireErr error) error {
+	if fireErr == nil || errors.Is(fireErr, http_errors.ErrNoSubscriptions) || errors.Is(fireErr, http_errors.ErrNoTelegramSubscribers) {
+		return s.storage.SetIdempotencyKeyState(ctx, idempotencyKey, entity.IdempotencyDone)
+	}
+
+	if delivered == 0 {
+		//Previous attempts might have reached someone
+		linkIDs, err := s.storage.GetIdempotencyRecipients(ctx, idempotencyKey)
+		if err != nil {
+			return err
+		}
+		if len(linkIDs) == 0 {
+			return s.storage.ReleaseIdempotencyKey(ctx, idempotencyKey)
+		}
+	}
+	return s.storage.SetIdempotencyKeyState(ctx, idempotencyKey, entity.IdempotencyPartial)
+}
+
+func (s *subscriptionService) ExportSubscribers(ctx context.Context, fn func(*response_object.SubscriberExportRO) error) error {
+	return s.storage.ExportSubscribers(ctx, fn)
+}
+
+func (s *subscriptionService) ExportSubscriptions(ctx context.Context, fn func(*response_object.SubscriptionExportRO) error) error {
+	return s.storage.ExportSubscriptions(ctx, fn)
+}
+
+func (s *subscriptionService) ExportTelegramLinks(ctx context.Context, fn func(*response_object.TelegramLinkRO) error) error {
+	return s.storage.ExportTelegramLinks(ctx, fn)
+}
+
+//ImportSubscriptions validates rows and subscribes valid ones, registering absent subscribers, in one transaction.
+//Rows already marked invalid, e.g. malformed csv records, are kept as is.
+//Nothing is committed on dry run or when any row is invalid, though valid rows still get the status they'd have
+func (s *subscriptionService) ImportSubscriptions(ctx context.Context, rows []*response_object.ImportRowRO, dryRun bool) (*response_object.ImportRO, error) {
+	result := &response_object.ImportRO{DryRun: dryRun, Rows: rows}
+
+	eventIDs := make(map[string]uint64)
+	valid := make([]*response_object.ImportRowRO, 0, len(rows))
+	for _, row := range rows {
+		if row.Status == response_object.ImportInvalid {
+			continue
+		}
+		if validation.ValidatePhoneNumber(row.PhoneNumber) != true {
+			row.Status, row.Error = response_object.ImportInvalid, "invalid phone number"
+			continue
+		}
+
+		eventID, ok := eventIDs[row.EventName]
+		if ok != true {
+			var err error
+			eventID, err = s.storage.DoesExist(ctx, row.EventName)
+			if err != nil {
+				return nil, err
+			}
+			eventIDs[row.EventName] = eventID
+		}
+		if eventID == 0 {
+			row.Status, row.Error = response_object.ImportInvalid, fmt.Sprintf("event with name %s does not exist", row.EventName)
+			continue
+		}
+
+		row.EventID = eventID
+		valid = append(valid, row)
+	}
+
+	result.Invalid = len(rows) - len(valid)
+	result.Committed = dryRun != true && result.Invalid == 0 && len(valid) > 0
+	if len(valid) > 0 {
+		if err := s.storage.ImportSubscriptions(ctx, valid, result.Committed); err != nil {
+			return nil, err
+		}
+	}
+
+	registered := 0
+	for _, row := range valid {
+		switch row.Status {
+		case response_object.ImportSubscribed:
+			result.Subscribed++
+		case response_object.ImportExists:
+			result.Exists++
+		}
+		if row.Registered {
+			registered++
+		}
+	}
+
+	if result.Committed {
+		s.auditService.Record(ctx, audit.ActionImport, audit.TargetSubscription, "", nil, audit.Fields{
+			"rows":       len(rows),
+			"subscribed": result.Subscribed,
+			"exists":     result.Exists,
+			"registered": registered,
+		})
+	}
+	return result, nil
+}
+
+//RecordFire keeps the fire and its deliveries for stats (see stats.Service)
+func (s *subscriptionService) RecordFire(ctx context.Context, f *entity.Fire, deliveries []*entity.Delivery) error {
+	return s.storage.RecordFire(ctx, f, deliveries)
+}
+
+func (s *subscriptionService) Mute(ctx context.Context, subscriberID uint64, eventID *uint64, until time.Time) error {
+	if until.After(time.Now()) != true {
+		return http_errors.ErrInvalidMuteWindow
+	}
+	if err := s.storage.Mute(ctx, subscriberID, eventID, until); err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, audit.ActionCreate, audit.TargetMute, subscriberID, nil, entity.Mute{
+		SubscriberID: subscriberID,
+		EventID:      eventID,
+		MutedUntil:   until,
+	})
+	return nil
+}
+
+func (s *subscriptionService) Unmute(ctx context.Context, subscriberID uint64, eventID *uint64) error {
+	ok, err := s.storage.Unmute(ctx, subscriberID, eventID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return http_errors.ErrNotMuted
+	}
+
+	s.auditService.Record(ctx, audit.ActionDelete, audit.TargetMute, subscriberID, entity.Mute{
+		SubscriberID: subscriberID,
+		EventID:      eventID,
+	}, nil)
+	return nil
+}
+
+func (s *subscriptionService) GetActiveMutes(ctx context.Context, subscriberID uint64) ([]*entity.Mute, error) {
+	return s.storage.GetActiveMutes(ctx, subscriberID)
+}
+
+//SuppressMuted returns subscribers who haven't muted eventID.
+//Muted ones are recorded as suppressed deliveries instead of being silently dropped
+func (s *subscriptionService) SuppressMuted(ctx context.Context, eventID uint64, subs []*entity.Subscriber) ([]*entity.Subscriber, error) {
+	ids := make([]uint64, 0, len(subs))
+	for _, sub := range subs {
+		ids = append(ids, sub.SubscriberID)
+	}
+
+	mutedIDs, err := s.storage.GetMutedSubscribers(ctx, eventID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(mutedIDs) == 0 {
+		return subs, nil
+	}
+
+	err = s.storage.RecordDeliveries(ctx, eventID, mutedIDs, entity.DeliverySuppressed)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx, s.logger).Debugf("suppressed %d muted subscribers of event %d", len(mutedIDs), eventID)
+	metrics.Deliveries.WithLabelValues(metrics.ChannelTelegram, metrics.DeliverySuppressed).Add(float64(len(mutedIDs)))
+
+	muted := make(map[uint64]bool, len(mutedIDs))
+	for _, id := range mutedIDs {
+		muted[id] = true
+	}
+
+	var active []*entity.Subscriber
+	for _, sub := range subs {
+		if muted[sub.SubscriberID] != true {
+			active = append(active, sub)
+		}
+	}
+	return active, nil
+}
+
+func (s *subscriptionService) IssueLinkCode(ctx context.Context, subscriberID uint64, threadID *int) (*entity.LinkCode, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	//5 random bytes are exactly 8 base32 chars without padding
+	code := base32.StdEncoding.EncodeToString(buf)
+
+	lc, err := s.storage.CreateLinkCode(ctx, code, subscriberID, threadID, linkCodeTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	//The code itself isn't audited, since it links any chat until it expires
+	s.auditService.Record(ctx, audit.ActionCreate, audit.TargetLinkCode, subscriberID, nil, audit.Fields{
+		"subscriber_id": lc.SubscriberID,
+		"thread_id":     lc.ThreadID,
+		"expires_at":    lc.ExpiresAt,
+	})
+	return lc, nil
+}
+
+//LinkTelegramChat links group, supergroup or channel chat with subscriber the code was issued for
+func (s *subscriptionService) LinkTelegramChat(ctx context.Context, botName string, code string, chatID int64, chatType string) (*entity.Subscriber, error) {
+	lc, err := s.storage.ConsumeLinkCode(ctx, strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		return nil, err
+	}
+	if lc == nil {
+		return nil, telegram_errors.ErrInvalidLinkCode
+	}
+
+	sub, err := s.GetSubscriberByID(ctx, lc.SubscriberID)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.storage.RegisterTelegramChat(ctx, botName, chatID, chatType, lc.ThreadID, lc.SubscriberID)
+	if err != nil {
+		return nil, err
+	}
+	if ok != true {
+		return nil, telegram_errors.ErrTgChatAlreadyLinked
+	}
+
+	s.auditService.Record(ctx, audit.ActionCreate, audit.TargetTelegramLink, lc.SubscriberID, nil, linkFields(&entity.TelegramSubscriber{
+		SubscriberID: lc.SubscriberID,
+		ChatType:     chatType,
+		BotName:      botName,
+		ThreadID:     threadIDOf(lc),
+		Enabled:      true,
+		Active:       true,
+	}))
+	return sub, nil
+}
+
+func (s *subscriptionService) MigrateTelegramChat(ctx context.Context, botName string, fromChatID int64, toChatID int64) error {
+	ok, err := s.storage.MigrateTelegramChat(ctx, botName, fromChatID, toChatID)
+	if err != nil {
+		return err
+	}
+	if ok != true {
+		return telegram_errors.ErrNoSuchTelegramSubscriber
+	}
+
+	links, err := s.storage.GetTelegramLinksByTelegramID(ctx, botName, toChatID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Errorf("could not audit migration of telegram chat. %s", err.Error())
+		return nil
+	}
+	for _, link := range links {
+		s.auditService.Record(ctx, audit.ActionMigrate, audit.TargetTelegramLink, link.LinkID, nil, linkFields(&link.TelegramSubscriber))
+	}
+	return nil
+}
+
+//Audit log is append-only, so phone numbers and telegram ids written there would outlive erasure of the subscriber.
+//Entries of subscribers and telegram links hold ids and state only
+
+func subscriberFields(sub *entity.Subscriber) audit.Fields {
+	return audit.Fields{
+		"subscriber_id":  sub.SubscriberID,
+		"active":         sub.Active,
+		"deactivated_at": sub.DeactivatedAt,
+	}
+}
+
+func linkFields(link *entity.TelegramSubscriber) audit.Fields {
+	fields := audit.Fields{
+		"subscriber_id":   link.SubscriberID,
+		"chat_type":       link.ChatType,
+		"bot_name":        link.BotName,
+		"thread_id":       link.ThreadID,
+		"enabled":         link.Enabled,
+		"active":          link.Active,
+		"inactive_reason": link.InactiveReason,
+	}
+	//Link id isn't known right after the link is made
+	if link.LinkID != 0 {
+		fields["link_id"] = link.LinkID
+	}
+	return fields
+}
+
+func threadIDOf(lc *entity.LinkCode) int {
+	if lc.ThreadID == nil {
+		return 0
+	}
+	return *lc.ThreadID
+}
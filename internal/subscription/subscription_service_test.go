@@ -0,0 +1,167 @@
+package subscription_test
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/internal/subscription"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/dto"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/response_object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+//fakeStorage keeps a single subscriber with a single telegram link. Methods the test doesn't call panic
+type fakeStorage struct {
+	storage.DBStorage
+	sub  *entity.Subscriber
+	link *response_object.TelegramLinkRO
+}
+
+func (f *fakeStorage) RegisterSubscriber(_ context.Context, phoneNumber string) (uint64, error) {
+	f.sub = &entity.Subscriber{SubscriberID: 1, PhoneNumber: phoneNumber, Active: true}
+	return f.sub.SubscriberID, nil
+}
+
+func (f *fakeStorage) GetSubscribersByIDs(_ context.Context, _ []uint64) ([]*entity.Subscriber, error) {
+	if f.sub == nil {
+		return nil, nil
+	}
+	sub := *f.sub
+	return []*entity.Subscriber{&sub}, nil
+}
+
+func (f *fakeStorage) UpdateSubscriberPhone(_ context.Context, _ uint64, phoneNumber string) (bool, error) {
+	f.sub.PhoneNumber = phoneNumber
+	return true, nil
+}
+
+func (f *fakeStorage) SetSubscriberActive(_ context.Context, _ uint64, active bool) (bool, error) {
+	f.sub.Active = active
+	return true, nil
+}
+
+func (f *fakeStorage) EraseSubscriber(_ context.Context, _ uint64) (bool, error) {
+	f.sub, f.link = nil, nil
+	return true, nil
+}
+
+func (f *fakeStorage) RegisterTelegramSubscriber(_ context.Context, botName string, telegramID int64, subscriberID uint64) (bool, error) {
+	f.link = &response_object.TelegramLinkRO{
+		TelegramSubscriber: entity.TelegramSubscriber{
+			LinkID:       7,
+			SubscriberID: subscriberID,
+			TelegramID:   telegramID,
+			ChatType:     "private",
+			BotName:      botName,
+			Enabled:      true,
+			Active:       true,
+		},
+		PhoneNumber: f.sub.PhoneNumber,
+	}
+	return true, nil
+}
+
+func (f *fakeStorage) GetTelegramLink(_ context.Context, _ uint64) (*response_object.TelegramLinkRO, error) {
+	if f.link == nil {
+		return nil, nil
+	}
+	link := *f.link
+	return &link, nil
+}
+
+func (f *fakeStorage) GetTelegramLinksByTelegramID(ctx context.Context, _ string, _ int64) ([]*response_object.TelegramLinkRO, error) {
+	link, _ := f.GetTelegramLink(ctx, 0)
+	if link == nil {
+		return nil, nil
+	}
+	return []*response_object.TelegramLinkRO{link}, nil
+}
+
+func (f *fakeStorage) SetTelegramLinkEnabled(_ context.Context, _ uint64, enabled bool) (bool, error) {
+	f.link.Enabled = enabled
+	return true, nil
+}
+
+func (f *fakeStorage) DeactivateTelegramChat(_ context.Context, _ string, _ int64, reason string) (bool, error) {
+	f.link.Active, f.link.InactiveReason = false, reason
+	return true, nil
+}
+
+func (f *fakeStorage) ReactivateTelegramChat(_ context.Context, _ string, _ int64) (bool, error) {
+	f.link.Active, f.link.InactiveReason = true, ""
+	return true, nil
+}
+
+func (f *fakeStorage) MigrateTelegramChat(_ context.Context, _ string, _ int64, toChatID int64) (bool, error) {
+	f.link.TelegramID, f.link.ChatType = toChatID, "supergroup"
+	return true, nil
+}
+
+func (f *fakeStorage) DeleteTelegramLink(_ context.Context, _ uint64) (bool, error) {
+	f.link = nil
+	return true, nil
+}
+
+func (f *fakeStorage) DeleteTelegramSubscriber(_ context.Context, _ string, _ int64) (bool, error) {
+	f.link = nil
+	return true, nil
+}
+
+//fakeAuditService keeps entries as they would be stored
+type fakeAuditService struct {
+	entries []string
+}
+
+func (f *fakeAuditService) Record(_ context.Context, action string, targetType string, targetID interface{}, before interface{}, after interface{}) {
+	b, _ := json.Marshal([]interface{}{action, targetType, targetID, before, after})
+	f.entries = append(f.entries, string(b))
+}
+
+func (f *fakeAuditService) GetEntries(_ context.Context, _ entity.AuditFilter) ([]*entity.AuditEntry, uint64, error) {
+	return nil, 0, nil
+}
+
+func TestAuditHoldsNoPersonalData(t *testing.T) {
+	ctx := context.Background()
+	auditService := &fakeAuditService{}
+	service := subscription.NewSubscriptionService(zap.NewNop().Sugar(), &fakeStorage{}, auditService)
+
+	const (
+		phoneNumber    = "+79990001122"
+		newPhoneNumber = "+79993334455"
+		telegramID     = int64(5550001)
+		migratedID     = int64(-1005550002)
+		botName        = "notifier_bot"
+	)
+
+	subscriberID, err := service.RegisterSubscriber(ctx, phoneNumber)
+	require.NoError(t, err)
+
+	newPhone, inactive := newPhoneNumber, false
+	_, err = service.UpdateSubscriber(ctx, subscriberID, dto.UpdateSubscriberInp{PhoneNumber: &newPhone, Active: &inactive})
+	require.NoError(t, err)
+
+	require.NoError(t, service.RegisterTelegramSubscriber(ctx, botName, telegramID, subscriberID))
+	require.NoError(t, service.SetTelegramLinkEnabled(ctx, 7, false))
+	require.NoError(t, service.DeactivateTelegramChat(ctx, botName, telegramID, "blocked"))
+	require.NoError(t, service.ReactivateTelegramChat(ctx, botName, telegramID))
+	require.NoError(t, service.MigrateTelegramChat(ctx, botName, telegramID, migratedID))
+	require.NoError(t, service.UnlinkTelegramSubscriber(ctx, botName, migratedID))
+	require.NoError(t, service.EraseSubscriber(ctx, subscriberID))
+
+	//Every change is audited, erasure included
+	require.Len(t, auditService.entries, 9)
+	assert.Contains(t, auditService.entries[8], `"erase"`)
+
+	for _, e := range auditService.entries {
+		for _, pii := range []string{phoneNumber[1:], newPhoneNumber[1:], strconv.FormatInt(telegramID, 10), strconv.FormatInt(-migratedID, 10)} {
+			assert.NotContains(t, e, pii)
+		}
+	}
+}
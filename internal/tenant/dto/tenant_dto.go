@@ -0,0 +1,10 @@
+package dto
+
+type CreateTenantDto struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type IssueAPIKeyDto struct {
+	Name   string   `json:"name" validate:"required"`
+	Scopes []string `json:"scopes" validate:"required"`
+}
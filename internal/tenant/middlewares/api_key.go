@@ -0,0 +1,92 @@
+package tenant_middlewares
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/pkg/actor"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+	"go.uber.org/zap"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+type apiKeyCtxKey struct{}
+
+//Authenticator resolves api keys, see tenant.Service
+type Authenticator interface {
+	Authenticate(ctx context.Context, apiKey string) (*entity.APIKey, error)
+}
+
+//APIKey resolves tenant of every request by its api key, so every query of the request is scoped to the tenant.
+//Require additionally checks scope of the key per route
+type APIKey struct {
+	logger        *zap.SugaredLogger
+	tenantService Authenticator
+	//skipPrefixes are paths authenticated otherwise, e.g. telegram webhooks and admin routes
+	skipPrefixes []string
+}
+
+func NewAPIKey(logger *zap.SugaredLogger, tenantService Authenticator, skipPrefixes ...string) *APIKey {
+	return &APIKey{
+		logger:        logger,
+		tenantService: tenantService,
+		skipPrefixes:  skipPrefixes,
+	}
+}
+
+//FromContext returns api key the request is authenticated with
+func FromContext(ctx context.Context) (*entity.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyCtxKey{}).(*entity.APIKey)
+	return key, ok
+}
+
+func (m *APIKey) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range m.skipPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				h.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		ctx := r.Context()
+
+		key, err := m.tenantService.Authenticate(ctx, r.Header.Get(APIKeyHeader))
+		if err != nil {
+			logging.FromContext(r.Context(), m.logger).Debug(err.Error())
+			http_errors.MakeErrorResponse(w, err)
+			return
+		}
+
+		ctx = context.WithValue(tenancy.WithID(ctx, key.TenantID), apiKeyCtxKey{}, key)
+		//Changes made by the request are audited as made by the key
+		ctx = actor.With(ctx, actor.Actor{
+			Type: actor.TypeAPIKey,
+			ID:   strconv.FormatUint(key.APIKeyID, 10),
+			Name: key.Name,
+		})
+
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+//Require lets only api keys with scope through
+func (m *APIKey) Require(scope string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		key, ok := FromContext(r.Context())
+		if ok != true || key.HasScope(scope) != true {
+			logging.FromContext(r.Context(), m.logger).Debugf("api key lacks %s scope", scope)
+			http_errors.MakeErrorResponse(w, http_errors.ErrInsufficientScope)
+			return
+		}
+
+		h(w, r, params)
+	}
+}
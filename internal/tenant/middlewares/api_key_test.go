@@ -0,0 +1,80 @@
+package tenant_middlewares_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/tenant/middlewares"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+//fakeTenantService authenticates keys of its map only
+type fakeTenantService struct {
+	keys map[string]*entity.APIKey
+}
+
+func (f *fakeTenantService) Authenticate(_ context.Context, apiKey string) (*entity.APIKey, error) {
+	if apiKey == "" {
+		return nil, http_errors.ErrMissingAPIKey
+	}
+	key, ok := f.keys[apiKey]
+	if ok != true {
+		return nil, http_errors.ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+func TestAPIKey(t *testing.T) {
+
+	service := &fakeTenantService{keys: map[string]*entity.APIKey{
+		"reader": {TenantID: 2, Scopes: []string{entity.ScopeEventsRead}},
+		"firer":  {TenantID: 3, Scopes: []string{entity.ScopeEventsFire}},
+		"admin":  {TenantID: 4, Scopes: []string{entity.ScopeAll}},
+	}}
+	auth := tenant_middlewares.NewAPIKey(zap.NewNop().Sugar(), service, "/api/telegram/webhook")
+
+	var tenantID uint64
+	handle := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		tenantID, _ = tenancy.FromContext(r.Context())
+	}
+
+	router := httprouter.New()
+	router.POST("/api/events/fire/:eventName", auth.Require(entity.ScopeEventsFire, handle))
+	router.POST("/api/telegram/webhook", handle)
+	h := auth.Wrap(router)
+
+	tests := []struct {
+		name             string
+		path             string
+		apiKey           string
+		expectedCode     int
+		expectedTenantID uint64
+	}{
+		{"missing key", "/api/events/fire/worker_login", "", http.StatusUnauthorized, 0},
+		{"unknown key", "/api/events/fire/worker_login", "unknown", http.StatusUnauthorized, 0},
+		{"insufficient scope", "/api/events/fire/worker_login", "reader", http.StatusForbidden, 0},
+		{"scope", "/api/events/fire/worker_login", "firer", http.StatusOK, 3},
+		{"all scopes", "/api/events/fire/worker_login", "admin", http.StatusOK, 4},
+		{"skipped path", "/api/telegram/webhook", "", http.StatusOK, 0},
+	}
+
+	for _, tc := range tests {
+		tenantID = 0
+		req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+		if tc.apiKey != "" {
+			req.Header.Set(tenant_middlewares.APIKeyHeader, tc.apiKey)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expectedCode, w.Code, tc.name)
+		assert.Equal(t, tc.expectedTenantID, tenantID, tc.name)
+	}
+}
@@ -0,0 +1,205 @@
+package tenant
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/delivery-service/pkg/binder"
+	"github.com/sonyamoonglade/notification-service/internal/tenant/dto"
+	"github.com/sonyamoonglade/notification-service/pkg/actor"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/response"
+	"go.uber.org/zap"
+)
+
+//AdminKeyHeader carries ADMIN_API_KEY. Tenant routes aren't scoped to a tenant, so tenant api keys don't work there
+const AdminKeyHeader = "X-Admin-Key"
+
+//RoutesPrefix is excluded from tenant api key check (see tenant_middlewares.APIKey)
+const RoutesPrefix = "/api/tenants"
+
+type Transport interface {
+	CreateTenant(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	GetTenants(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
+	IssueAPIKey(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	GetAPIKeys(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	RevokeAPIKey(w http.ResponseWriter, r *http.Request, params httprouter.Params)
+	InitRoutes(router *httprouter.Router)
+}
+
+type tenantTransport struct {
+	tenantService Service
+	logger        *zap.SugaredLogger
+	adminKey      string
+}
+
+func NewTenantTransport(logger *zap.SugaredLogger, service Service, adminKey string) Transport {
+	return &tenantTransport{
+		logger:        logger,
+		tenantService: service,
+		adminKey:      adminKey,
+	}
+}
+
+//InitRoutes registers nothing unless admin key is configured
+func (t *tenantTransport) InitRoutes(router *httprouter.Router) {
+	if t.adminKey == "" {
+		return
+	}
+	router.POST(RoutesPrefix, t.admin(t.CreateTenant))
+	router.GET(RoutesPrefix, t.admin(t.GetTenants))
+	router.POST(RoutesPrefix+"/:tenantId/api-keys", t.admin(t.IssueAPIKey))
+	router.GET(RoutesPrefix+"/:tenantId/api-keys", t.admin(t.GetAPIKeys))
+	router.DELETE(RoutesPrefix+"/:tenantId/api-keys/:apiKeyId", t.admin(t.RevokeAPIKey))
+}
+
+func (t *tenantTransport) admin(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		key := r.Header.Get(AdminKeyHeader)
+		if key == "" {
+			http_errors.MakeErrorResponse(w, http_errors.ErrMissingAPIKey)
+			logging.FromContext(r.Context(), t.logger).Debug("missing admin key")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(key), []byte(t.adminKey)) != 1 {
+			http_errors.MakeErrorResponse(w, http_errors.ErrInvalidAPIKey)
+			logging.FromContext(r.Context(), t.logger).Warn("invalid admin key")
+			return
+		}
+		h(w, r.WithContext(actor.With(r.Context(), actor.Actor{Type: actor.TypeAdmin})), params)
+	}
+}
+
+func (t *tenantTransport) CreateTenant(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	var inp dto.CreateTenantDto
+
+	err := binder.Bind(r.Body, &inp)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), t.logger).Error(err.Error())
+		return
+	}
+
+	if ValidName(inp.Name) != true {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidPayload)
+		logging.FromContext(r.Context(), t.logger).Debug("invalid tenant name")
+		return
+	}
+
+	tenant, apiKey, err := t.tenantService.CreateTenant(r.Context(), inp.Name)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), t.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(t.logger, w, http.StatusCreated, response.JSON{
+		"tenant":  tenant,
+		"api_key": apiKey,
+	})
+}
+
+func (t *tenantTransport) GetTenants(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	tenants, err := t.tenantService.GetTenants(r.Context())
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), t.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(t.logger, w, http.StatusOK, response.JSON{
+		"tenants": tenants,
+	})
+}
+
+func (t *tenantTransport) IssueAPIKey(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	tenantID, err := parseTenantID(params)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), t.logger).Debug(err.Error())
+		return
+	}
+
+	var inp dto.IssueAPIKeyDto
+
+	err = binder.Bind(r.Body, &inp)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.NewErrInvalidPayload(err))
+		logging.FromContext(r.Context(), t.logger).Error(err.Error())
+		return
+	}
+
+	key, apiKey, err := t.tenantService.IssueAPIKey(r.Context(), tenantID, inp.Name, inp.Scopes)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), t.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(t.logger, w, http.StatusCreated, response.JSON{
+		"api_key":    apiKey,
+		"api_key_id": key.APIKeyID,
+		"scopes":     key.Scopes,
+	})
+}
+
+func (t *tenantTransport) GetAPIKeys(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	tenantID, err := parseTenantID(params)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), t.logger).Debug(err.Error())
+		return
+	}
+
+	keys, err := t.tenantService.GetAPIKeys(r.Context(), tenantID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), t.logger).Error(err.Error())
+		return
+	}
+
+	response.Json(t.logger, w, http.StatusOK, response.JSON{
+		"api_keys": keys,
+	})
+}
+
+func (t *tenantTransport) RevokeAPIKey(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+
+	tenantID, err := parseTenantID(params)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), t.logger).Debug(err.Error())
+		return
+	}
+
+	apiKeyID, err := strconv.ParseUint(params.ByName("apiKeyId"), 10, 64)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, http_errors.ErrInvalidAPIKeyID)
+		logging.FromContext(r.Context(), t.logger).Debug(err.Error())
+		return
+	}
+
+	err = t.tenantService.RevokeAPIKey(r.Context(), tenantID, apiKeyID)
+	if err != nil {
+		http_errors.MakeErrorResponse(w, err)
+		logging.FromContext(r.Context(), t.logger).Error(err.Error())
+		return
+	}
+
+	response.Ok(w)
+}
+
+func parseTenantID(params httprouter.Params) (uint64, error) {
+	tenantID, err := strconv.ParseUint(params.ByName("tenantId"), 10, 64)
+	if err != nil {
+		return 0, http_errors.ErrInvalidTenantID
+	}
+	return tenantID, nil
+}
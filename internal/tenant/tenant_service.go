@@ -0,0 +1,279 @@
+package tenant
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sonyamoonglade/notification-service/internal/audit"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/events"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+	"go.uber.org/zap"
+)
+
+const apiKeyBytes = 32
+
+//bootstrapKeyName is name of the api key issued by Bootstrap
+const bootstrapKeyName = "bootstrap"
+
+//touchInterval is how often usage of api key is recorded. See storage.TouchAPIKey
+const touchInterval = time.Minute
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+type Service interface {
+	CreateTenant(ctx context.Context, name string) (*entity.Tenant, string, error)
+	GetTenants(ctx context.Context) ([]*entity.Tenant, error)
+	GetTenantByName(ctx context.Context, name string) (*entity.Tenant, error)
+	IssueAPIKey(ctx context.Context, tenantID uint64, name string, scopes []string) (*entity.APIKey, string, error)
+	GetAPIKeys(ctx context.Context, tenantID uint64) ([]*entity.APIKey, error)
+	RevokeAPIKey(ctx context.Context, tenantID uint64, apiKeyID uint64) error
+	Authenticate(ctx context.Context, apiKey string) (*entity.APIKey, error)
+	Bootstrap(ctx context.Context, apiKey string) (string, error)
+}
+
+type tenantService struct {
+	storage       storage.DBStorage
+	logger        *zap.SugaredLogger
+	eventsService events.Service
+	auditService  audit.Service
+	//touched is api key id -> when its usage was recorded by this replica, so busy keys don't cost a query per request
+	touchedMu sync.Mutex
+	touched   map[uint64]time.Time
+}
+
+func NewTenantService(logger *zap.SugaredLogger,
+	storage storage.DBStorage,
+	eventsService events.Service,
+	auditService audit.Service) Service {
+	return &tenantService{
+		logger:        logger,
+		storage:       storage,
+		eventsService: eventsService,
+		auditService:  auditService,
+		touched:       make(map[uint64]time.Time),
+	}
+}
+
+//ValidName reports whether name might be used as tenant name, e.g. in bots[].tenant of config
+func ValidName(name string) bool {
+	return nameRegexp.MatchString(name)
+}
+
+//CreateTenant creates tenant with events of events.json and returns its first api key
+func (s *tenantService) CreateTenant(ctx context.Context, name string) (*entity.Tenant, string, error) {
+	tenantID, err := s.storage.CreateTenant(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	if tenantID == 0 {
+		return nil, "", http_errors.ErrTenantAlreadyExists
+	}
+
+	if err := s.eventsService.RegisterCatalog(tenancy.WithID(ctx, tenantID)); err != nil {
+		return nil, "", err
+	}
+
+	_, apiKey, err := s.IssueAPIKey(ctx, tenantID, name, []string{entity.ScopeAll})
+	if err != nil {
+		return nil, "", err
+	}
+
+	t, err := s.storage.GetTenant(ctx, tenantID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.auditService.Record(tenancy.WithID(ctx, tenantID), audit.ActionCreate, audit.TargetTenant, tenantID, nil, t)
+	return t, apiKey, nil
+}
+
+func (s *tenantService) GetTenants(ctx context.Context) ([]*entity.Tenant, error) {
+	tenants, err := s.storage.GetTenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tenants == nil {
+		return []*entity.Tenant{}, nil
+	}
+	return tenants, nil
+}
+
+func (s *tenantService) GetTenantByName(ctx context.Context, name string) (*entity.Tenant, error) {
+	t, err := s.storage.GetTenantByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, http_errors.ErrTenantDoesNotExist
+	}
+	return t, nil
+}
+
+//IssueAPIKey returns new api key of the tenant allowed to access routes of scopes.
+//Only its hash is stored, so the key can't be shown again
+func (s *tenantService) IssueAPIKey(ctx context.Context, tenantID uint64, name string, scopes []string) (*entity.APIKey, string, error) {
+	if err := validateScopes(scopes); err != nil {
+		return nil, "", err
+	}
+
+	if err := s.checkTenant(ctx, tenantID); err != nil {
+		return nil, "", err
+	}
+
+	apiKey, err := newAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := s.storage.CreateAPIKey(ctx, tenantID, name, hashAPIKey(apiKey), scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	//Audit log is per tenant, so changes of api keys are seen by the tenant
+	s.auditService.Record(tenancy.WithID(ctx, tenantID), audit.ActionCreate, audit.TargetAPIKey, key.APIKeyID, nil, key)
+	return key, apiKey, nil
+}
+
+func (s *tenantService) GetAPIKeys(ctx context.Context, tenantID uint64) ([]*entity.APIKey, error) {
+	if err := s.checkTenant(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	keys, err := s.storage.GetAPIKeys(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		return []*entity.APIKey{}, nil
+	}
+	return keys, nil
+}
+
+func (s *tenantService) RevokeAPIKey(ctx context.Context, tenantID uint64, apiKeyID uint64) error {
+	key, err := s.storage.RevokeAPIKey(ctx, tenantID, apiKeyID)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return http_errors.ErrAPIKeyDoesNotExist
+	}
+
+	before := *key
+	before.RevokedAt = nil
+	s.auditService.Record(tenancy.WithID(ctx, tenantID), audit.ActionRevoke, audit.TargetAPIKey, apiKeyID, before, key)
+	return nil
+}
+
+//Authenticate returns not revoked api key and records its usage
+func (s *tenantService) Authenticate(ctx context.Context, apiKey string) (*entity.APIKey, error) {
+	if apiKey == "" {
+		return nil, http_errors.ErrMissingAPIKey
+	}
+	key, err := s.storage.GetAPIKeyByHash(ctx, hashAPIKey(apiKey))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, http_errors.ErrInvalidAPIKey
+	}
+	//Failing to record usage must not fail the request
+	if s.shouldTouch(key.APIKeyID, time.Now()) {
+		if err := s.storage.TouchAPIKey(ctx, key.APIKeyID); err != nil {
+			logging.FromContext(ctx, s.logger).Error(err.Error())
+		}
+	}
+	return key, nil
+}
+
+//shouldTouch reports whether usage of the key hasn't been recorded for touchInterval and marks it recorded at now
+func (s *tenantService) shouldTouch(apiKeyID uint64, now time.Time) bool {
+	s.touchedMu.Lock()
+	defer s.touchedMu.Unlock()
+
+	if last, ok := s.touched[apiKeyID]; ok && now.Sub(last) < touchInterval {
+		return false
+	}
+	s.touched[apiKeyID] = now
+	return true
+}
+
+//Bootstrap issues the first api key of default tenant, so its producers keep working after upgrade to tenants.
+//apiKey is issued if set (see DEFAULT_TENANT_API_KEY), otherwise new key is generated and returned to be shown once.
+//Nothing is issued if default tenant has ever had api keys
+func (s *tenantService) Bootstrap(ctx context.Context, apiKey string) (string, error) {
+	keys, err := s.storage.GetAPIKeys(ctx, tenancy.DefaultID)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) != 0 {
+		return "", nil
+	}
+
+	generated := apiKey == ""
+	if generated {
+		if apiKey, err = newAPIKey(); err != nil {
+			return "", err
+		}
+	}
+
+	key, err := s.storage.CreateAPIKey(ctx, tenancy.DefaultID, bootstrapKeyName, hashAPIKey(apiKey), []string{entity.ScopeAll})
+	if err != nil {
+		return "", err
+	}
+	s.auditService.Record(tenancy.WithID(ctx, tenancy.DefaultID), audit.ActionCreate, audit.TargetAPIKey, key.APIKeyID, nil, key)
+
+	if generated {
+		return apiKey, nil
+	}
+	return "", nil
+}
+
+func (s *tenantService) checkTenant(ctx context.Context, tenantID uint64) error {
+	t, err := s.storage.GetTenant(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return http_errors.ErrTenantDoesNotExist
+	}
+	return nil
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return http_errors.ErrInvalidScope
+	}
+	known := make(map[string]bool, len(entity.Scopes))
+	for _, scope := range entity.Scopes {
+		known[scope] = true
+	}
+	for _, scope := range scopes {
+		if known[scope] != true {
+			return fmt.Errorf("%w: %s", http_errors.ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
+
+func newAPIKey() (string, error) {
+	buf := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
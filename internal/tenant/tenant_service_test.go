@@ -0,0 +1,40 @@
+package tenant_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/storage"
+	"github.com/sonyamoonglade/notification-service/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+//touchStorage knows every api key and counts usage records
+type touchStorage struct {
+	storage.DBStorage
+	touches map[uint64]int
+}
+
+func (f *touchStorage) GetAPIKeyByHash(_ context.Context, _ string) (*entity.APIKey, error) {
+	return &entity.APIKey{APIKeyID: 1, TenantID: 1, Scopes: []string{entity.ScopeAll}}, nil
+}
+
+func (f *touchStorage) TouchAPIKey(_ context.Context, apiKeyID uint64) error {
+	f.touches[apiKeyID]++
+	return nil
+}
+
+func TestAuthenticateRecordsUsageOncePerInterval(t *testing.T) {
+	db := &touchStorage{touches: map[uint64]int{}}
+	service := tenant.NewTenantService(zap.NewNop().Sugar(), db, nil, nil)
+
+	for i := 0; i < 100; i++ {
+		_, err := service.Authenticate(context.Background(), "key")
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, db.touches[1])
+}
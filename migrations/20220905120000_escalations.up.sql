@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS "escalations"(
+    "escalation_id" SERIAL PRIMARY KEY,
+    "event_id" INTEGER NOT NULL,
+    "text" TEXT NOT NULL,
+    "tier" INTEGER NOT NULL DEFAULT 0,
+    "status" varchar(32) NOT NULL DEFAULT 'pending',
+    "acknowledged_by" BIGINT,
+    "acknowledged_at" TIMESTAMPTZ,
+    "next_escalation_at" TIMESTAMPTZ,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "escalations" ADD CONSTRAINT "escalation_event_id_fk"
+    FOREIGN KEY("event_id")
+    REFERENCES events("event_id")
+    ON DELETE CASCADE;
+
+-- Worker polls pending escalations by deadline
+CREATE INDEX IF NOT EXISTS "escalations_pending_idx"
+    ON "escalations"("next_escalation_at") WHERE "status" = 'pending';
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS "groups"(
+    "group_id" SERIAL PRIMARY KEY,
+    "name" varchar(255) UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "group_members"(
+    "group_id" INTEGER NOT NULL,
+    "subscriber_id" INTEGER NOT NULL
+);
+
+ALTER TABLE "group_members" ADD CONSTRAINT "group_members_group_id_fk"
+    FOREIGN KEY("group_id")
+    REFERENCES groups("group_id")
+    ON DELETE CASCADE;
+
+ALTER TABLE "group_members" ADD CONSTRAINT "group_members_subscriber_id_fk"
+    FOREIGN KEY("subscriber_id")
+    REFERENCES subscribers("subscriber_id")
+    ON DELETE CASCADE;
+
+ALTER TABLE "group_members" ADD CONSTRAINT "group_id_sub_id_unique"
+    UNIQUE("group_id","subscriber_id");
+
+CREATE TABLE IF NOT EXISTS "group_subscriptions"(
+    "group_id" INTEGER NOT NULL,
+    "event_id" INTEGER NOT NULL
+);
+
+ALTER TABLE "group_subscriptions" ADD CONSTRAINT "group_subscriptions_group_id_fk"
+    FOREIGN KEY("group_id")
+    REFERENCES groups("group_id")
+    ON DELETE CASCADE;
+
+ALTER TABLE "group_subscriptions" ADD CONSTRAINT "group_subscriptions_event_id_fk"
+    FOREIGN KEY("event_id")
+    REFERENCES events("event_id")
+    ON DELETE CASCADE;
+
+-- Make sure on database level that one group cant subscribe to the same event multiple times.
+ALTER TABLE "group_subscriptions" ADD CONSTRAINT "group_id_event_id_unique"
+    UNIQUE("group_id","event_id");
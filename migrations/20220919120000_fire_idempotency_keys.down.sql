@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "fire_idempotency_keys";
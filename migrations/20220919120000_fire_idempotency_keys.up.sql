@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS "fire_idempotency_keys"(
+    "key" varchar(255) PRIMARY KEY,
+    "event_id" INTEGER NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "fire_idempotency_keys" ADD CONSTRAINT "idempotency_event_id_fk"
+    FOREIGN KEY("event_id")
+    REFERENCES events("event_id")
+    ON DELETE CASCADE;
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS "mutes"(
+    "mute_id" SERIAL PRIMARY KEY,
+    "subscriber_id" INTEGER NOT NULL,
+    -- NULL event_id mutes every event
+    "event_id" INTEGER,
+    "muted_until" TIMESTAMPTZ NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "mutes" ADD CONSTRAINT "mutes_subscriber_id_fk"
+    FOREIGN KEY("subscriber_id")
+    REFERENCES subscribers("subscriber_id")
+    ON DELETE CASCADE;
+
+ALTER TABLE "mutes" ADD CONSTRAINT "mutes_event_id_fk"
+    FOREIGN KEY("event_id")
+    REFERENCES events("event_id")
+    ON DELETE CASCADE;
+
+-- One mute window per subscriber and event (or all events)
+CREATE UNIQUE INDEX IF NOT EXISTS "mutes_subscriber_event_unique"
+    ON "mutes"("subscriber_id", (COALESCE("event_id", 0)));
+
+CREATE TABLE IF NOT EXISTS "deliveries"(
+    "delivery_id" SERIAL PRIMARY KEY,
+    "event_id" INTEGER NOT NULL,
+    "subscriber_id" INTEGER NOT NULL,
+    "status" varchar(32) NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "deliveries" ADD CONSTRAINT "deliveries_event_id_fk"
+    FOREIGN KEY("event_id")
+    REFERENCES events("event_id")
+    ON DELETE CASCADE;
+
+ALTER TABLE "deliveries" ADD CONSTRAINT "deliveries_subscriber_id_fk"
+    FOREIGN KEY("subscriber_id")
+    REFERENCES subscribers("subscriber_id")
+    ON DELETE CASCADE;
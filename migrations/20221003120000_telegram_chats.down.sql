@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "link_codes";
+ALTER TABLE "telegram_subscribers" DROP COLUMN IF EXISTS "thread_id";
+ALTER TABLE "telegram_subscribers" DROP COLUMN IF EXISTS "chat_type";
@@ -0,0 +1,16 @@
+ALTER TABLE "telegram_subscribers" ADD COLUMN IF NOT EXISTS "chat_type" varchar(32) NOT NULL DEFAULT 'private';
+-- Forum topic of supergroup notifications are sent to
+ALTER TABLE "telegram_subscribers" ADD COLUMN IF NOT EXISTS "thread_id" INTEGER;
+
+CREATE TABLE IF NOT EXISTS "link_codes"(
+    "code" varchar(32) PRIMARY KEY,
+    "subscriber_id" INTEGER NOT NULL,
+    "thread_id" INTEGER,
+    "expires_at" TIMESTAMPTZ NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "link_codes" ADD CONSTRAINT "link_codes_subscriber_id_fk"
+    FOREIGN KEY("subscriber_id")
+    REFERENCES subscribers("subscriber_id")
+    ON DELETE CASCADE;
@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS "telegram_subscribers_subscriber_id_idx";
+ALTER TABLE "telegram_subscribers" DROP CONSTRAINT IF EXISTS "telegram_id_subscriber_id_unique";
+
+-- Keep the oldest link of each telegram account and subscriber, so one-to-one constraints can be restored
+DELETE FROM "telegram_subscribers" a USING "telegram_subscribers" b
+    WHERE a.link_id > b.link_id AND (a.telegram_id = b.telegram_id OR a.subscriber_id = b.subscriber_id);
+
+ALTER TABLE "telegram_subscribers" DROP COLUMN IF EXISTS "created_at";
+ALTER TABLE "telegram_subscribers" DROP COLUMN IF EXISTS "enabled";
+ALTER TABLE "telegram_subscribers" DROP COLUMN IF EXISTS "link_id";
+
+ALTER TABLE "telegram_subscribers" ADD CONSTRAINT "teleg_id_unqiue"
+    UNIQUE("subscriber_id");
+
+ALTER TABLE "telegram_subscribers" ADD CONSTRAINT "subscriber_id_unique"
+    UNIQUE("telegram_id");
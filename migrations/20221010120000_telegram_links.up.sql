@@ -0,0 +1,13 @@
+-- One subscriber might receive notifications in many telegram accounts and one account might receive them for many subscribers
+ALTER TABLE "telegram_subscribers" DROP CONSTRAINT IF EXISTS "teleg_id_unqiue";
+ALTER TABLE "telegram_subscribers" DROP CONSTRAINT IF EXISTS "subscriber_id_unique";
+
+ALTER TABLE "telegram_subscribers" ADD COLUMN IF NOT EXISTS "link_id" SERIAL PRIMARY KEY;
+-- Disabled links are kept, but nothing is sent through them
+ALTER TABLE "telegram_subscribers" ADD COLUMN IF NOT EXISTS "enabled" BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE "telegram_subscribers" ADD COLUMN IF NOT EXISTS "created_at" TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE "telegram_subscribers" ADD CONSTRAINT "telegram_id_subscriber_id_unique"
+    UNIQUE("telegram_id","subscriber_id");
+
+CREATE INDEX IF NOT EXISTS "telegram_subscribers_subscriber_id_idx" ON "telegram_subscribers"("subscriber_id");
@@ -0,0 +1,3 @@
+ALTER TABLE "telegram_subscribers" DROP COLUMN IF EXISTS "inactive_since";
+ALTER TABLE "telegram_subscribers" DROP COLUMN IF EXISTS "inactive_reason";
+ALTER TABLE "telegram_subscribers" DROP COLUMN IF EXISTS "active";
@@ -0,0 +1,4 @@
+-- Link becomes inactive when telegram refuses to deliver to the chat, e.g. user has blocked the bot
+ALTER TABLE "telegram_subscribers" ADD COLUMN IF NOT EXISTS "active" BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE "telegram_subscribers" ADD COLUMN IF NOT EXISTS "inactive_reason" varchar(32);
+ALTER TABLE "telegram_subscribers" ADD COLUMN IF NOT EXISTS "inactive_since" TIMESTAMPTZ;
@@ -0,0 +1,8 @@
+ALTER TABLE "telegram_subscribers" DROP CONSTRAINT IF EXISTS "bot_name_telegram_id_subscriber_id_unique";
+
+-- Links of other bots can't be kept without bot_name
+DELETE FROM "telegram_subscribers" WHERE "bot_name" != 'default';
+ALTER TABLE "telegram_subscribers" DROP COLUMN IF EXISTS "bot_name";
+
+ALTER TABLE "telegram_subscribers" ADD CONSTRAINT "telegram_id_subscriber_id_unique"
+    UNIQUE("telegram_id","subscriber_id");
@@ -0,0 +1,6 @@
+-- Telegram chat ids are per bot, so every link belongs to the bot it was made with
+ALTER TABLE "telegram_subscribers" ADD COLUMN IF NOT EXISTS "bot_name" varchar(64) NOT NULL DEFAULT 'default';
+
+ALTER TABLE "telegram_subscribers" DROP CONSTRAINT IF EXISTS "telegram_id_subscriber_id_unique";
+ALTER TABLE "telegram_subscribers" ADD CONSTRAINT "bot_name_telegram_id_subscriber_id_unique"
+    UNIQUE("bot_name","telegram_id","subscriber_id");
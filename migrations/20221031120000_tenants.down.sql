@@ -0,0 +1,95 @@
+-- Only the default tenant can be kept without tenant_id. Rows of other tenants are deleted by cascade
+DELETE FROM "tenants" WHERE "tenant_id" != 1;
+
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['events', 'subscribers', 'subscriptions', 'telegram_subscribers', 'link_codes',
+        'escalations', 'groups', 'group_members', 'group_subscriptions', 'fire_idempotency_keys', 'mutes', 'deliveries']
+    LOOP
+        EXECUTE format('DROP POLICY IF EXISTS "tenant_isolation" ON %I', t);
+        EXECUTE format('ALTER TABLE %I NO FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE %I DISABLE ROW LEVEL SECURITY', t);
+    END LOOP;
+END $$;
+
+DROP TABLE IF EXISTS "templates";
+
+ALTER TABLE "subscriptions" DROP CONSTRAINT IF EXISTS "event_id_fk";
+ALTER TABLE "escalations" DROP CONSTRAINT IF EXISTS "escalation_event_id_fk";
+ALTER TABLE "group_subscriptions" DROP CONSTRAINT IF EXISTS "group_subscriptions_event_id_fk";
+ALTER TABLE "fire_idempotency_keys" DROP CONSTRAINT IF EXISTS "idempotency_event_id_fk";
+ALTER TABLE "mutes" DROP CONSTRAINT IF EXISTS "mutes_event_id_fk";
+ALTER TABLE "deliveries" DROP CONSTRAINT IF EXISTS "deliveries_event_id_fk";
+ALTER TABLE "subscriptions" DROP CONSTRAINT IF EXISTS "subscriber_id_fk";
+ALTER TABLE "group_members" DROP CONSTRAINT IF EXISTS "group_members_subscriber_id_fk";
+ALTER TABLE "mutes" DROP CONSTRAINT IF EXISTS "mutes_subscriber_id_fk";
+ALTER TABLE "deliveries" DROP CONSTRAINT IF EXISTS "deliveries_subscriber_id_fk";
+ALTER TABLE "link_codes" DROP CONSTRAINT IF EXISTS "link_codes_subscriber_id_fk";
+ALTER TABLE "group_members" DROP CONSTRAINT IF EXISTS "group_members_group_id_fk";
+ALTER TABLE "group_subscriptions" DROP CONSTRAINT IF EXISTS "group_subscriptions_group_id_fk";
+
+ALTER TABLE "events" DROP CONSTRAINT IF EXISTS "events_pkey";
+ALTER TABLE "events" DROP CONSTRAINT IF EXISTS "events_tenant_id_name_unique";
+ALTER TABLE "events" DROP CONSTRAINT IF EXISTS "events_tenant_id_translate_unique";
+ALTER TABLE "subscribers" DROP CONSTRAINT IF EXISTS "subscribers_tenant_id_phone_number_unique";
+ALTER TABLE "subscribers" DROP CONSTRAINT IF EXISTS "subscribers_tenant_id_subscriber_id_unique";
+ALTER TABLE "groups" DROP CONSTRAINT IF EXISTS "groups_tenant_id_name_unique";
+ALTER TABLE "groups" DROP CONSTRAINT IF EXISTS "groups_tenant_id_group_id_unique";
+ALTER TABLE "fire_idempotency_keys" DROP CONSTRAINT IF EXISTS "fire_idempotency_keys_pkey";
+
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['events', 'subscribers', 'subscriptions', 'telegram_subscribers', 'link_codes',
+        'escalations', 'groups', 'group_members', 'group_subscriptions', 'fire_idempotency_keys', 'mutes', 'deliveries']
+    LOOP
+        EXECUTE format('ALTER TABLE %I DROP COLUMN IF EXISTS "tenant_id"', t);
+    END LOOP;
+END $$;
+
+ALTER TABLE "events" ADD PRIMARY KEY ("event_id");
+ALTER TABLE "events" ADD CONSTRAINT "events_name_key" UNIQUE("name");
+ALTER TABLE "events" ADD CONSTRAINT "events_translate_key" UNIQUE("translate");
+ALTER TABLE "subscribers" ADD CONSTRAINT "subscribers_phone_number_key" UNIQUE("phone_number");
+ALTER TABLE "groups" ADD CONSTRAINT "groups_name_key" UNIQUE("name");
+ALTER TABLE "fire_idempotency_keys" ADD PRIMARY KEY ("key");
+
+ALTER TABLE "subscriptions" ADD CONSTRAINT "event_id_fk"
+    FOREIGN KEY("event_id") REFERENCES events("event_id") ON DELETE CASCADE;
+ALTER TABLE "escalations" ADD CONSTRAINT "escalation_event_id_fk"
+    FOREIGN KEY("event_id") REFERENCES events("event_id") ON DELETE CASCADE;
+ALTER TABLE "group_subscriptions" ADD CONSTRAINT "group_subscriptions_event_id_fk"
+    FOREIGN KEY("event_id") REFERENCES events("event_id") ON DELETE CASCADE;
+ALTER TABLE "fire_idempotency_keys" ADD CONSTRAINT "idempotency_event_id_fk"
+    FOREIGN KEY("event_id") REFERENCES events("event_id") ON DELETE CASCADE;
+ALTER TABLE "mutes" ADD CONSTRAINT "mutes_event_id_fk"
+    FOREIGN KEY("event_id") REFERENCES events("event_id") ON DELETE CASCADE;
+ALTER TABLE "deliveries" ADD CONSTRAINT "deliveries_event_id_fk"
+    FOREIGN KEY("event_id") REFERENCES events("event_id") ON DELETE CASCADE;
+ALTER TABLE "subscriptions" ADD CONSTRAINT "subscriber_id_fk"
+    FOREIGN KEY("subscriber_id") REFERENCES subscribers("subscriber_id") ON DELETE CASCADE;
+ALTER TABLE "group_members" ADD CONSTRAINT "group_members_subscriber_id_fk"
+    FOREIGN KEY("subscriber_id") REFERENCES subscribers("subscriber_id") ON DELETE CASCADE;
+ALTER TABLE "mutes" ADD CONSTRAINT "mutes_subscriber_id_fk"
+    FOREIGN KEY("subscriber_id") REFERENCES subscribers("subscriber_id") ON DELETE CASCADE;
+ALTER TABLE "deliveries" ADD CONSTRAINT "deliveries_subscriber_id_fk"
+    FOREIGN KEY("subscriber_id") REFERENCES subscribers("subscriber_id") ON DELETE CASCADE;
+ALTER TABLE "link_codes" ADD CONSTRAINT "link_codes_subscriber_id_fk"
+    FOREIGN KEY("subscriber_id") REFERENCES subscribers("subscriber_id") ON DELETE CASCADE;
+ALTER TABLE "group_members" ADD CONSTRAINT "group_members_group_id_fk"
+    FOREIGN KEY("group_id") REFERENCES groups("group_id") ON DELETE CASCADE;
+ALTER TABLE "group_subscriptions" ADD CONSTRAINT "group_subscriptions_group_id_fk"
+    FOREIGN KEY("group_id") REFERENCES groups("group_id") ON DELETE CASCADE;
+
+DROP FUNCTION IF EXISTS current_tenant_id();
+DROP TABLE IF EXISTS "api_keys";
+DROP TABLE IF EXISTS "tenants";
+
+REVOKE ALL ON ALL TABLES IN SCHEMA public FROM notification_tenant;
+REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM notification_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE ALL ON TABLES FROM notification_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE ALL ON SEQUENCES FROM notification_tenant;
+DROP ROLE IF EXISTS notification_tenant;
@@ -0,0 +1,142 @@
+CREATE TABLE IF NOT EXISTS "tenants"(
+    "tenant_id" SERIAL PRIMARY KEY,
+    "name" varchar(64) UNIQUE NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Everything created before tenants belongs to the default tenant
+INSERT INTO "tenants" ("tenant_id", "name") VALUES (1, 'default') ON CONFLICT DO NOTHING;
+SELECT setval(pg_get_serial_sequence('tenants', 'tenant_id'), (SELECT max("tenant_id") FROM "tenants"));
+
+-- Tenant of http request is resolved by api key. Only sha256 of the key is stored
+CREATE TABLE IF NOT EXISTS "api_keys"(
+    "api_key_id" SERIAL PRIMARY KEY,
+    "tenant_id" INTEGER NOT NULL REFERENCES tenants("tenant_id") ON DELETE CASCADE,
+    "key_hash" varchar(64) UNIQUE NOT NULL,
+    "name" varchar(255) NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Tenant of the session is set by the application on every connection acquire (see pkg/postgres)
+CREATE OR REPLACE FUNCTION current_tenant_id() RETURNS INTEGER AS $$
+    SELECT NULLIF(current_setting('app.tenant_id', true), '')::INTEGER
+$$ LANGUAGE SQL STABLE;
+
+-- Per tenant overrides of templates.json
+CREATE TABLE IF NOT EXISTS "templates"(
+    "tenant_id" INTEGER NOT NULL DEFAULT current_tenant_id() REFERENCES tenants("tenant_id") ON DELETE CASCADE,
+    "event_id" INTEGER NOT NULL,
+    "text" TEXT NOT NULL,
+    "updated_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("tenant_id", "event_id")
+);
+
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['events', 'subscribers', 'subscriptions', 'telegram_subscribers', 'link_codes',
+        'escalations', 'groups', 'group_members', 'group_subscriptions', 'fire_idempotency_keys', 'mutes', 'deliveries']
+    LOOP
+        EXECUTE format('ALTER TABLE %I ADD COLUMN IF NOT EXISTS "tenant_id" INTEGER NOT NULL DEFAULT 1
+            REFERENCES tenants("tenant_id") ON DELETE CASCADE', t);
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN "tenant_id" SET DEFAULT current_tenant_id()', t);
+    END LOOP;
+END $$;
+
+-- Event ids come from events.json and are the same in every tenant, so events are identified by tenant and id
+ALTER TABLE "subscriptions" DROP CONSTRAINT IF EXISTS "event_id_fk";
+ALTER TABLE "escalations" DROP CONSTRAINT IF EXISTS "escalation_event_id_fk";
+ALTER TABLE "group_subscriptions" DROP CONSTRAINT IF EXISTS "group_subscriptions_event_id_fk";
+ALTER TABLE "fire_idempotency_keys" DROP CONSTRAINT IF EXISTS "idempotency_event_id_fk";
+ALTER TABLE "mutes" DROP CONSTRAINT IF EXISTS "mutes_event_id_fk";
+ALTER TABLE "deliveries" DROP CONSTRAINT IF EXISTS "deliveries_event_id_fk";
+
+ALTER TABLE "events" DROP CONSTRAINT IF EXISTS "events_event_id_key";
+ALTER TABLE "events" DROP CONSTRAINT IF EXISTS "events_pkey";
+ALTER TABLE "events" DROP CONSTRAINT IF EXISTS "events_name_key";
+ALTER TABLE "events" DROP CONSTRAINT IF EXISTS "events_translate_key";
+ALTER TABLE "events" ADD PRIMARY KEY ("tenant_id", "event_id");
+ALTER TABLE "events" ADD CONSTRAINT "events_tenant_id_name_unique" UNIQUE("tenant_id", "name");
+ALTER TABLE "events" ADD CONSTRAINT "events_tenant_id_translate_unique" UNIQUE("tenant_id", "translate");
+
+-- Subscribers and groups might reference rows of the same tenant only
+ALTER TABLE "subscriptions" DROP CONSTRAINT IF EXISTS "subscriber_id_fk";
+ALTER TABLE "group_members" DROP CONSTRAINT IF EXISTS "group_members_subscriber_id_fk";
+ALTER TABLE "mutes" DROP CONSTRAINT IF EXISTS "mutes_subscriber_id_fk";
+ALTER TABLE "deliveries" DROP CONSTRAINT IF EXISTS "deliveries_subscriber_id_fk";
+ALTER TABLE "link_codes" DROP CONSTRAINT IF EXISTS "link_codes_subscriber_id_fk";
+ALTER TABLE "group_members" DROP CONSTRAINT IF EXISTS "group_members_group_id_fk";
+ALTER TABLE "group_subscriptions" DROP CONSTRAINT IF EXISTS "group_subscriptions_group_id_fk";
+
+ALTER TABLE "subscribers" DROP CONSTRAINT IF EXISTS "subscribers_phone_number_key";
+ALTER TABLE "subscribers" ADD CONSTRAINT "subscribers_tenant_id_phone_number_unique" UNIQUE("tenant_id", "phone_number");
+ALTER TABLE "subscribers" ADD CONSTRAINT "subscribers_tenant_id_subscriber_id_unique" UNIQUE("tenant_id", "subscriber_id");
+
+ALTER TABLE "groups" DROP CONSTRAINT IF EXISTS "groups_name_key";
+ALTER TABLE "groups" ADD CONSTRAINT "groups_tenant_id_name_unique" UNIQUE("tenant_id", "name");
+ALTER TABLE "groups" ADD CONSTRAINT "groups_tenant_id_group_id_unique" UNIQUE("tenant_id", "group_id");
+
+ALTER TABLE "fire_idempotency_keys" DROP CONSTRAINT IF EXISTS "fire_idempotency_keys_pkey";
+ALTER TABLE "fire_idempotency_keys" ADD PRIMARY KEY ("tenant_id", "key");
+
+ALTER TABLE "subscriptions" ADD CONSTRAINT "event_id_fk"
+    FOREIGN KEY("tenant_id", "event_id") REFERENCES events("tenant_id", "event_id") ON DELETE CASCADE;
+ALTER TABLE "escalations" ADD CONSTRAINT "escalation_event_id_fk"
+    FOREIGN KEY("tenant_id", "event_id") REFERENCES events("tenant_id", "event_id") ON DELETE CASCADE;
+ALTER TABLE "group_subscriptions" ADD CONSTRAINT "group_subscriptions_event_id_fk"
+    FOREIGN KEY("tenant_id", "event_id") REFERENCES events("tenant_id", "event_id") ON DELETE CASCADE;
+ALTER TABLE "fire_idempotency_keys" ADD CONSTRAINT "idempotency_event_id_fk"
+    FOREIGN KEY("tenant_id", "event_id") REFERENCES events("tenant_id", "event_id") ON DELETE CASCADE;
+ALTER TABLE "mutes" ADD CONSTRAINT "mutes_event_id_fk"
+    FOREIGN KEY("tenant_id", "event_id") REFERENCES events("tenant_id", "event_id") ON DELETE CASCADE;
+ALTER TABLE "deliveries" ADD CONSTRAINT "deliveries_event_id_fk"
+    FOREIGN KEY("tenant_id", "event_id") REFERENCES events("tenant_id", "event_id") ON DELETE CASCADE;
+ALTER TABLE "templates" ADD CONSTRAINT "templates_event_id_fk"
+    FOREIGN KEY("tenant_id", "event_id") REFERENCES events("tenant_id", "event_id") ON DELETE CASCADE;
+
+ALTER TABLE "subscriptions" ADD CONSTRAINT "subscriber_id_fk"
+    FOREIGN KEY("tenant_id", "subscriber_id") REFERENCES subscribers("tenant_id", "subscriber_id") ON DELETE CASCADE;
+ALTER TABLE "group_members" ADD CONSTRAINT "group_members_subscriber_id_fk"
+    FOREIGN KEY("tenant_id", "subscriber_id") REFERENCES subscribers("tenant_id", "subscriber_id") ON DELETE CASCADE;
+ALTER TABLE "mutes" ADD CONSTRAINT "mutes_subscriber_id_fk"
+    FOREIGN KEY("tenant_id", "subscriber_id") REFERENCES subscribers("tenant_id", "subscriber_id") ON DELETE CASCADE;
+ALTER TABLE "deliveries" ADD CONSTRAINT "deliveries_subscriber_id_fk"
+    FOREIGN KEY("tenant_id", "subscriber_id") REFERENCES subscribers("tenant_id", "subscriber_id") ON DELETE CASCADE;
+ALTER TABLE "link_codes" ADD CONSTRAINT "link_codes_subscriber_id_fk"
+    FOREIGN KEY("tenant_id", "subscriber_id") REFERENCES subscribers("tenant_id", "subscriber_id") ON DELETE CASCADE;
+ALTER TABLE "group_members" ADD CONSTRAINT "group_members_group_id_fk"
+    FOREIGN KEY("tenant_id", "group_id") REFERENCES groups("tenant_id", "group_id") ON DELETE CASCADE;
+ALTER TABLE "group_subscriptions" ADD CONSTRAINT "group_subscriptions_group_id_fk"
+    FOREIGN KEY("tenant_id", "group_id") REFERENCES groups("tenant_id", "group_id") ON DELETE CASCADE;
+
+-- Defense in depth: even a query missing tenant condition sees rows of the session tenant only.
+-- Session without tenant sees nothing
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['events', 'subscribers', 'subscriptions', 'telegram_subscribers', 'link_codes', 'templates',
+        'escalations', 'groups', 'group_members', 'group_subscriptions', 'fire_idempotency_keys', 'mutes', 'deliveries']
+    LOOP
+        EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format('DROP POLICY IF EXISTS "tenant_isolation" ON %I', t);
+        EXECUTE format('CREATE POLICY "tenant_isolation" ON %I
+            USING ("tenant_id" = current_tenant_id()) WITH CHECK ("tenant_id" = current_tenant_id())', t);
+    END LOOP;
+END $$;
+
+-- Superusers bypass row level security, so the application switches to this role on every connection acquire
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'notification_tenant') THEN
+        CREATE ROLE notification_tenant NOLOGIN;
+    END IF;
+END $$;
+
+GRANT notification_tenant TO CURRENT_USER;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO notification_tenant;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO notification_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO notification_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO notification_tenant;
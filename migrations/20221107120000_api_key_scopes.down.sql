@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "api_keys_tenant_id_idx";
+
+-- Revoked keys would become valid again without revoked_at
+DELETE FROM "api_keys" WHERE "revoked_at" IS NOT NULL;
+ALTER TABLE "api_keys" DROP COLUMN IF EXISTS "revoked_at";
+ALTER TABLE "api_keys" DROP COLUMN IF EXISTS "last_used_at";
+ALTER TABLE "api_keys" DROP COLUMN IF EXISTS "scopes";
@@ -0,0 +1,8 @@
+-- Keys issued before scopes keep full access
+ALTER TABLE "api_keys" ADD COLUMN IF NOT EXISTS "scopes" varchar(64)[] NOT NULL DEFAULT '{*}';
+ALTER TABLE "api_keys" ALTER COLUMN "scopes" DROP DEFAULT;
+ALTER TABLE "api_keys" ADD COLUMN IF NOT EXISTS "last_used_at" TIMESTAMPTZ;
+-- Revoked keys are kept, so it's visible what was revoked and when
+ALTER TABLE "api_keys" ADD COLUMN IF NOT EXISTS "revoked_at" TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS "api_keys_tenant_id_idx" ON "api_keys"("tenant_id");
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS "audit_log"(
+    "audit_id" BIGSERIAL PRIMARY KEY,
+    "tenant_id" INTEGER NOT NULL DEFAULT current_tenant_id() REFERENCES tenants("tenant_id") ON DELETE CASCADE,
+    "actor_type" varchar(16) NOT NULL,
+    "actor_id" varchar(64) NOT NULL DEFAULT '',
+    "actor_name" varchar(255) NOT NULL DEFAULT '',
+    "action" varchar(32) NOT NULL,
+    "target_type" varchar(32) NOT NULL,
+    "target_id" varchar(64) NOT NULL DEFAULT '',
+    "before" JSONB,
+    "after" JSONB,
+    "request_id" varchar(128) NOT NULL DEFAULT '',
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS "audit_log_tenant_id_audit_id_idx" ON "audit_log" ("tenant_id", "audit_id" DESC);
+CREATE INDEX IF NOT EXISTS "audit_log_target_idx" ON "audit_log" ("tenant_id", "target_type", "target_id");
+
+ALTER TABLE "audit_log" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "audit_log" FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS "tenant_isolation" ON "audit_log";
+CREATE POLICY "tenant_isolation" ON "audit_log"
+    USING ("tenant_id" = current_tenant_id()) WITH CHECK ("tenant_id" = current_tenant_id());
+
+-- Append-only: the application might only add entries and read them
+REVOKE UPDATE, DELETE, TRUNCATE ON "audit_log" FROM notification_tenant;
+GRANT SELECT, INSERT ON "audit_log" TO notification_tenant;
+GRANT USAGE, SELECT ON SEQUENCE "audit_log_audit_id_seq" TO notification_tenant;
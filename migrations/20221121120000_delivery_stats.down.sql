@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS "deliveries_fire_id_idx";
+DROP INDEX IF EXISTS "deliveries_tenant_id_created_at_idx";
+
+ALTER TABLE "deliveries" DROP COLUMN IF EXISTS "latency_ms";
+ALTER TABLE "deliveries" DROP COLUMN IF EXISTS "error";
+ALTER TABLE "deliveries" DROP COLUMN IF EXISTS "telegram_id";
+ALTER TABLE "deliveries" DROP COLUMN IF EXISTS "channel";
+ALTER TABLE "deliveries" DROP COLUMN IF EXISTS "fire_id";
+
+DROP TABLE IF EXISTS "fires";
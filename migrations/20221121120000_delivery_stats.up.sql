@@ -0,0 +1,35 @@
+-- Every fire of an event, so stats could tell fires apart from deliveries
+CREATE TABLE IF NOT EXISTS "fires"(
+    "fire_id" BIGSERIAL PRIMARY KEY,
+    "tenant_id" INTEGER NOT NULL DEFAULT current_tenant_id() REFERENCES tenants("tenant_id") ON DELETE CASCADE,
+    "event_id" INTEGER NOT NULL,
+    "status" varchar(32) NOT NULL,
+    "recipients" INTEGER NOT NULL DEFAULT 0,
+    "request_id" varchar(128) NOT NULL DEFAULT '',
+    "fired_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "fires" ADD CONSTRAINT "fires_event_id_fk"
+    FOREIGN KEY("tenant_id", "event_id") REFERENCES events("tenant_id", "event_id") ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS "fires_tenant_id_fired_at_idx" ON "fires" ("tenant_id", "fired_at");
+
+ALTER TABLE "fires" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "fires" FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS "tenant_isolation" ON "fires";
+CREATE POLICY "tenant_isolation" ON "fires"
+    USING ("tenant_id" = current_tenant_id()) WITH CHECK ("tenant_id" = current_tenant_id());
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON "fires" TO notification_tenant;
+GRANT USAGE, SELECT ON SEQUENCE "fires_fire_id_seq" TO notification_tenant;
+
+-- Deliveries made before are kept without fire, channel and latency
+ALTER TABLE "deliveries" ADD COLUMN IF NOT EXISTS "fire_id" BIGINT REFERENCES fires("fire_id") ON DELETE CASCADE;
+ALTER TABLE "deliveries" ADD COLUMN IF NOT EXISTS "channel" varchar(16) NOT NULL DEFAULT 'telegram';
+ALTER TABLE "deliveries" ADD COLUMN IF NOT EXISTS "telegram_id" BIGINT;
+ALTER TABLE "deliveries" ADD COLUMN IF NOT EXISTS "error" TEXT NOT NULL DEFAULT '';
+-- Time from fire to telegram accepting the message
+ALTER TABLE "deliveries" ADD COLUMN IF NOT EXISTS "latency_ms" INTEGER;
+
+CREATE INDEX IF NOT EXISTS "deliveries_tenant_id_created_at_idx" ON "deliveries" ("tenant_id", "created_at");
+CREATE INDEX IF NOT EXISTS "deliveries_fire_id_idx" ON "deliveries" ("fire_id");
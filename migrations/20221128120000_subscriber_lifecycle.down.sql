@@ -0,0 +1,5 @@
+DELETE FROM "deliveries" WHERE "subscriber_id" IS NULL;
+ALTER TABLE "deliveries" ALTER COLUMN "subscriber_id" SET NOT NULL;
+
+ALTER TABLE "subscribers" DROP COLUMN IF EXISTS "deactivated_at";
+ALTER TABLE "subscribers" DROP COLUMN IF EXISTS "active";
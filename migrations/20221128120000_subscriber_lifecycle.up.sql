@@ -0,0 +1,6 @@
+-- Deactivated subscriber keeps subscriptions and links, but isn't notified
+ALTER TABLE "subscribers" ADD COLUMN IF NOT EXISTS "active" BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE "subscribers" ADD COLUMN IF NOT EXISTS "deactivated_at" TIMESTAMPTZ;
+
+-- Deliveries of erased subscriber are kept anonymous for stats
+ALTER TABLE "deliveries" ALTER COLUMN "subscriber_id" DROP NOT NULL;
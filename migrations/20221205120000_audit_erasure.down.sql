@@ -0,0 +1 @@
+DROP FUNCTION IF EXISTS erase_audit_actors(BIGINT[]);
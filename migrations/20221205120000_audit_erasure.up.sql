@@ -0,0 +1,10 @@
+-- Audit log is append-only for the application, so changes made by telegram user of erased subscriber
+-- are made anonymous by the function running with rights of its owner. Entries of other tenants aren't touched
+CREATE OR REPLACE FUNCTION erase_audit_actors(telegram_ids BIGINT[]) RETURNS VOID AS $$
+    UPDATE "audit_log" SET "actor_id" = '', "actor_name" = ''
+    WHERE "tenant_id" = current_tenant_id() AND "actor_type" = 'telegram'
+        AND "actor_id" IN (SELECT id::TEXT FROM unnest(telegram_ids) AS id)
+$$ LANGUAGE SQL SECURITY DEFINER SET search_path = public;
+
+REVOKE ALL ON FUNCTION erase_audit_actors(BIGINT[]) FROM PUBLIC;
+GRANT EXECUTE ON FUNCTION erase_audit_actors(BIGINT[]) TO notification_tenant;
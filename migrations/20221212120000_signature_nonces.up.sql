@@ -0,0 +1,12 @@
+-- Nonces of signed requests are shared by every replica, so request is accepted once by any of them.
+-- Producers aren't tenants, so nonces aren't scoped to tenant
+CREATE TABLE IF NOT EXISTS "signature_nonces"(
+    "producer" varchar(255) NOT NULL,
+    "nonce" varchar(64) NOT NULL,
+    "expires_at" TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY ("producer", "nonce")
+);
+
+CREATE INDEX IF NOT EXISTS "signature_nonces_expires_at_idx" ON "signature_nonces" ("expires_at");
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON "signature_nonces" TO notification_tenant;
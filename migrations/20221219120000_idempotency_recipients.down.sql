@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "fire_idempotency_recipients";
+
+ALTER TABLE "fire_idempotency_keys" DROP COLUMN IF EXISTS "state";
@@ -0,0 +1,23 @@
+-- Keys made before are of finished fires
+ALTER TABLE "fire_idempotency_keys" ADD COLUMN IF NOT EXISTS "state" varchar(16) NOT NULL DEFAULT 'done';
+ALTER TABLE "fire_idempotency_keys" ALTER COLUMN "state" SET DEFAULT 'pending';
+
+-- Recipients reached by the fire with the key, so retry of partially failed fire reaches the rest only
+CREATE TABLE IF NOT EXISTS "fire_idempotency_recipients"(
+    "tenant_id" INTEGER NOT NULL DEFAULT current_tenant_id() REFERENCES tenants("tenant_id") ON DELETE CASCADE,
+    "key" varchar(255) NOT NULL,
+    "link_id" INTEGER NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("tenant_id", "key", "link_id")
+);
+
+ALTER TABLE "fire_idempotency_recipients" ADD CONSTRAINT "idempotency_recipients_key_fk"
+    FOREIGN KEY("tenant_id", "key") REFERENCES fire_idempotency_keys("tenant_id", "key") ON DELETE CASCADE;
+
+ALTER TABLE "fire_idempotency_recipients" ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "fire_idempotency_recipients" FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS "tenant_isolation" ON "fire_idempotency_recipients";
+CREATE POLICY "tenant_isolation" ON "fire_idempotency_recipients"
+    USING ("tenant_id" = current_tenant_id()) WITH CHECK ("tenant_id" = current_tenant_id());
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON "fire_idempotency_recipients" TO notification_tenant;
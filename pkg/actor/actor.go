@@ -0,0 +1,36 @@
+package actor
+
+import "context"
+
+//Types of actors changes are made by
+const (
+	TypeAPIKey   = "api_key"
+	TypeAdmin    = "admin"
+	TypeTelegram = "telegram"
+	TypeSystem   = "system"
+)
+
+//Actor is who made the change: api key, admin, telegram user or the service itself
+type Actor struct {
+	Type string
+	ID   string
+	Name string
+}
+
+//System makes changes nobody has asked for, e.g. deactivation of chats which blocked the bot
+var System = Actor{Type: TypeSystem}
+
+type ctxKey struct{}
+
+func With(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, ctxKey{}, a)
+}
+
+//FromContext returns actor of ctx, System if there's none
+func FromContext(ctx context.Context) Actor {
+	a, ok := ctx.Value(ctxKey{}).(Actor)
+	if ok != true {
+		return System
+	}
+	return a
+}
@@ -0,0 +1,230 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+//Kinds of internal failures admins are alerted about
+const (
+	KindDelivery = "delivery"
+	KindTemplate = "template"
+	KindPostgres = "postgres"
+	KindBotPoll  = "bot_poll"
+)
+
+const (
+	defaultSummaryInterval = time.Hour
+	defaultMaxPerMinute    = 10
+	queueSize              = 256
+)
+
+//Alerter alerts admins about internal failures. Alert never blocks and never fails
+type Alerter interface {
+	Alert(kind string, err error)
+}
+
+//SendFunc sends text to admin chat. It must not alert by itself, otherwise failing alert would recurse
+type SendFunc func(chatID int64, text string) error
+
+type Config struct {
+	//ChatIDs are admin chats. Alerts are only logged if there're none
+	ChatIDs []int64
+	//SummaryInterval is how often repeats of already sent alerts are reported
+	SummaryInterval time.Duration
+	//MaxPerMinute throttles alerts. Alerts over the limit are reported in summary
+	MaxPerMinute int
+}
+
+type incident struct {
+	kind     string
+	text     string
+	resolved bool
+}
+
+//repeat is an alert already sent and the number of its repeats since the last summary
+type repeat struct {
+	kind  string
+	text  string
+	count int
+}
+
+//Notifier deduplicates and throttles alerts in background, see Run. First occurrence of an alert is sent at once,
+//repeats are counted and reported in summary
+type Notifier struct {
+	logger *zap.SugaredLogger
+	send   SendFunc
+	cfg    Config
+	queue  chan incident
+	//dropped counts alerts lost because the queue was full
+	dropped uint64
+
+	//The rest is owned by Run
+	repeats     map[string]*repeat
+	windowStart time.Time
+	windowSent  int
+}
+
+func NewNotifier(logger *zap.SugaredLogger, send SendFunc, cfg Config) *Notifier {
+	if cfg.SummaryInterval <= 0 {
+		cfg.SummaryInterval = defaultSummaryInterval
+	}
+	if cfg.MaxPerMinute <= 0 {
+		cfg.MaxPerMinute = defaultMaxPerMinute
+	}
+	return &Notifier{
+		logger:  logger,
+		send:    send,
+		cfg:     cfg,
+		queue:   make(chan incident, queueSize),
+		repeats: make(map[string]*repeat),
+	}
+}
+
+func (n *Notifier) Alert(kind string, err error) {
+	n.enqueue(incident{kind: kind, text: err.Error()})
+}
+
+//Resolve tells admins the failure of kind is over, e.g. postgres is reachable again
+func (n *Notifier) Resolve(kind string, text string) {
+	n.enqueue(incident{kind: kind, text: text, resolved: true})
+}
+
+func (n *Notifier) enqueue(inc incident) {
+	select {
+	case n.queue <- inc:
+	default:
+		atomic.AddUint64(&n.dropped, 1)
+	}
+}
+
+//Run sends alerts until ctx is done
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.cfg.SummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case inc := <-n.queue:
+			n.handle(inc, time.Now())
+		case <-ticker.C:
+			n.summarize()
+		}
+	}
+}
+
+func (n *Notifier) handle(inc incident, now time.Time) {
+	if inc.resolved {
+		//Next failure of the kind is news again
+		for key, r := range n.repeats {
+			if r.kind == inc.kind {
+				delete(n.repeats, key)
+			}
+		}
+		n.deliver(fmt.Sprintf("✅ %s: %s", inc.kind, inc.text))
+		return
+	}
+
+	key := inc.kind + "\n" + inc.text
+	if r, ok := n.repeats[key]; ok {
+		r.count++
+		return
+	}
+	r := &repeat{kind: inc.kind, text: inc.text}
+	n.repeats[key] = r
+
+	if n.allow(now) != true {
+		r.count++
+		return
+	}
+	n.deliver(fmt.Sprintf("🚨 %s: %s", inc.kind, inc.text))
+}
+
+//allow throttles alerts to MaxPerMinute
+func (n *Notifier) allow(now time.Time) bool {
+	if now.Sub(n.windowStart) >= time.Minute {
+		n.windowStart = now
+		n.windowSent = 0
+	}
+	if n.windowSent >= n.cfg.MaxPerMinute {
+		return false
+	}
+	n.windowSent++
+	return true
+}
+
+//summarize reports repeats since the last summary. Alerts without repeats are forgotten,
+//so if they happen again later they're sent at once
+func (n *Notifier) summarize() {
+	var lines []string
+	for key, r := range n.repeats {
+		if r.count == 0 {
+			delete(n.repeats, key)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s (%d times)", r.kind, r.text, r.count))
+		r.count = 0
+	}
+	if dropped := atomic.SwapUint64(&n.dropped, 0); dropped != 0 {
+		lines = append(lines, fmt.Sprintf("%d alerts are dropped, since queue was full", dropped))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	sort.Strings(lines)
+	n.deliver(fmt.Sprintf("🔁 Repeated in the last %s:\n%s", n.cfg.SummaryInterval, strings.Join(lines, "\n")))
+}
+
+//deliver sends text to every admin chat. Failures are only logged, alerting about them would recurse
+func (n *Notifier) deliver(text string) {
+	n.logger.Warnf("admin alert: %s", text)
+	for _, chatID := range n.cfg.ChatIDs {
+		if err := n.send(chatID, text); err != nil {
+			n.logger.Errorf("could not send admin alert to %d. %s", chatID, err.Error())
+		}
+	}
+}
+
+//Watch runs check every interval until ctx is done. Start of failure is alerted as kind and its end is resolved.
+//Errors of checks usually differ every time (e.g. age of the last poll), so they aren't alerted while failing lasts
+func (n *Notifier) Watch(ctx context.Context, kind string, check func(ctx context.Context) error, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cctx, cancel := context.WithTimeout(ctx, interval)
+		err := check(cctx)
+		cancel()
+		if err != nil {
+			if failing != true {
+				failing = true
+				n.Alert(kind, err)
+			}
+			continue
+		}
+		if failing {
+			failing = false
+			n.Resolve(kind, "recovered")
+		}
+	}
+}
+
+//Nop only drops alerts, e.g. in tests
+var Nop Alerter = nop{}
+
+type nop struct{}
+
+func (nop) Alert(kind string, err error) {}
@@ -0,0 +1,58 @@
+package alert
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type sent struct {
+	texts []string
+	err   error
+}
+
+func (s *sent) send(chatID int64, text string) error {
+	s.texts = append(s.texts, text)
+	return s.err
+}
+
+func TestNotifier(t *testing.T) {
+
+	s := &sent{}
+	n := NewNotifier(zap.NewNop().Sugar(), s.send, Config{ChatIDs: []int64{1}, MaxPerMinute: 2})
+	now := time.Now()
+
+	//Repeats are counted, not sent
+	n.handle(incident{kind: KindDelivery, text: "bad gateway"}, now)
+	n.handle(incident{kind: KindDelivery, text: "bad gateway"}, now)
+	n.handle(incident{kind: KindTemplate, text: "template for event 1 not found"}, now)
+	//Throttled
+	n.handle(incident{kind: KindPostgres, text: "connection refused"}, now)
+	assert.Equal(t, []string{
+		"🚨 delivery: bad gateway",
+		"🚨 template: template for event 1 not found",
+	}, s.texts)
+
+	s.texts = nil
+	n.summarize()
+	assert.Equal(t, []string{"🔁 Repeated in the last 1h0m0s:\n" +
+		"delivery: bad gateway (1 times)\n" +
+		"postgres: connection refused (1 times)"}, s.texts)
+
+	//Alert without repeats is forgotten by the next summary, so it's news again
+	s.texts = nil
+	n.summarize()
+	assert.Empty(t, s.texts)
+	n.handle(incident{kind: KindTemplate, text: "template for event 1 not found"}, now.Add(time.Minute))
+	assert.Equal(t, []string{"🚨 template: template for event 1 not found"}, s.texts)
+
+	//Failed alert is not alerted again
+	s.texts = nil
+	s.err = errors.New("telegram is down")
+	n.handle(incident{kind: KindPostgres, text: "recovered", resolved: true}, now.Add(time.Minute))
+	assert.Equal(t, []string{"✅ postgres: recovered"}, s.texts)
+	assert.Empty(t, n.queue)
+}
@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+//Methods of telegram api successful responses of which are tracked for readiness
+const (
+	methodGetMe      = "getMe"
+	methodGetUpdates = "getUpdates"
+)
+
+//apiClient remembers when telegram last answered each api method successfully.
+//Polling loop of tg.BotAPI swallows errors, so that's the only way to tell it's stuck
+type apiClient struct {
+	tg.HTTPClient
+	mu   sync.RWMutex
+	last map[string]time.Time
+}
+
+func newAPIClient() *apiClient {
+	return &apiClient{HTTPClient: &http.Client{}, last: make(map[string]time.Time)}
+}
+
+func (c *apiClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err == nil && resp.StatusCode == http.StatusOK {
+		//Url is <endpoint>/bot<token>/<method>
+		method := path.Base(req.URL.Path)
+		c.mu.Lock()
+		c.last[method] = time.Now()
+		c.mu.Unlock()
+	}
+	return resp, err
+}
+
+//lastOK returns time of the last successful response to method, zero time if there was none
+func (c *apiClient) lastOK(method string) time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.last[method]
+}
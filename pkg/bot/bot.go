@@ -1,31 +1,83 @@
 package bot
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/response_object"
+	"github.com/sonyamoonglade/notification-service/pkg/alert"
+	"github.com/sonyamoonglade/notification-service/pkg/metrics"
+	"github.com/sonyamoonglade/notification-service/pkg/telegram_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+//Callback data of inline buttons is prefixed with action. Rest of the data is id
+const (
+	//AckCallbackPrefix prefixes callback data of acknowledge button. Rest of the data is escalation id
+	AckCallbackPrefix = "ack:"
+	//SubscribeCallbackPrefix prefixes callback data of self-subscription button. Rest of the data is event id
+	SubscribeCallbackPrefix = "sub:"
+	//UnsubscribeCallbackPrefix prefixes callback data of self-unsubscription button. Rest of the data is event id
+	UnsubscribeCallbackPrefix = "unsub:"
+	//EnableLinkCallbackPrefix, DisableLinkCallbackPrefix and UnlinkCallbackPrefix prefix callback data of /links buttons.
+	//Rest of the data is link id
+	EnableLinkCallbackPrefix  = "link_on:"
+	DisableLinkCallbackPrefix = "link_off:"
+	UnlinkCallbackPrefix      = "unlink:"
+)
+
 type Bot interface {
-	Notify(receiverID int64, fmtTempl string) error
+	Name() string
+	Notify(ctx context.Context, receiverID int64, threadID int, fmtTempl string) error
+	NotifyWithKeyboard(ctx context.Context, receiverID int64, threadID int, fmtTempl string, kb tg.InlineKeyboardMarkup) error
+	AnswerCallback(callbackID string, text string) error
 	GetClient() *tg.BotAPI
 	GetUpdatesCfg() tg.UpdateConfig
 	StartKeyboard() tg.ReplyKeyboardMarkup
+	AckKeyboard(escalationID uint64) tg.InlineKeyboardMarkup
+	EventsKeyboard(callbackPrefix string, events []*entity.Event) tg.InlineKeyboardMarkup
+	LinksKeyboard(links []*response_object.TelegramLinkRO) tg.InlineKeyboardMarkup
 	Send(ch tg.Chattable) (*tg.Message, error)
 	SoftSend(ch tg.Chattable) error
+	SetWebhook(url string, secretToken string) error
+	DeleteWebhook() error
 	ClosePoll()
+	//Ping calls getMe. LastPing and LastPoll are times of the last successful getMe and getUpdates, zero if none
+	Ping() error
+	LastPing() time.Time
+	LastPoll() time.Time
+	//SetAlerter makes failed sends alert admins. NotifyAdmin never alerts, so a failing alert doesn't recurse
+	SetAlerter(a alert.Alerter)
+	NotifyAdmin(chatID int64, text string) error
 }
 
 type bot struct {
+	name      string
 	client    *tg.BotAPI
+	api       *apiClient
+	alerter   alert.Alerter
 	logger    *zap.SugaredLogger
 	updateCfg tg.UpdateConfig
 }
 
-func NewBot(token string, logger *zap.SugaredLogger) (Bot, error) {
+//NewBot creates bot against apiEndpoint (e.g. "https://api.telegram.org/bot%s/%s"). Empty apiEndpoint is tg.APIEndpoint.
+//name tells bots apart, links of subscribers are made with particular bot
+func NewBot(name string, token string, apiEndpoint string, logger *zap.SugaredLogger) (Bot, error) {
+
+	if apiEndpoint == "" {
+		apiEndpoint = tg.APIEndpoint
+	}
 
-	client, err := tg.NewBotAPI(token)
+	api := newAPIClient()
+	client, err := tg.NewBotAPIWithClient(token, apiEndpoint, api)
 	if err != nil {
 		return nil, err
 	}
@@ -34,22 +86,38 @@ func NewBot(token string, logger *zap.SugaredLogger) (Bot, error) {
 	updateCfg.Timeout = 60
 	//client.Debug = true
 	return &bot{
-		logger:    logger,
+		name:      name,
+		logger:    logger.With("bot", name),
 		client:    client,
+		api:       api,
+		alerter:   alert.Nop,
 		updateCfg: updateCfg,
 	}, nil
 }
 
+func (b *bot) Name() string {
+	return b.name
+}
+
 //SoftSend it syntax-sugar for sends that do not require message to be returned
 func (b *bot) SoftSend(ch tg.Chattable) error {
 	_, err := b.Send(ch)
 	return err
 }
 
-func (b *bot) Notify(receiverID int64, fmtTempl string) error {
+//Notify sends fmtTempl to receiverID. Non-zero threadID is forum topic of supergroup
+func (b *bot) Notify(ctx context.Context, receiverID int64, threadID int, fmtTempl string) (err error) {
+	_, span := b.startSend(ctx, threadID)
+	defer func() { tracing.End(span, err) }()
+
+	if threadID != 0 {
+		return b.notifyThread(receiverID, threadID, fmtTempl, nil)
+	}
+
 	msg := tg.NewMessage(receiverID, fmtTempl)
 
-	_, err := b.Send(msg)
+	_, err = b.Send(msg)
+	countDelivery(err)
 	if err != nil {
 		return err
 	}
@@ -58,6 +126,67 @@ func (b *bot) Notify(receiverID int64, fmtTempl string) error {
 	return nil
 }
 
+func (b *bot) NotifyWithKeyboard(ctx context.Context, receiverID int64, threadID int, fmtTempl string, kb tg.InlineKeyboardMarkup) (err error) {
+	_, span := b.startSend(ctx, threadID)
+	defer func() { tracing.End(span, err) }()
+
+	if threadID != 0 {
+		return b.notifyThread(receiverID, threadID, fmtTempl, &kb)
+	}
+
+	msg := tg.NewMessage(receiverID, fmtTempl)
+	msg.ReplyMarkup = kb
+
+	_, err = b.Send(msg)
+	countDelivery(err)
+	if err != nil {
+		return err
+	}
+
+	b.logger.Debugf("notified %d with keyboard successfully", receiverID)
+	return nil
+}
+
+//notifyThread sends message to forum topic.
+//tg.MessageConfig has no message_thread_id, hence raw sendMessage request
+func (b *bot) notifyThread(receiverID int64, threadID int, fmtTempl string, kb *tg.InlineKeyboardMarkup) error {
+	params := make(tg.Params)
+	params.AddNonZero64("chat_id", receiverID)
+	params.AddNonZero("message_thread_id", threadID)
+	params.AddNonEmpty("text", fmtTempl)
+	if kb != nil {
+		if err := params.AddInterface("reply_markup", kb); err != nil {
+			return err
+		}
+	}
+
+	_, err := b.client.MakeRequest("sendMessage", params)
+	if err != nil {
+		b.logger.Error(err.Error())
+		b.countAPIError(err)
+		err = fmt.Errorf("bot could not send a message. %w", telegram_errors.FromAPIError(err))
+		b.alertFailure(err)
+		countDelivery(err)
+		return err
+	}
+	countDelivery(nil)
+
+	b.logger.Debugf("notified %d in thread %d successfully", receiverID, threadID)
+	return nil
+}
+
+//AnswerCallback stops loading animation on inline button and shows text to the user.
+//answerCallbackQuery returns bool instead of message, so client.Request is used in place of Send
+func (b *bot) AnswerCallback(callbackID string, text string) error {
+	_, err := b.client.Request(tg.NewCallback(callbackID, text))
+	if err != nil {
+		b.logger.Error(err.Error())
+		b.countAPIError(err)
+		return fmt.Errorf("bot could not answer callback. %s", err.Error())
+	}
+	return nil
+}
+
 func (b *bot) GetClient() *tg.BotAPI {
 	return b.client
 }
@@ -70,8 +199,11 @@ func (b *bot) Send(ch tg.Chattable) (*tg.Message, error) {
 	m, err := b.client.Send(ch)
 	if err != nil {
 		b.logger.Error(err.Error())
-		//Todo: notify admin here
-		return nil, fmt.Errorf("bot could not send a message. %s", err.Error())
+		b.countAPIError(err)
+		//Errors of unreachable chats are typed, so callers could deactivate the link (see telegram_errors.ErrChatUnreachable)
+		err = fmt.Errorf("bot could not send a message. %w", telegram_errors.FromAPIError(err))
+		b.alertFailure(err)
+		return nil, err
 	}
 	return &m, nil
 }
@@ -85,6 +217,130 @@ func (b *bot) StartKeyboard() tg.ReplyKeyboardMarkup {
 	return tg.NewReplyKeyboard(row)
 }
 
+func (b *bot) AckKeyboard(escalationID uint64) tg.InlineKeyboardMarkup {
+	bt := tg.NewInlineKeyboardButtonData("Принято ✅", fmt.Sprintf("%s%d", AckCallbackPrefix, escalationID))
+	return tg.NewInlineKeyboardMarkup(tg.NewInlineKeyboardRow(bt))
+}
+
+//EventsKeyboard is one button per event in a row. Pressing button sends callbackPrefix + event id
+func (b *bot) EventsKeyboard(callbackPrefix string, events []*entity.Event) tg.InlineKeyboardMarkup {
+	var rows [][]tg.InlineKeyboardButton
+	for _, e := range events {
+		bt := tg.NewInlineKeyboardButtonData(e.Translate, fmt.Sprintf("%s%d", callbackPrefix, e.EventID))
+		rows = append(rows, tg.NewInlineKeyboardRow(bt))
+	}
+	return tg.NewInlineKeyboardMarkup(rows...)
+}
+
+//LinksKeyboard has a row per link: enable or disable button and unlink button
+func (b *bot) LinksKeyboard(links []*response_object.TelegramLinkRO) tg.InlineKeyboardMarkup {
+	var rows [][]tg.InlineKeyboardButton
+	for _, l := range links {
+		toggle := tg.NewInlineKeyboardButtonData("🔕 "+l.PhoneNumber, fmt.Sprintf("%s%d", DisableLinkCallbackPrefix, l.LinkID))
+		if l.Enabled != true {
+			toggle = tg.NewInlineKeyboardButtonData("🔔 "+l.PhoneNumber, fmt.Sprintf("%s%d", EnableLinkCallbackPrefix, l.LinkID))
+		}
+		unlink := tg.NewInlineKeyboardButtonData("❌ Отвязать", fmt.Sprintf("%s%d", UnlinkCallbackPrefix, l.LinkID))
+		rows = append(rows, tg.NewInlineKeyboardRow(toggle, unlink))
+	}
+	return tg.NewInlineKeyboardMarkup(rows...)
+}
+
+//SetWebhook makes telegram send updates to url with X-Telegram-Bot-Api-Secret-Token header.
+//tg.WebhookConfig has no secret_token, hence raw setWebhook request
+func (b *bot) SetWebhook(url string, secretToken string) error {
+	params := make(tg.Params)
+	params.AddNonEmpty("url", url)
+	params.AddNonEmpty("secret_token", secretToken)
+
+	_, err := b.client.MakeRequest("setWebhook", params)
+	if err != nil {
+		b.countAPIError(err)
+		return fmt.Errorf("could not set webhook. %s", err.Error())
+	}
+	return nil
+}
+
+//DeleteWebhook switches bot back to getUpdates. Pending updates are kept
+func (b *bot) DeleteWebhook() error {
+	_, err := b.client.Request(tg.DeleteWebhookConfig{})
+	if err != nil {
+		b.countAPIError(err)
+		return fmt.Errorf("could not delete webhook. %s", err.Error())
+	}
+	return nil
+}
+
 func (b *bot) ClosePoll() {
 	b.client.StopReceivingUpdates()
 }
+
+func (b *bot) Ping() error {
+	_, err := b.client.GetMe()
+	if err != nil {
+		b.logger.Error(err.Error())
+		b.countAPIError(err)
+		return fmt.Errorf("could not get me. %s", err.Error())
+	}
+	return nil
+}
+
+func (b *bot) LastPing() time.Time {
+	return b.api.lastOK(methodGetMe)
+}
+
+func (b *bot) LastPoll() time.Time {
+	return b.api.lastOK(methodGetUpdates)
+}
+
+func (b *bot) SetAlerter(a alert.Alerter) {
+	b.alerter = a
+}
+
+func (b *bot) NotifyAdmin(chatID int64, text string) error {
+	_, err := b.client.Send(tg.NewMessage(chatID, text))
+	if err != nil {
+		b.countAPIError(err)
+		return fmt.Errorf("bot could not send admin alert. %s", err.Error())
+	}
+	return nil
+}
+
+//alertFailure alerts admins about failed send. Unreachable chats are expected, they're deactivated instead
+func (b *bot) alertFailure(err error) {
+	if errors.Is(err, telegram_errors.ErrChatUnreachable) {
+		return
+	}
+	b.alerter.Alert(alert.KindDelivery, fmt.Errorf("%s bot: %w", b.name, err))
+}
+
+//startSend starts span of sendMessage request to telegram
+func (b *bot) startSend(ctx context.Context, threadID int) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "telegram sendMessage",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("bot.name", b.name), attribute.Bool("telegram.thread", threadID != 0)))
+}
+
+//countAPIError counts err of telegram api request by code telegram responded with
+func (b *bot) countAPIError(err error) {
+	code := "transport"
+	var apiErr *tg.Error
+	if errors.As(err, &apiErr) {
+		code = strconv.Itoa(apiErr.Code)
+	}
+	metrics.TelegramAPIErrors.WithLabelValues(b.name, code).Inc()
+}
+
+//countDelivery counts notification by result of sending it. Unreachable chats are told apart from failures,
+//since they're expected and deactivated rather than retried
+func countDelivery(err error) {
+	status := metrics.DeliverySent
+	switch true {
+	case err == nil:
+	case errors.Is(err, telegram_errors.ErrChatUnreachable):
+		status = metrics.DeliveryUnreachable
+	default:
+		status = metrics.DeliveryFailed
+	}
+	metrics.Deliveries.WithLabelValues(metrics.ChannelTelegram, status).Inc()
+}
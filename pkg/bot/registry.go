@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+)
+
+//DefaultName is the name of the bot created from BOT_TOKEN. Events not assigned to other bots are sent by it
+const DefaultName = "default"
+
+//Registry is the set of named bots, e.g. one per cafe brand. Every bot belongs to a tenant and reaches links of the tenant only.
+//Event of tenant is sent by the bot it's assigned to, by the first bot of the tenant otherwise
+type Registry struct {
+	bots  map[string]Bot
+	order []string
+	//tenants is bot name -> tenant id
+	tenants map[string]uint64
+	//eventBots is tenant id -> event name -> bot name. Resolved into eventIDBots by ResolveEvents
+	eventBots   map[uint64]map[string]string
+	eventIDBots map[uint64]map[uint64]Bot
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		bots:        make(map[string]Bot),
+		tenants:     make(map[string]uint64),
+		eventBots:   make(map[uint64]map[string]string),
+		eventIDBots: make(map[uint64]map[uint64]Bot),
+	}
+}
+
+//Add adds bot of tenantID sending eventNames. Event might be assigned to only one bot of the tenant
+func (r *Registry) Add(b Bot, tenantID uint64, eventNames []string) error {
+	if _, ok := r.bots[b.Name()]; ok {
+		return fmt.Errorf("duplicate bot %s", b.Name())
+	}
+	if _, ok := r.eventBots[tenantID]; ok != true {
+		r.eventBots[tenantID] = make(map[string]string)
+	}
+	for _, name := range eventNames {
+		if other, ok := r.eventBots[tenantID][name]; ok {
+			return fmt.Errorf("event %s is assigned to both %s and %s bots", name, other, b.Name())
+		}
+		r.eventBots[tenantID][name] = b.Name()
+	}
+	r.bots[b.Name()] = b
+	r.tenants[b.Name()] = tenantID
+	r.order = append(r.order, b.Name())
+	return nil
+}
+
+//ResolveEvents maps assigned event names of the tenant to ids. Must be called before ForEvent once events are registered
+func (r *Registry) ResolveEvents(tenantID uint64, events []*entity.Event) error {
+	known := make(map[string]bool, len(events))
+	resolved := make(map[uint64]Bot)
+	for _, e := range events {
+		known[e.Name] = true
+		if botName, ok := r.eventBots[tenantID][e.Name]; ok {
+			resolved[e.EventID] = r.bots[botName]
+		}
+	}
+	for name, botName := range r.eventBots[tenantID] {
+		if known[name] != true {
+			return fmt.Errorf("unknown event %s is assigned to %s bot", name, botName)
+		}
+	}
+	r.eventIDBots[tenantID] = resolved
+	return nil
+}
+
+func (r *Registry) Get(name string) (Bot, bool) {
+	b, ok := r.bots[name]
+	return b, ok
+}
+
+func (r *Registry) Default() Bot {
+	return r.bots[DefaultName]
+}
+
+//TenantOf returns tenant of the bot
+func (r *Registry) TenantOf(name string) uint64 {
+	return r.tenants[name]
+}
+
+//Tenants returns tenants having bots, in the order their first bots were added
+func (r *Registry) Tenants() []uint64 {
+	var tenants []uint64
+	seen := make(map[uint64]bool)
+	for _, name := range r.order {
+		tenantID := r.tenants[name]
+		if seen[tenantID] != true {
+			seen[tenantID] = true
+			tenants = append(tenants, tenantID)
+		}
+	}
+	return tenants
+}
+
+//ForEvent returns bot of the tenant the event is assigned to, the first bot of the tenant otherwise.
+//Default bot is returned for tenants without bots
+func (r *Registry) ForEvent(tenantID uint64, eventID uint64) Bot {
+	if b, ok := r.eventIDBots[tenantID][eventID]; ok {
+		return b
+	}
+	for _, name := range r.order {
+		if r.tenants[name] == tenantID {
+			return r.bots[name]
+		}
+	}
+	return r.Default()
+}
+
+//All returns bots in the order they were added
+func (r *Registry) All() []Bot {
+	bots := make([]Bot, 0, len(r.order))
+	for _, name := range r.order {
+		bots = append(bots, r.bots[name])
+	}
+	return bots
+}
+
+//KeepAlive pings every bot each interval until ctx is done, so LastPing of bot tells whether telegram is reachable
+func (r *Registry) KeepAlive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, b := range r.All() {
+			//Errors are logged and counted by bot
+			_ = b.Ping()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
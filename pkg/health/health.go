@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/pkg/response"
+	"go.uber.org/zap"
+)
+
+//Paths of probes. They're served without api key
+const (
+	LivePath  = "/healthz"
+	ReadyPath = "/readyz"
+)
+
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+//checkTimeout bounds every check, so a hanging dependency fails readiness instead of hanging the probe
+const checkTimeout = time.Second * 2
+
+//errShuttingDown fails readiness once graceful shutdown has started
+var errShuttingDown = fmt.Errorf("service is shutting down")
+
+//CheckFunc returns nil if dependency is fine
+type CheckFunc func(ctx context.Context) error
+
+//CheckResult is JSON detail of a check
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+//Checker serves liveness and readiness probes. Liveness tells the process is alive,
+//readiness runs every check and fails if any of them fails or shutdown has started
+type Checker struct {
+	logger       *zap.SugaredLogger
+	mu           sync.RWMutex
+	checks       map[string]CheckFunc
+	shuttingDown int32
+}
+
+func NewChecker(logger *zap.SugaredLogger) *Checker {
+	return &Checker{logger: logger, checks: make(map[string]CheckFunc)}
+}
+
+//Add adds readiness check. Names appear in JSON detail of /readyz
+func (c *Checker) Add(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+//Shutdown flips readiness to failing, so orchestrator stops routing requests before the server is closed
+func (c *Checker) Shutdown() {
+	atomic.StoreInt32(&c.shuttingDown, 1)
+}
+
+func (c *Checker) InitRoutes(router *httprouter.Router) {
+	router.GET(LivePath, c.Live)
+	router.GET(ReadyPath, c.Ready)
+}
+
+func (c *Checker) Live(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	response.Json(c.logger, w, http.StatusOK, response.JSON{
+		"status": StatusOK,
+	})
+}
+
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	results := c.run(r.Context())
+	if atomic.LoadInt32(&c.shuttingDown) == 1 {
+		results["shutdown"] = CheckResult{Status: StatusFailing, Error: errShuttingDown.Error()}
+	}
+
+	status, code := StatusOK, http.StatusOK
+	for name, res := range results {
+		if res.Status != StatusOK {
+			status, code = StatusFailing, http.StatusServiceUnavailable
+			c.logger.Warnf("readiness check %s is failing: %s", name, res.Error)
+		}
+	}
+
+	response.Json(c.logger, w, code, response.JSON{
+		"status": status,
+		"checks": results,
+	})
+}
+
+//run runs checks concurrently
+func (c *Checker) run(ctx context.Context) map[string]CheckResult {
+	c.mu.RLock()
+	checks := make(map[string]CheckFunc, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]CheckResult, len(checks))
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check CheckFunc) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			res := CheckResult{Status: StatusOK}
+			if err := check(cctx); err != nil {
+				res = CheckResult{Status: StatusFailing, Error: err.Error()}
+			}
+			mu.Lock()
+			results[name] = res
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+	return results
+}
+
+//Recent fails if last() is zero or older than maxAge, e.g. last successful telegram request
+func Recent(what string, last func() time.Time, maxAge time.Duration) CheckFunc {
+	return func(ctx context.Context) error {
+		t := last()
+		if t.IsZero() {
+			return fmt.Errorf("%s has never happened", what)
+		}
+		if age := time.Since(t); age > maxAge {
+			return fmt.Errorf("%s happened %s ago, longer than %s", what, age.Truncate(time.Second), maxAge)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/pkg/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type readiness struct {
+	Status string                        `json:"status"`
+	Checks map[string]health.CheckResult `json:"checks"`
+}
+
+func ready(t *testing.T, router *httprouter.Router) (int, readiness) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, health.ReadyPath, nil))
+	var body readiness
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	return w.Code, body
+}
+
+func TestReady(t *testing.T) {
+
+	checker := health.NewChecker(zap.NewNop().Sugar())
+	router := httprouter.New()
+	checker.InitRoutes(router)
+
+	var dbErr error
+	checker.Add("postgres", func(ctx context.Context) error { return dbErr })
+	lastPing := time.Now()
+	checker.Add("telegram", health.Recent("getMe", func() time.Time { return lastPing }, time.Minute))
+
+	code, body := ready(t, router)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusOK, body.Status)
+	assert.Equal(t, health.StatusOK, body.Checks["telegram"].Status)
+
+	dbErr = errors.New("connection refused")
+	lastPing = time.Now().Add(-time.Hour)
+	code, body = ready(t, router)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusFailing, body.Status)
+	assert.Equal(t, "connection refused", body.Checks["postgres"].Error)
+	assert.Equal(t, health.StatusFailing, body.Checks["telegram"].Status)
+
+	dbErr = nil
+	lastPing = time.Now()
+	checker.Shutdown()
+	code, body = ready(t, router)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusFailing, body.Checks["shutdown"].Status)
+
+	//Liveness doesn't depend on checks
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, health.LivePath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -7,60 +7,139 @@ import (
 	"strings"
 )
 
-var ErrNoEventName = errors.New("missing eventName in url string")
-var ErrNoSubscriptionID = errors.New("missing subscriptionId in url string")
-var ErrInvalidEventId = errors.New("invalid eventId format")
-var ErrInternalError = errors.New("internal error")
-var ErrMissingTemplateServiceUnavailable = errors.New("service is unavailable due to missing template")
-var ErrInvalidPayload = errors.New("invalid request payload")
-var ErrSubscriberDoesNotExist = errors.New("subscriber does not exist")
-var ErrSubscriptionDoesNotExist = errors.New("subscription does not exist")
-var ErrSubscriptionAlreadyExists = errors.New("subscription already exists")
-var ErrNoSubscriptions = errors.New("no subscriptions")
-var ErrNoTelegramSubscribers = errors.New("no telegram subscribers")
+var ErrNoEventName = New("missing_event_name", http.StatusBadRequest, "missing eventName in url string")
+var ErrEventDoesNotExist = New("event_not_found", http.StatusBadRequest, "event does not exist")
+var ErrDuplicateFire = New("duplicate_fire", http.StatusConflict, "fire with this idempotency key has already happened")
+var ErrBatchTooLarge = New("batch_too_large", http.StatusRequestEntityTooLarge, "batch is too large")
+var ErrNoSubscriptionID = New("missing_subscription_id", http.StatusBadRequest, "missing subscriptionId in url string")
+var ErrInvalidEventId = New("invalid_event_id", http.StatusBadRequest, "invalid eventId format")
+var ErrInternalError = New("internal_error", http.StatusInternalServerError, "internal error")
+var ErrMissingTemplateServiceUnavailable = New("template_unavailable", http.StatusServiceUnavailable, "service is unavailable due to missing template")
+var ErrInvalidPayload = New("invalid_payload", http.StatusBadRequest, "invalid request payload")
+var ErrSubscriberDoesNotExist = New("subscriber_not_found", http.StatusBadRequest, "subscriber does not exist")
+var ErrSubscriberAlreadyExists = New("subscriber_already_exists", http.StatusConflict, "subscriber with this phone number already exists")
+var ErrSubscriptionDoesNotExist = New("subscription_not_found", http.StatusBadRequest, "subscription does not exist")
+var ErrSubscriptionAlreadyExists = New("subscription_already_exists", http.StatusConflict, "subscription already exists")
+var ErrNoSubscriptions = New("no_subscriptions", http.StatusNoContent, "no subscriptions")
+var ErrNoTelegramSubscribers = New("no_telegram_subscribers", http.StatusNoContent, "no telegram subscribers")
+var ErrPartialDelivery = New("partial_delivery", http.StatusMultiStatus, "some recipients have not been notified")
+var ErrInvalidEscalationID = New("invalid_escalation_id", http.StatusBadRequest, "invalid escalationId format")
+var ErrEscalationDoesNotExist = New("escalation_not_found", http.StatusNotFound, "escalation does not exist")
+var ErrEscalationAlreadyHandled = New("escalation_already_handled", http.StatusConflict, "escalation is already handled")
+var ErrUnknownRecipients = New("unknown_recipients", http.StatusBadRequest, "unknown recipients")
+var ErrInvalidRecipientsMode = New("invalid_recipients_mode", http.StatusBadRequest, "invalid recipients mode")
+var ErrInvalidMuteWindow = New("invalid_mute_window", http.StatusBadRequest, "invalid mute window")
+var ErrNotMuted = New("not_muted", http.StatusNotFound, "subscriber is not muted")
+var ErrInvalidGroupID = New("invalid_group_id", http.StatusBadRequest, "invalid groupId format")
+var ErrInvalidSubscriberID = New("invalid_subscriber_id", http.StatusBadRequest, "invalid subscriberId format")
+var ErrGroupDoesNotExist = New("group_not_found", http.StatusNotFound, "group does not exist")
+var ErrGroupAlreadyExists = New("group_already_exists", http.StatusConflict, "group already exists")
+var ErrGroupMemberDoesNotExist = New("group_member_not_found", http.StatusNotFound, "group member does not exist")
+var ErrGroupMemberAlreadyExists = New("group_member_already_exists", http.StatusConflict, "group member already exists")
+var ErrGroupSubscriptionDoesNotExist = New("group_subscription_not_found", http.StatusNotFound, "group subscription does not exist")
+var ErrGroupSubscriptionAlreadyExists = New("group_subscription_already_exists", http.StatusConflict, "group subscription already exists")
+var ErrInvalidLinkID = New("invalid_link_id", http.StatusBadRequest, "invalid link id")
+var ErrTelegramLinkDoesNotExist = New("telegram_link_not_found", http.StatusNotFound, "telegram link does not exist")
+var ErrTemplateDoesNotExist = New("template_not_found", http.StatusNotFound, "template override does not exist")
+var ErrMissingAPIKey = New("missing_api_key", http.StatusUnauthorized, "missing api key")
+var ErrInvalidAPIKey = New("invalid_api_key", http.StatusUnauthorized, "invalid api key")
+var ErrInvalidTenantID = New("invalid_tenant_id", http.StatusBadRequest, "invalid tenantId format")
+var ErrTenantDoesNotExist = New("tenant_not_found", http.StatusNotFound, "tenant does not exist")
+var ErrTenantAlreadyExists = New("tenant_already_exists", http.StatusConflict, "tenant already exists")
+var ErrInsufficientScope = New("insufficient_scope", http.StatusForbidden, "api key has insufficient scope")
+var ErrInvalidScope = New("invalid_scope", http.StatusBadRequest, "invalid scope")
+var ErrInvalidAPIKeyID = New("invalid_api_key_id", http.StatusBadRequest, "invalid apiKeyId format")
+var ErrAPIKeyDoesNotExist = New("api_key_not_found", http.StatusNotFound, "api key does not exist")
+var ErrInvalidAuditFilter = New("invalid_audit_filter", http.StatusBadRequest, "invalid audit filter")
+var ErrInvalidStatsFilter = New("invalid_stats_filter", http.StatusBadRequest, "invalid stats filter")
+var ErrInvalidSubscriberFilter = New("invalid_subscriber_filter", http.StatusBadRequest, "invalid subscriber filter")
+var ErrInvalidExportFormat = New("invalid_export_format", http.StatusBadRequest, "format must be csv or ndjson")
+var ErrInvalidImport = New("invalid_import", http.StatusBadRequest, "invalid csv of phone,event_name rows")
+
+//ErrAlreadyExists and ErrInvalidReference are constraint violations of storage not caught by services
+var ErrAlreadyExists = New("already_exists", http.StatusConflict, "resource already exists")
+var ErrInvalidReference = New("invalid_reference", http.StatusBadRequest, "referenced resource does not exist")
+
+//Details are extension members of problem, e.g. names of unknown recipients
+type Details map[string]interface{}
+
+//Error is a domain error. Code is stable and machine-readable, Title is the same for every error of the code.
+//Detail and Details describe the occurrence
+type Error struct {
+	Code    string
+	Status  int
+	Title   string
+	Detail  string
+	Details Details
+}
+
+func New(code string, status int, title string) *Error {
+	return &Error{Code: code, Status: status, Title: title}
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+	return e.Title
+}
+
+//Is lets errors.Is(err, ErrX) match copies of ErrX with details
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+//With returns copy of e describing the occurrence. Empty detail keeps the title as message
+func (e *Error) With(detail string, details Details) *Error {
+	cp := *e
+	cp.Detail = detail
+	cp.Details = details
+	return &cp
+}
+
+//CodeOf returns code of typed err, ErrInternalError code otherwise
+func CodeOf(err error) string {
+	return resolve(err).Code
+}
+
+//StatusOf returns http status of typed err, 500 otherwise
+func StatusOf(err error) int {
+	return resolve(err).Status
+}
 
 func NewErrEventDoesNotExist(eventName string) error {
-	return errors.New(fmt.Sprintf("event with name %s does not exist", eventName))
+	return ErrEventDoesNotExist.With(
+		fmt.Sprintf("event with name %s does not exist", eventName),
+		Details{"event_name": eventName})
+}
+
+func NewErrUnknownRecipients(recipients []string) error {
+	return ErrUnknownRecipients.With(
+		fmt.Sprintf("%s: %s", ErrUnknownRecipients.Title, strings.Join(recipients, ", ")),
+		Details{"recipients": recipients})
+}
+
+//NewErrInvalidImport tells the client why its csv can't be read
+func NewErrInvalidImport(err error) error {
+	return ErrInvalidImport.With(fmt.Sprintf("%s. %s", ErrInvalidImport.Title, err.Error()), nil)
 }
 
-func MakeErrorResponse(w http.ResponseWriter, err error) {
-	w.Header().Set("Content-Type", "text/plain")
-	switch true {
-	case strings.Contains(err.Error(), "with name"):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	case errors.Is(err, ErrNoEventName):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	case strings.Contains(err.Error(), "template for event"):
-		http.Error(w, ErrMissingTemplateServiceUnavailable.Error(), http.StatusServiceUnavailable)
-		return
-	case strings.Contains(err.Error(), "Bad request"):
-		http.Error(w, ErrInvalidPayload.Error(), http.StatusBadRequest)
-		return
-	case strings.Contains(err.Error(), "Validation"):
-		http.Error(w, ErrInvalidPayload.Error(), http.StatusBadRequest)
-		return
-	case strings.Contains(err.Error(), "already exists"):
-		http.Error(w, err.Error(), http.StatusConflict)
-		return
-	case strings.Contains(err.Error(), "subscriber does not exist"):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	case strings.Contains(err.Error(), "subscription does not exist"):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	case strings.Contains(err.Error(), "no subscriptions"):
-		http.Error(w, "", http.StatusNoContent)
-		return
-	case strings.Contains(err.Error(), "no telegram subscribers"):
-		http.Error(w, "", http.StatusNoContent)
-		return
-	case strings.Contains(err.Error(), "invalid request payload"):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	default:
-		http.Error(w, ErrInternalError.Error(), http.StatusInternalServerError)
-		return
+//NewErrInvalidPayload tells the client why its payload can't be decoded
+func NewErrInvalidPayload(err error) error {
+	return ErrInvalidPayload.With(fmt.Sprintf("%s. %s", ErrInvalidPayload.Title, err.Error()), nil)
+}
+
+//resolve returns typed error of err chain. Errors of other packages known to be client errors are typed here
+func resolve(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	for _, known := range external {
+		if errors.Is(err, known.err) {
+			return known.as
+		}
 	}
+	return ErrInternalError
 }
@@ -0,0 +1,83 @@
+package http_errors
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sonyamoonglade/notification-service/pkg/requestid"
+	"github.com/sonyamoonglade/notification-service/pkg/signature"
+)
+
+const ProblemContentType = "application/problem+json"
+
+//typePrefix makes problem type URI of error code
+const typePrefix = "urn:notification-service:problem:"
+
+//external are sentinels of packages which can't depend on http_errors
+var external = []struct {
+	err error
+	as  *Error
+}{
+	{signature.ErrMissingSignature, New("missing_signature", http.StatusUnauthorized, signature.ErrMissingSignature.Error())},
+	{signature.ErrUnknownProducer, New("unknown_producer", http.StatusUnauthorized, signature.ErrUnknownProducer.Error())},
+	{signature.ErrInvalidTimestamp, New("invalid_timestamp", http.StatusUnauthorized, signature.ErrInvalidTimestamp.Error())},
+	{signature.ErrInvalidNonce, New("invalid_nonce", http.StatusUnauthorized, signature.ErrInvalidNonce.Error())},
+	{signature.ErrReplayedNonce, New("replayed_nonce", http.StatusUnauthorized, signature.ErrReplayedNonce.Error())},
+	{signature.ErrInvalidSignature, New("invalid_signature", http.StatusUnauthorized, signature.ErrInvalidSignature.Error())},
+	{signature.ErrForeignProducer, New("foreign_producer", http.StatusForbidden, signature.ErrForeignProducer.Error())},
+}
+
+//Problem is RFC 7807 body of error response
+type Problem struct {
+	Type      string  `json:"type"`
+	Title     string  `json:"title"`
+	Status    int     `json:"status"`
+	Detail    string  `json:"detail"`
+	Code      string  `json:"code"`
+	RequestID string  `json:"request_id,omitempty"`
+	Details   Details `json:"details,omitempty"`
+}
+
+//MakeErrorResponse renders err as application/problem+json. Errors which aren't typed are internal ones,
+//so their messages aren't shown to the client
+func MakeErrorResponse(w http.ResponseWriter, err error) {
+	e := resolve(err)
+
+	//Nothing to notify about isn't an error for the client
+	if e.Status == http.StatusNoContent {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	p := Problem{
+		Type:   typePrefix + e.Code,
+		Title:  e.Title,
+		Status: e.Status,
+		Detail: e.Title,
+		Code:   e.Code,
+		//See requestid.Wrap
+		RequestID: w.Header().Get(requestid.Header),
+		Details:   e.Details,
+	}
+	if e != ErrInternalError {
+		p.Detail = err.Error()
+	}
+
+	if e.Status == http.StatusUnauthorized && isSignatureError(e) {
+		w.Header().Set("WWW-Authenticate", "HMAC-SHA256")
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.Status)
+	//Headers are sent already, nothing to do on failure
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+func isSignatureError(e *Error) bool {
+	for _, known := range external {
+		if known.as == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package http_errors_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/requestid"
+	"github.com/sonyamoonglade/notification-service/pkg/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeErrorResponse(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{
+			name:       "typed",
+			err:        http_errors.ErrGroupDoesNotExist,
+			wantStatus: http.StatusNotFound,
+			wantCode:   "group_not_found",
+			wantDetail: "group does not exist",
+		},
+		{
+			name:       "wrapped",
+			err:        fmt.Errorf("%w: admin", http_errors.ErrInvalidScope),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_scope",
+			wantDetail: "invalid scope: admin",
+		},
+		{
+			name:       "occurrence",
+			err:        http_errors.NewErrEventDoesNotExist("order_created"),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "event_not_found",
+			wantDetail: "event with name order_created does not exist",
+		},
+		{
+			//Message of untyped error mentioning "already exists" must neither leak nor turn it into 409
+			name:       "untyped",
+			err:        errors.New("relation already exists"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal_error",
+			wantDetail: "internal error",
+		},
+		{
+			name:       "signature",
+			err:        signature.ErrReplayedNonce,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "replayed_nonce",
+			wantDetail: signature.ErrReplayedNonce.Error(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			w.Header().Set(requestid.Header, "req-1")
+
+			http_errors.MakeErrorResponse(w, tc.err)
+
+			var p http_errors.Problem
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+			assert.Equal(t, tc.wantStatus, w.Code)
+			assert.Equal(t, http_errors.ProblemContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tc.wantStatus, p.Status)
+			assert.Equal(t, tc.wantCode, p.Code)
+			assert.Equal(t, tc.wantDetail, p.Detail)
+			assert.Equal(t, "req-1", p.RequestID)
+		})
+	}
+}
+
+func TestMakeErrorResponseDetails(t *testing.T) {
+
+	w := httptest.NewRecorder()
+
+	http_errors.MakeErrorResponse(w, http_errors.NewErrUnknownRecipients([]string{"+79999999999"}))
+
+	var p http_errors.Problem
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	assert.Equal(t, []interface{}{"+79999999999"}, p.Details["recipients"])
+	assert.True(t, errors.Is(http_errors.NewErrUnknownRecipients(nil), http_errors.ErrUnknownRecipients))
+}
+
+func TestMakeErrorResponseNoContent(t *testing.T) {
+
+	w := httptest.NewRecorder()
+
+	http_errors.MakeErrorResponse(w, http_errors.ErrNoSubscriptions)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, 0, w.Body.Len())
+}
@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/pkg/requestid"
+	"github.com/sonyamoonglade/notification-service/pkg/server"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+//MatchFunc returns route pattern of the request and its params (see server.Match)
+type MatchFunc func(r *http.Request) (string, httprouter.Params)
+
+//AccessLog puts request-scoped logger to ctx of every request handled by next and writes one line per request.
+//Request id and trace id of ctx are logged, so put AccessLog inside requestid.Wrap and tracing.Wrap
+func AccessLog(logger *zap.SugaredLogger, match MatchFunc, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := r.Context()
+		route, params := match(r)
+
+		fields := []interface{}{
+			"request_id", requestid.FromContext(ctx),
+			"method", r.Method,
+			"route", route,
+			"remote_addr", r.RemoteAddr,
+		}
+		if eventName := params.ByName("eventName"); eventName != "" {
+			fields = append(fields, "event_name", eventName)
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			fields = append(fields, "trace_id", sc.TraceID().String())
+		}
+		reqLogger := logger.With(fields...)
+
+		sw := server.NewStatusRecorder(w)
+		next.ServeHTTP(sw, r.WithContext(WithLogger(ctx, reqLogger)))
+
+		reqLogger.Infow("request",
+			"path", r.URL.Path,
+			"status", sw.Status,
+			"bytes", sw.Bytes,
+			"latency", time.Since(start))
+	})
+}
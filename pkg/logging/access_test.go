@@ -0,0 +1,51 @@
+package logging_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/requestid"
+	"github.com/sonyamoonglade/notification-service/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAccessLog(t *testing.T) {
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core).Sugar()
+
+	router := httprouter.New()
+	router.POST("/api/events/fire/:eventName", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		//Handler logs with logger of the request
+		logging.FromContext(r.Context(), nil).Error("could not fire")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	match := func(r *http.Request) (string, httprouter.Params) {
+		return server.Match(router, r)
+	}
+	h := requestid.Wrap(logging.AccessLog(logger, match, router))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/events/fire/worker_login", nil)
+	req.Header.Set(requestid.Header, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	for _, e := range entries {
+		fields := e.ContextMap()
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Equal(t, "/api/events/fire/:eventName", fields["route"])
+		assert.Equal(t, "worker_login", fields["event_name"])
+		assert.Equal(t, http.MethodPost, fields["method"])
+	}
+	assert.Equal(t, "could not fire", entries[0].Message)
+	assert.Equal(t, "request", entries[1].Message)
+	assert.EqualValues(t, http.StatusInternalServerError, entries[1].ContextMap()["status"])
+}
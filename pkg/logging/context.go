@@ -0,0 +1,22 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+//WithLogger puts logger scoped to request or telegram update to ctx
+func WithLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+//FromContext returns logger of ctx, fallback if there's none, e.g. in workers
+func FromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if logger, ok := ctx.Value(ctxKey{}).(*zap.SugaredLogger); ok {
+		return logger
+	}
+	return fallback
+}
@@ -40,6 +40,110 @@ var (
 		"%s уcпешно зарегистрирован ✅\n" +
 		"\n" +
 		"Я буду присылать уведомления в этот чат\n"
+	Escalated = "" +
+		"⚠️ Никто не отреагировал на уведомление\n" +
+		"\n" +
+		"%s"
+	Acknowledged = "" +
+		"Принято, спасибо ✅"
+	AlreadyHandled = "" +
+		"Уведомление уже обработано"
+	NotLinked = "" +
+		"Я вас пока не знаю 🤔\n" +
+		"\n" +
+		"Сначала нажмите 'Получать уведомления',\n" +
+		"чтобы связать телеграм и номер телефона.\n" +
+		"\n" +
+		"Для перезапуска бота введите /start"
+	AvailableEvents = "" +
+		"Доступные события 📋\n" +
+		"\n" +
+		"%s\n" +
+		"\n" +
+		"На события, отмеченные ✍️, можно подписаться самостоятельно: /subscribe"
+	EventLine = "" +
+		"• %s"
+	SelfSubscribableEventLine = "" +
+		"• %s ✍️"
+	ChooseToSubscribe = "" +
+		"Выберите событие, на которое хотите подписаться"
+	ChooseToUnsubscribe = "" +
+		"Выберите событие, от которого хотите отписаться"
+	NothingToSubscribe = "" +
+		"Нет событий, на которые можно подписаться"
+	NothingToUnsubscribe = "" +
+		"Нет подписок, от которых можно отписаться"
+	Subscribed = "" +
+		"Вы подписались на «%s» ✅"
+	AlreadySubscribed = "" +
+		"Вы уже подписаны на «%s»"
+	Unsubscribed = "" +
+		"Вы отписались от «%s»"
+	NotSelfSubscribable = "" +
+		"На это событие нельзя подписаться самостоятельно"
+	MySubscriptions = "" +
+		"Ваши подписки 📬\n" +
+		"\n" +
+		"%s"
+	GroupEventLine = "" +
+		"• %s (группа %s)"
+	NoSubscriptions = "" +
+		"У вас нет подписок"
+	Muted = "" +
+		"Уведомления отключены до %s 🔕\n" +
+		"\n" +
+		"Чтобы включить их раньше, введите /unmute"
+	MutedEvent = "" +
+		"Уведомления «%s» отключены до %s 🔕\n" +
+		"\n" +
+		"Чтобы включить их раньше, введите /unmute"
+	InvalidMute = "" +
+		"Не понял, на сколько отключить уведомления 🤔\n" +
+		"\n" +
+		"Например:\n" +
+		"/mute 30m\n" +
+		"/mute 2h\n" +
+		"/mute until tomorrow\n" +
+		"/mute until 18:00"
+	Unmuted = "" +
+		"Уведомления снова включены 🔔"
+	NotMuted = "" +
+		"Уведомления не были отключены"
+	ForeignContact = "" +
+		"Можно привязать только свой номер телефона 🙅"
+	Relink = "" +
+		"Нажмите 'Получать уведомления',\n" +
+		"чтобы привязать новый номер телефона.\n" +
+		"\n" +
+		"Старые номера останутся привязаны,\n" +
+		"отвязать их можно командой /links"
+	YourLinks = "" +
+		"Номера, привязанные к этому чату:\n" +
+		"\n" +
+		"%s\n" +
+		"\n" +
+		"🔕 - отключить уведомления номера\n" +
+		"🔔 - включить уведомления номера\n" +
+		"❌ - отвязать номер"
+	LinkLine         = "• %s"
+	DisabledLinkLine = "• %s (отключен)"
+	LinkEnabled      = "Уведомления для %s включены 🔔"
+	LinkDisabled     = "Уведомления для %s отключены 🔕"
+	Unlinked         = "Номер %s отвязан"
+	ChatLinked       = "" +
+		"Чат привязан к %s ✅\n" +
+		"\n" +
+		"Я буду присылать уведомления сюда\n"
+	InvalidLinkCode = "" +
+		"Код привязки неверный или устарел.\n" +
+		"Попросите администратора выдать новый"
+	ChatAlreadyLinked = "" +
+		"Этот чат уже привязан к номеру"
+	Stopped = "" +
+		"Телеграм отвязан от номеров %s\n" +
+		"\n" +
+		"Уведомления больше не будут приходить.\n" +
+		"Для повторной привязки введите /start"
 )
 
 func Format(m string, args ...interface{}) string {
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sonyamoonglade/notification-service/pkg/server"
+)
+
+//RouteFunc returns route pattern of the request, e.g. /api/events/fire/:eventName.
+//Patterns are used instead of paths to keep cardinality of route label bounded
+type RouteFunc func(r *http.Request) string
+
+//Instrument observes duration of every request handled by next in HTTPRequestDuration
+func Instrument(route RouteFunc, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := server.NewStatusRecorder(w)
+
+		next.ServeHTTP(sw, r)
+
+		HTTPRequestDuration.
+			WithLabelValues(r.Method, route(r), strconv.Itoa(sw.Status)).
+			Observe(time.Since(start).Seconds())
+	})
+}
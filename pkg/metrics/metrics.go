@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+//Path metrics are scraped from. It's served without api key, like /healthz
+const Path = "/metrics"
+
+const namespace = "notification"
+
+//ChannelTelegram is the only channel notifications are delivered to so far
+const ChannelTelegram = "telegram"
+
+//Statuses of deliveries. Suppressed ones are skipped because of mute (see entity.DeliverySuppressed)
+const (
+	DeliverySent        = "sent"
+	DeliveryFailed      = "failed"
+	DeliveryUnreachable = "unreachable"
+	DeliverySuppressed  = "suppressed"
+)
+
+//registry holds metrics of the service only, so tests and other packages don't collide on the default one
+var registry = prometheus.NewRegistry()
+
+var (
+	Fires = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fires_total",
+		Help:      "Fired events by event name and result.",
+	}, []string{"event", "result"})
+
+	Deliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deliveries_total",
+		Help:      "Notifications delivered to subscribers by channel and status.",
+	}, []string{"channel", "status"})
+
+	TelegramAPIErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_api_errors_total",
+		Help:      "Errors of telegram bot api requests by bot and error code. Code is 'transport' if telegram hasn't responded.",
+	}, []string{"bot", "code"})
+
+	BotUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bot_updates_total",
+		Help:      "Telegram updates processed by bot and update type.",
+	}, []string{"bot", "type"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of http requests by method, route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Fires,
+		Deliveries,
+		TelegramAPIErrors,
+		BotUpdates,
+		HTTPRequestDuration,
+	)
+}
+
+//Register adds collector to the registry served at Path, e.g. NewPoolCollector
+func Register(c prometheus.Collector) error {
+	return registry.Register(c)
+}
+
+//Handler serves metrics of the registry in prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
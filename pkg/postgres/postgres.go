@@ -2,21 +2,38 @@ package postgres
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+	"go.uber.org/zap"
 )
 
+//tenantRole is subject to row level security, unlike superuser the service usually connects with (see tenants migration)
+const tenantRole = "notification_tenant"
+
 type Postgres struct {
 	Pool *pgxpool.Pool
 }
 
-func New(ctx context.Context, dbURL string) (*Postgres, error) {
+func New(ctx context.Context, logger *zap.SugaredLogger, dbURL string, minConns int32, maxConns int32) (*Postgres, error) {
 	config, err := pgxpool.ParseConfig(dbURL)
 	if err != nil {
 		return nil, err
 	}
-	config.MinConns = 2
-	config.MaxConns = 4
+	config.MinConns = minConns
+	config.MaxConns = maxConns
+	config.BeforeAcquire = scopeToTenant(logger)
+	config.AfterRelease = resetTenant(logger)
+	config.ConnConfig.Logger = queryTracer{}
+	config.ConnConfig.LogLevel = pgx.LogLevelInfo
+
+	if err = checkTenantRole(ctx, config.ConnConfig); err != nil {
+		return nil, err
+	}
 
 	pool, err := pgxpool.ConnectConfig(ctx, config)
 	if err != nil {
@@ -29,3 +46,53 @@ func New(ctx context.Context, dbURL string) (*Postgres, error) {
 func (p *Postgres) CloseConn() {
 	p.Pool.Close()
 }
+
+//checkTenantRole makes sure the service might switch to tenantRole. Otherwise scopeToTenant fails on every connection
+//and pgxpool retries acquire forever, so it's checked once on its own connection before the pool is made
+func checkTenantRole(ctx context.Context, config *pgx.ConnConfig) error {
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	var member bool
+	err = conn.QueryRow(ctx, "SELECT pg_has_role(current_user, $1, 'MEMBER')", tenantRole).Scan(&member)
+	if err != nil {
+		return fmt.Errorf("could not check %s role. %w", tenantRole, err)
+	}
+	if member != true {
+		return fmt.Errorf("database user %s is not a member of %s role", config.User, tenantRole)
+	}
+	return nil
+}
+
+//scopeToTenant makes row level security policies see tenant of ctx. Without tenant in ctx no tenant rows are visible.
+//Connection which couldn't be scoped is destroyed and acquire is retried with another one
+func scopeToTenant(logger *zap.SugaredLogger) func(ctx context.Context, conn *pgx.Conn) bool {
+	return func(ctx context.Context, conn *pgx.Conn) bool {
+		var tenantID string
+		if id, ok := tenancy.FromContext(ctx); ok {
+			tenantID = strconv.FormatUint(id, 10)
+		}
+		_, err := conn.Exec(ctx, "SELECT set_config('app.tenant_id', $1, false), set_config('role', $2, false)",
+			tenantID, tenantRole)
+		if err != nil {
+			logging.FromContext(ctx, logger).Errorf("could not scope connection to tenant %q. %s", tenantID, err.Error())
+			return false
+		}
+		return true
+	}
+}
+
+//resetTenant makes sure released connection doesn't leak tenant to the next acquire
+func resetTenant(logger *zap.SugaredLogger) func(conn *pgx.Conn) bool {
+	return func(conn *pgx.Conn) bool {
+		_, err := conn.Exec(context.Background(), "SELECT set_config('app.tenant_id', '', false), set_config('role', 'none', false)")
+		if err != nil {
+			logger.Errorf("could not reset tenant of released connection. %s", err.Error())
+			return false
+		}
+		return true
+	}
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sonyamoonglade/notification-service/pkg/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//queryTracer turns pgx logs of finished queries into spans. pgx v4 has no tracing hooks, but logs every query
+//with its duration, so span is started back-dated. Only queries made within a trace are traced,
+//e.g. connection reset on release is not. Arguments aren't recorded, they might be personal data
+type queryTracer struct{}
+
+func (queryTracer) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	if trace.SpanContextFromContext(ctx).IsValid() != true {
+		return
+	}
+	sql, ok := data["sql"].(string)
+	if ok != true {
+		return
+	}
+	end := time.Now()
+	took, _ := data["time"].(time.Duration)
+
+	_, span := tracing.Start(ctx, "postgres "+msg,
+		trace.WithTimestamp(end.Add(-took)),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBStatementKey.String(sql)))
+
+	var err error
+	if level == pgx.LogLevelError {
+		err, ok = data["err"].(error)
+		if ok != true {
+			err = errors.New("query failed")
+		}
+	}
+	tracing.End(span, err, trace.WithTimestamp(end))
+}
@@ -0,0 +1,46 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+)
+
+const Header = "X-Request-ID"
+
+//Request ids of clients are kept as is if they look sane, so they can be traced across services
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type ctxKey struct{}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+//FromContext returns request id of ctx, empty string outside of http requests
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+//Wrap puts request id of X-Request-ID header, or generated one, to ctx of every request and to the response
+func Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if validID.MatchString(id) != true {
+			id = newID()
+		}
+
+		w.Header().Set(Header, id)
+		h.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+	})
+}
+
+func newID() string {
+	buf := make([]byte, 16)
+	//crypto/rand doesn't fail on supported platforms
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/sonyamoonglade/notification-service/pkg/response"
@@ -51,3 +52,41 @@ func TestJson(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, w.Header().Get("Content-Type"), "application/json")
 }
+
+type streamRow struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func (r streamRow) Record() []string {
+	return []string{r.Name, strconv.Itoa(r.Count)}
+}
+
+func TestStream(t *testing.T) {
+
+	rows := []streamRow{{Name: "a", Count: 1}, {Name: "b, c", Count: 2}}
+
+	w := httptest.NewRecorder()
+	s, err := response.NewStream(w, response.FormatCSV, "rows", []string{"name", "count"})
+	assert.NoError(t, err)
+	for _, r := range rows {
+		assert.NoError(t, s.Write(r))
+	}
+	assert.NoError(t, s.Flush())
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="rows.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "name,count\na,1\n\"b, c\",2\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	s, err = response.NewStream(w, response.FormatNDJSON, "rows", []string{"name", "count"})
+	assert.NoError(t, err)
+	for _, r := range rows {
+		assert.NoError(t, s.Write(r))
+	}
+	assert.NoError(t, s.Flush())
+
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"name\":\"a\",\"count\":1}\n{\"name\":\"b, c\",\"count\":2}\n", w.Body.String())
+}
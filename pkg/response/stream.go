@@ -0,0 +1,91 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+)
+
+//Formats of streamed responses
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+//streamFlushRows is how many rows are buffered before they're sent to client
+const streamFlushRows = 100
+
+//Row is a row of streamed response. Record is the row as csv record in order of the header of the stream
+type Row interface {
+	Record() []string
+}
+
+//Stream writes rows to client one by one, so exports of any size aren't kept in memory.
+//Status is sent with the first byte, so an error in the middle of the stream can only cut it short
+type Stream struct {
+	csv     *csv.Writer
+	json    *json.Encoder
+	flusher http.Flusher
+	rows    int
+}
+
+func IsStreamFormat(format string) bool {
+	return format == FormatCSV || format == FormatNDJSON
+}
+
+//NewStream starts attachment name in format. CSV starts with header
+func NewStream(w http.ResponseWriter, format string, name string, header []string) (*Stream, error) {
+	s := &Stream{}
+	s.flusher, _ = w.(http.Flusher)
+
+	switch format {
+	case FormatNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		s.json = json.NewEncoder(w)
+	default:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		s.csv = csv.NewWriter(w)
+		format = FormatCSV
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+"."+format+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	if s.csv != nil {
+		if err := s.csv.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Stream) Write(row Row) error {
+	var err error
+	if s.csv != nil {
+		err = s.csv.Write(row.Record())
+	} else {
+		err = s.json.Encode(row)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.rows++
+	if s.rows%streamFlushRows == 0 {
+		return s.Flush()
+	}
+	return nil
+}
+
+//Flush sends buffered rows to client
+func (s *Stream) Flush() error {
+	if s.csv != nil {
+		s.csv.Flush()
+		if err := s.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
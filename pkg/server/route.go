@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+//Unmatched is route of requests no route is registered for
+const Unmatched = "unmatched"
+
+//Route returns pattern of the route r is handled by, e.g. /api/events/fire/:eventName.
+//httprouter doesn't expose matched pattern, so values of params in the path are replaced back with their names
+func Route(router *httprouter.Router, r *http.Request) string {
+	pattern, _ := Match(router, r)
+	return pattern
+}
+
+//Match returns pattern of the route r is handled by and params of r, e.g. eventName
+func Match(router *httprouter.Router, r *http.Request) (string, httprouter.Params) {
+	path := r.URL.Path
+	handle, params, _ := router.Lookup(r.Method, path)
+	if handle == nil {
+		if v, ok := router.NotFound.(*verbRoutes); ok {
+			if _, ok := v.routes[r.Method+" "+path]; ok {
+				return path, nil
+			}
+		}
+		return Unmatched, nil
+	}
+	if len(params) == 0 {
+		return path, params
+	}
+
+	segments := strings.Split(path, "/")
+	i := 0
+	for _, p := range params {
+		for ; i < len(segments); i++ {
+			if segments[i] == p.Value {
+				segments[i] = ":" + p.Key
+				i++
+				break
+			}
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
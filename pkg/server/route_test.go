@@ -0,0 +1,37 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoute(t *testing.T) {
+
+	noop := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {}
+	router := httprouter.New()
+	router.POST("/api/events/fire/:eventName", noop)
+	router.DELETE("/api/groups/:groupId/members/:subscriberId", noop)
+	router.GET("/api/audit", noop)
+	server.HandleVerb(router, http.MethodPost, "/api/events/fire:batch", noop)
+
+	tests := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{http.MethodPost, "/api/events/fire/worker_login", "/api/events/fire/:eventName"},
+		{http.MethodDelete, "/api/groups/7/members/7", "/api/groups/:groupId/members/:subscriberId"},
+		{http.MethodGet, "/api/audit", "/api/audit"},
+		{http.MethodPost, "/api/events/fire:batch", "/api/events/fire:batch"},
+		{http.MethodGet, "/api/unknown", server.Unmatched},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, server.Route(router, httptest.NewRequest(tc.method, tc.path, nil)), tc.path)
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import "net/http"
+
+//StatusRecorder remembers status code written by handler, e.g. for metrics and traces of the request
+type StatusRecorder struct {
+	http.ResponseWriter
+	Status      int
+	Bytes       int
+	wroteHeader bool
+}
+
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	if sr, ok := w.(*StatusRecorder); ok {
+		return sr
+	}
+	return &StatusRecorder{ResponseWriter: w, Status: http.StatusOK}
+}
+
+func (w *StatusRecorder) WriteHeader(code int) {
+	if w.wroteHeader != true {
+		w.Status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *StatusRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.Bytes += n
+	return n, err
+}
+
+//Flush keeps streaming responses working through the recorder
+func (w *StatusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
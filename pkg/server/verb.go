@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+//verbRoutes serves paths with custom verbs like /api/events/fire:batch.
+//httprouter treats ':' as a start of named parameter anywhere in the path, so such paths can't be registered there.
+//verbRoutes is installed as router.NotFound handler, hence it is reached only when no regular route matched
+type verbRoutes struct {
+	routes   map[string]httprouter.Handle
+	notFound http.Handler
+}
+
+func HandleVerb(router *httprouter.Router, method string, path string, handle httprouter.Handle) {
+	v, ok := router.NotFound.(*verbRoutes)
+	if ok != true {
+		v = &verbRoutes{routes: make(map[string]httprouter.Handle), notFound: router.NotFound}
+		router.NotFound = v
+	}
+	v.routes[method+" "+path] = handle
+}
+
+func (v *verbRoutes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, ok := v.routes[r.Method+" "+r.URL.Path]
+	if ok {
+		h(w, r, nil)
+		return
+	}
+	if v.notFound != nil {
+		v.notFound.ServeHTTP(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
@@ -0,0 +1,45 @@
+package server_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleVerb(t *testing.T) {
+
+	router := httprouter.New()
+	router.POST("/api/events/fire/:eventName", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		w.Write([]byte("single " + p.ByName("eventName")))
+	})
+	server.HandleVerb(router, http.MethodPost, "/api/events/fire:batch", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Write([]byte("batch"))
+	})
+
+	tests := []struct {
+		method       string
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{http.MethodPost, "/api/events/fire:batch", http.StatusOK, "batch"},
+		{http.MethodPost, "/api/events/fire/worker_login", http.StatusOK, "single worker_login"},
+		{http.MethodGet, "/api/events/fire:batch", http.StatusNotFound, "404 page not found\n"},
+		{http.MethodPost, "/api/events/fire:unknown", http.StatusNotFound, "404 page not found\n"},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+		body, err := io.ReadAll(w.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedCode, w.Code, tc.path)
+		assert.Equal(t, tc.expectedBody, string(body), tc.path)
+	}
+}
@@ -0,0 +1,152 @@
+package signature
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+//Headers of signed request. Signature is hex HMAC-SHA256 of StringToSign with the secret of producer
+const (
+	ProducerHeader  = "X-Producer"
+	TimestampHeader = "X-Timestamp"
+	NonceHeader     = "X-Nonce"
+	SignatureHeader = "X-Signature"
+)
+
+const maxNonceLength = 64
+
+var (
+	ErrMissingSignature = errors.New("missing request signature")
+	ErrUnknownProducer  = errors.New("unknown producer")
+	ErrInvalidTimestamp = errors.New("invalid or expired signature timestamp")
+	ErrInvalidNonce     = errors.New("invalid signature nonce")
+	ErrReplayedNonce    = errors.New("signature nonce has already been used")
+	ErrInvalidSignature = errors.New("invalid request signature")
+	ErrForeignProducer  = errors.New("producer doesn't sign requests of the tenant")
+)
+
+//Producer signs requests of tenant with secret
+type Producer struct {
+	Secret   string
+	TenantID uint64
+}
+
+//Request is what's signed by producer
+type Request struct {
+	Producer  string
+	Timestamp string
+	Nonce     string
+	Signature string
+	Method    string
+	Path      string
+	Body      []byte
+}
+
+//StringToSign binds signature to the moment, the route and the body, so none of them might be changed or replayed
+func StringToSign(timestamp string, nonce string, method string, path string, body []byte) []byte {
+	s := timestamp + "\n" + nonce + "\n" + method + "\n" + path + "\n"
+	return append([]byte(s), body...)
+}
+
+//Sign returns hex signature of the request with secret
+func Sign(secret string, timestamp string, nonce string, method string, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(StringToSign(timestamp, nonce, method, path, body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//NonceStore remembers nonces of producers until they expire. It's shared by every replica of the service,
+//so request accepted by one of them is replayed for the others
+type NonceStore interface {
+	//ClaimNonce returns false if nonce of producer is already known and isn't expired by now
+	ClaimNonce(ctx context.Context, producer string, nonce string, expiresAt time.Time, now time.Time) (bool, error)
+	//PurgeNonces forgets nonces expired by now
+	PurgeNonces(ctx context.Context, now time.Time) error
+}
+
+//Verifier checks signatures of producers. Timestamp must be within skew from now and nonce is accepted once within the window
+type Verifier struct {
+	logger    *zap.SugaredLogger
+	producers map[string]Producer
+	skew      time.Duration
+	nonces    NonceStore
+	now       func() time.Time
+}
+
+//NewVerifier creates verifier of producers. producers is producer name -> producer
+func NewVerifier(logger *zap.SugaredLogger, producers map[string]Producer, skew time.Duration, nonces NonceStore) *Verifier {
+	return &Verifier{
+		logger:    logger,
+		producers: producers,
+		skew:      skew,
+		nonces:    nonces,
+		now:       time.Now,
+	}
+}
+
+//Verify returns tenant id of producer that has signed the request
+func (v *Verifier) Verify(ctx context.Context, req Request) (uint64, error) {
+	if req.Signature == "" {
+		return 0, ErrMissingSignature
+	}
+
+	producer, ok := v.producers[req.Producer]
+	if ok != true {
+		return 0, ErrUnknownProducer
+	}
+
+	unix, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidTimestamp
+	}
+	now := v.now()
+	ts := time.Unix(unix, 0)
+	if ts.Before(now.Add(-v.skew)) || ts.After(now.Add(v.skew)) {
+		return 0, ErrInvalidTimestamp
+	}
+
+	if req.Nonce == "" || len(req.Nonce) > maxNonceLength {
+		return 0, ErrInvalidNonce
+	}
+
+	expected := Sign(producer.Secret, req.Timestamp, req.Nonce, req.Method, req.Path, req.Body)
+	if hmac.Equal([]byte(expected), []byte(req.Signature)) != true {
+		return 0, ErrInvalidSignature
+	}
+
+	//Nonce is remembered only for valid signatures, otherwise anyone could burn nonces of producer.
+	//Requests older than skew are rejected by timestamp, so nonce is kept until then
+	ok, err = v.nonces.ClaimNonce(ctx, req.Producer, req.Nonce, ts.Add(v.skew), now)
+	if err != nil {
+		return 0, fmt.Errorf("could not claim signature nonce. %w", err)
+	}
+	if ok != true {
+		return 0, ErrReplayedNonce
+	}
+
+	return producer.TenantID, nil
+}
+
+//PurgeExpired forgets expired nonces every interval until ctx is done
+func (v *Verifier) PurgeExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := v.nonces.PurgeNonces(ctx, v.now()); err != nil {
+			v.logger.Errorf("could not purge signature nonces. %s", err.Error())
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package signature
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+//memoryNonces is NonceStore of replicas sharing one map
+type memoryNonces struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+func (m *memoryNonces) ClaimNonce(_ context.Context, producer string, nonce string, expiresAt time.Time, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := producer + ":" + nonce
+	if exp, ok := m.nonces[key]; ok && exp.After(now) {
+		return false, nil
+	}
+	m.nonces[key] = expiresAt
+	return true, nil
+}
+
+func (m *memoryNonces) PurgeNonces(_ context.Context, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, exp := range m.nonces {
+		if exp.After(now) != true {
+			delete(m.nonces, key)
+		}
+	}
+	return nil
+}
+
+func TestVerifier_Verify(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Unix(1666000000, 0)
+	nonces := &memoryNonces{nonces: make(map[string]time.Time)}
+	newVerifier := func() *Verifier {
+		v := NewVerifier(zap.NewNop().Sugar(), map[string]Producer{"delivery": {Secret: "secret", TenantID: 2}}, time.Minute*5, nonces)
+		v.now = func() time.Time { return now }
+		return v
+	}
+	v := newVerifier()
+
+	errOf := func(_ uint64, err error) error {
+		return err
+	}
+
+	path := "/api/events/fire/user_order_create"
+	body := []byte(`{"order_id":1}`)
+	ts := "1666000000"
+
+	signed := func(nonce string) Request {
+		return Request{
+			Producer:  "delivery",
+			Timestamp: ts,
+			Nonce:     nonce,
+			Signature: Sign("secret", ts, nonce, http.MethodPost, path, body),
+			Method:    http.MethodPost,
+			Path:      path,
+			Body:      body,
+		}
+	}
+
+	tenantID, err := v.Verify(ctx, signed("n1"))
+	assert.NoError(t, err)
+	//Tenant of producer is checked against tenant of api key by the caller
+	assert.Equal(t, uint64(2), tenantID)
+	assert.ErrorIs(t, errOf(v.Verify(ctx, signed("n1"))), ErrReplayedNonce)
+	assert.NoError(t, errOf(v.Verify(ctx, signed("n2"))))
+
+	//Nonce accepted by one replica is replayed for the others
+	assert.ErrorIs(t, errOf(newVerifier().Verify(ctx, signed("n2"))), ErrReplayedNonce)
+
+	req := signed("n3")
+	req.Signature = ""
+	assert.ErrorIs(t, errOf(v.Verify(ctx, req)), ErrMissingSignature)
+
+	req = signed("n3")
+	req.Producer = "unknown"
+	assert.ErrorIs(t, errOf(v.Verify(ctx, req)), ErrUnknownProducer)
+
+	req = signed("n3")
+	req.Body = []byte(`{"order_id":2}`)
+	assert.ErrorIs(t, errOf(v.Verify(ctx, req)), ErrInvalidSignature)
+
+	req = signed("n3")
+	req.Path = "/api/events/fire/worker_login"
+	assert.ErrorIs(t, errOf(v.Verify(ctx, req)), ErrInvalidSignature)
+
+	req = signed("")
+	assert.ErrorIs(t, errOf(v.Verify(ctx, req)), ErrInvalidNonce)
+
+	//Stale request signed correctly is rejected by timestamp
+	stale := "1665999000"
+	req = signed("n3")
+	req.Timestamp = stale
+	req.Signature = Sign("secret", stale, "n3", http.MethodPost, path, body)
+	assert.ErrorIs(t, errOf(v.Verify(ctx, req)), ErrInvalidTimestamp)
+
+	//Failed verifications don't burn the nonce
+	assert.NoError(t, errOf(v.Verify(ctx, signed("n3"))))
+}
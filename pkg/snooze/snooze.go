@@ -0,0 +1,63 @@
+package snooze
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSpec = errors.New("invalid snooze spec")
+
+//Until parses human-friendly snooze spec and returns the moment snooze ends.
+//Supported specs: "30m", "2h", "1d", "until tomorrow" ("до завтра"), "until 18:00" ("до 18:00").
+//Tomorrow and clock times are resolved in loc
+func Until(spec string, now time.Time, loc *time.Location) (time.Time, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" {
+		return time.Time{}, ErrInvalidSpec
+	}
+
+	local := now.In(loc)
+
+	for _, prefix := range []string{"until ", "до "} {
+		if strings.HasPrefix(spec, prefix) != true {
+			continue
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(spec, prefix))
+
+		if rest == "tomorrow" || rest == "завтра" {
+			y, m, d := local.Date()
+			return time.Date(y, m, d+1, 0, 0, 0, 0, loc), nil
+		}
+
+		clock, err := time.ParseInLocation("15:04", rest, loc)
+		if err != nil {
+			return time.Time{}, ErrInvalidSpec
+		}
+		y, m, d := local.Date()
+		until := time.Date(y, m, d, clock.Hour(), clock.Minute(), 0, 0, loc)
+		//Time of day which has already passed means the next day
+		if until.After(local) != true {
+			until = until.AddDate(0, 0, 1)
+		}
+		return until, nil
+	}
+
+	unit := spec[len(spec)-1:]
+	n, err := strconv.Atoi(spec[:len(spec)-1])
+	if err != nil || n <= 0 {
+		return time.Time{}, ErrInvalidSpec
+	}
+
+	switch unit {
+	case "m":
+		return now.Add(time.Minute * time.Duration(n)), nil
+	case "h":
+		return now.Add(time.Hour * time.Duration(n)), nil
+	case "d":
+		return now.AddDate(0, 0, n), nil
+	default:
+		return time.Time{}, ErrInvalidSpec
+	}
+}
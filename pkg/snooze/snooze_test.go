@@ -0,0 +1,39 @@
+package snooze_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sonyamoonglade/notification-service/pkg/snooze"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUntil(t *testing.T) {
+
+	loc := time.FixedZone("MSK", 3*60*60)
+	now := time.Date(2022, 9, 26, 14, 30, 0, 0, loc)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"30m", now.Add(time.Minute * 30)},
+		{"2h", now.Add(time.Hour * 2)},
+		{"1d", now.AddDate(0, 0, 1)},
+		{"until tomorrow", time.Date(2022, 9, 27, 0, 0, 0, 0, loc)},
+		{"до завтра", time.Date(2022, 9, 27, 0, 0, 0, 0, loc)},
+		{"until 18:00", time.Date(2022, 9, 26, 18, 0, 0, 0, loc)},
+		{"until 09:00", time.Date(2022, 9, 27, 9, 0, 0, 0, loc)},
+	}
+
+	for _, tc := range tests {
+		actual, err := snooze.Until(tc.spec, now, loc)
+		assert.NoError(t, err, tc.spec)
+		assert.True(t, tc.expected.Equal(actual), tc.spec)
+	}
+
+	for _, spec := range []string{"", "h", "0h", "-1h", "2w", "until never", "until 25:00"} {
+		_, err := snooze.Until(spec, now, loc)
+		assert.ErrorIs(t, err, snooze.ErrInvalidSpec, spec)
+	}
+}
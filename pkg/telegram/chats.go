@@ -0,0 +1,129 @@
+package telegram
+
+import (
+	"context"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/message"
+	"github.com/sonyamoonglade/notification-service/pkg/telegram_errors"
+)
+
+//Statuses of chat member
+const (
+	memberLeft          = "left"
+	memberKicked        = "kicked"
+	memberMember        = "member"
+	memberAdministrator = "administrator"
+)
+
+//handleChatUpdate handles updates of group, supergroup and channel chats
+func (t *telegramListener) handleChatUpdate(ctx context.Context, chat *tg.Chat, upd *tg.Update) {
+	m := upd.Message
+	//Channels send posts instead of messages
+	if m == nil {
+		m = upd.ChannelPost
+	}
+	if m == nil {
+		return
+	}
+
+	//Group has been upgraded to supergroup and got new id
+	if m.MigrateToChatID != 0 {
+		t.handleMigration(ctx, chat.ID, m.MigrateToChatID)
+		return
+	}
+
+	switch m.Command() {
+	case "link":
+		t.handleLink(ctx, chat, m.CommandArguments())
+		return
+	default:
+		//Ignore other messages...
+		return
+	}
+}
+
+func (t *telegramListener) handleLink(ctx context.Context, chat *tg.Chat, code string) {
+	if code == "" {
+		_ = t.bot.SoftSend(tg.NewMessage(chat.ID, message.InvalidLinkCode))
+		return
+	}
+
+	sub, err := t.subscriptionService.LinkTelegramChat(ctx, t.bot.Name(), code, chat.ID, chat.Type)
+	if err != nil {
+		switch true {
+		case errors.Is(err, telegram_errors.ErrInvalidLinkCode):
+			_ = t.bot.SoftSend(tg.NewMessage(chat.ID, message.InvalidLinkCode))
+		case errors.Is(err, telegram_errors.ErrTgChatAlreadyLinked):
+			_ = t.bot.SoftSend(tg.NewMessage(chat.ID, message.ChatAlreadyLinked))
+		default:
+			logging.FromContext(ctx, t.logger).Error(err.Error())
+			_ = t.bot.SoftSend(tg.NewMessage(chat.ID, message.SomethingWentWrong))
+		}
+		return
+	}
+	logging.FromContext(ctx, t.logger).Debugf("%s chat %d is linked with %s", chat.Type, chat.ID, sub.PhoneNumber)
+
+	text := message.Format(message.ChatLinked, sub.PhoneNumber)
+	_ = t.bot.SoftSend(tg.NewMessage(chat.ID, text))
+}
+
+func (t *telegramListener) handleMigration(ctx context.Context, fromChatID int64, toChatID int64) {
+	err := t.subscriptionService.MigrateTelegramChat(ctx, t.bot.Name(), fromChatID, toChatID)
+	if err != nil {
+		//Chat was never linked
+		if errors.Is(err, telegram_errors.ErrNoSuchTelegramSubscriber) {
+			return
+		}
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		return
+	}
+	logging.FromContext(ctx, t.logger).Infof("chat %d is migrated to supergroup %d", fromChatID, toChatID)
+}
+
+//handleMyChatMember tracks membership of the bot. In private chat "kicked" means user has blocked the bot
+//and "member" means user has unblocked it. Group, supergroup and channel chats bot is removed from are unlinked
+func (t *telegramListener) handleMyChatMember(ctx context.Context, member *tg.ChatMemberUpdated) {
+	status := member.NewChatMember.Status
+
+	if member.Chat.IsPrivate() {
+		switch status {
+		case memberKicked:
+			err := t.subscriptionService.DeactivateTelegramChat(ctx, t.bot.Name(), member.Chat.ID, telegram_errors.ReasonBlocked)
+			if err != nil {
+				logging.FromContext(ctx, t.logger).Error(err.Error())
+			}
+		case memberMember:
+			err := t.subscriptionService.ReactivateTelegramChat(ctx, t.bot.Name(), member.Chat.ID)
+			if err != nil && errors.Is(err, telegram_errors.ErrNoSuchTelegramSubscriber) != true {
+				logging.FromContext(ctx, t.logger).Error(err.Error())
+			}
+		}
+		return
+	}
+
+	//Bot is added back, e.g. after it was kicked while delivering
+	if status == memberMember || status == memberAdministrator {
+		err := t.subscriptionService.ReactivateTelegramChat(ctx, t.bot.Name(), member.Chat.ID)
+		if err != nil && errors.Is(err, telegram_errors.ErrNoSuchTelegramSubscriber) != true {
+			logging.FromContext(ctx, t.logger).Error(err.Error())
+		}
+		return
+	}
+
+	if status != memberLeft && status != memberKicked {
+		return
+	}
+
+	err := t.subscriptionService.UnlinkTelegramSubscriber(ctx, t.bot.Name(), member.Chat.ID)
+	if err != nil {
+		if errors.Is(err, telegram_errors.ErrNoSuchTelegramSubscriber) {
+			return
+		}
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		return
+	}
+	logging.FromContext(ctx, t.logger).Infof("bot is removed from %s chat %d, chat is unlinked", member.Chat.Type, member.Chat.ID)
+}
@@ -0,0 +1,355 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/pkg/bot"
+	"github.com/sonyamoonglade/notification-service/pkg/formatter"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/message"
+	"github.com/sonyamoonglade/notification-service/pkg/snooze"
+	"github.com/sonyamoonglade/notification-service/pkg/telegram_errors"
+)
+
+//linkedSubscriber returns telegram subscriber linked to chatID. If there's none, user is told to link phone first.
+//Chat might be linked with many phones, then the oldest enabled link is used
+func (t *telegramListener) linkedSubscriber(ctx context.Context, chatID int64) (*entity.TelegramSubscriber, bool) {
+	links, err := t.subscriptionService.GetTelegramLinksByTelegramID(ctx, t.bot.Name(), chatID)
+	if err != nil {
+		text := message.NotLinked
+		if errors.Is(err, telegram_errors.ErrNoSuchTelegramSubscriber) != true {
+			logging.FromContext(ctx, t.logger).Error(err.Error())
+			text = message.SomethingWentWrong
+		}
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, text))
+		return nil, false
+	}
+
+	for _, l := range links {
+		if l.Enabled {
+			return &l.TelegramSubscriber, true
+		}
+	}
+	return &links[0].TelegramSubscriber, true
+}
+
+func (t *telegramListener) isSelfSubscribable(e *entity.Event) bool {
+	return t.selfSubscribable[e.Name]
+}
+
+func (t *telegramListener) handleEvents(ctx context.Context, chatID int64) {
+	evnts, err := t.eventsService.GetAvailableEvents(ctx)
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, message.SomethingWentWrong))
+		return
+	}
+
+	lines := make([]string, 0, len(evnts))
+	for _, e := range evnts {
+		line := message.EventLine
+		if t.isSelfSubscribable(e) {
+			line = message.SelfSubscribableEventLine
+		}
+		lines = append(lines, message.Format(line, e.Translate))
+	}
+
+	text := message.Format(message.AvailableEvents, strings.Join(lines, "\n"))
+	_ = t.bot.SoftSend(tg.NewMessage(chatID, text))
+}
+
+func (t *telegramListener) handleSubscribe(ctx context.Context, chatID int64) {
+	tgsub, ok := t.linkedSubscriber(ctx, chatID)
+	if ok != true {
+		return
+	}
+
+	evnts, err := t.eventsService.GetAvailableEvents(ctx)
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, message.SomethingWentWrong))
+		return
+	}
+
+	subscribed, err := t.directlySubscribed(ctx, tgsub.SubscriberID)
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, message.SomethingWentWrong))
+		return
+	}
+
+	var candidates []*entity.Event
+	for _, e := range evnts {
+		if t.isSelfSubscribable(e) && subscribed[e.EventID] != true {
+			candidates = append(candidates, e)
+		}
+	}
+
+	if len(candidates) == 0 {
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, message.NothingToSubscribe))
+		return
+	}
+
+	msg := tg.NewMessage(chatID, message.ChooseToSubscribe)
+	msg.ReplyMarkup = t.bot.EventsKeyboard(bot.SubscribeCallbackPrefix, candidates)
+	_ = t.bot.SoftSend(msg)
+}
+
+func (t *telegramListener) handleUnsubscribe(ctx context.Context, chatID int64) {
+	tgsub, ok := t.linkedSubscriber(ctx, chatID)
+	if ok != true {
+		return
+	}
+
+	subEvents, err := t.subscriptionService.GetSubscriberEvents(ctx, tgsub.SubscriberID)
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, message.SomethingWentWrong))
+		return
+	}
+
+	//Subscriptions via groups and the ones made by admin for not self-subscribable events can't be cancelled in bot
+	var candidates []*entity.Event
+	for _, se := range subEvents {
+		e := se.Event
+		if se.GroupName == "" && t.isSelfSubscribable(&e) {
+			candidates = append(candidates, &e)
+		}
+	}
+
+	if len(candidates) == 0 {
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, message.NothingToUnsubscribe))
+		return
+	}
+
+	msg := tg.NewMessage(chatID, message.ChooseToUnsubscribe)
+	msg.ReplyMarkup = t.bot.EventsKeyboard(bot.UnsubscribeCallbackPrefix, candidates)
+	_ = t.bot.SoftSend(msg)
+}
+
+func (t *telegramListener) handleMySubs(ctx context.Context, chatID int64) {
+	tgsub, ok := t.linkedSubscriber(ctx, chatID)
+	if ok != true {
+		return
+	}
+
+	subEvents, err := t.subscriptionService.GetSubscriberEvents(ctx, tgsub.SubscriberID)
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, message.SomethingWentWrong))
+		return
+	}
+
+	if len(subEvents) == 0 {
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, message.NoSubscriptions))
+		return
+	}
+
+	lines := make([]string, 0, len(subEvents))
+	for _, se := range subEvents {
+		if se.GroupName != "" {
+			lines = append(lines, message.Format(message.GroupEventLine, se.Translate, se.GroupName))
+			continue
+		}
+		lines = append(lines, message.Format(message.EventLine, se.Translate))
+	}
+
+	text := message.Format(message.MySubscriptions, strings.Join(lines, "\n"))
+	_ = t.bot.SoftSend(tg.NewMessage(chatID, text))
+}
+
+//handleStop removes every link of the chat
+func (t *telegramListener) handleStop(ctx context.Context, chatID int64) {
+	links, err := t.subscriptionService.GetTelegramLinksByTelegramID(ctx, t.bot.Name(), chatID)
+	if err != nil {
+		text := message.NotLinked
+		if errors.Is(err, telegram_errors.ErrNoSuchTelegramSubscriber) != true {
+			logging.FromContext(ctx, t.logger).Error(err.Error())
+			text = message.SomethingWentWrong
+		}
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, text))
+		return
+	}
+
+	phones := make([]string, 0, len(links))
+	for _, l := range links {
+		phones = append(phones, l.PhoneNumber)
+	}
+
+	err = t.subscriptionService.UnlinkTelegramSubscriber(ctx, t.bot.Name(), chatID)
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, message.SomethingWentWrong))
+		return
+	}
+	logging.FromContext(ctx, t.logger).Debugf("telegram %d is unlinked from %s", chatID, strings.Join(phones, ", "))
+
+	msg := tg.NewMessage(chatID, message.Format(message.Stopped, strings.Join(phones, ", ")))
+	msg.ReplyMarkup = tg.NewRemoveKeyboard(true)
+	_ = t.bot.SoftSend(msg)
+}
+
+//handleMute handles "/mute <spec> [event_name]", e.g. "/mute 2h" or "/mute until tomorrow worker_login"
+func (t *telegramListener) handleMute(ctx context.Context, chatID int64, args string) {
+	tgsub, ok := t.linkedSubscriber(ctx, chatID)
+	if ok != true {
+		return
+	}
+
+	now := time.Now()
+	var eventID *uint64
+	var event *entity.Event
+
+	until, err := snooze.Until(args, now, t.loc)
+	//Maybe the last argument is event name
+	if err != nil {
+		fields := strings.Fields(args)
+		if len(fields) < 2 {
+			_ = t.bot.SoftSend(tg.NewMessage(chatID, message.InvalidMute))
+			return
+		}
+		eventName := fields[len(fields)-1]
+		until, err = snooze.Until(strings.Join(fields[:len(fields)-1], " "), now, t.loc)
+		if err != nil {
+			_ = t.bot.SoftSend(tg.NewMessage(chatID, message.InvalidMute))
+			return
+		}
+
+		id, err := t.eventsService.DoesExist(ctx, eventName)
+		if err != nil {
+			if errors.Is(err, http_errors.ErrEventDoesNotExist) != true {
+				logging.FromContext(ctx, t.logger).Error(err.Error())
+			}
+			_ = t.bot.SoftSend(tg.NewMessage(chatID, message.InvalidMute))
+			return
+		}
+		eventID = &id
+
+		event, err = t.findEvent(ctx, id)
+		if err != nil || event == nil {
+			_ = t.bot.SoftSend(tg.NewMessage(chatID, message.SomethingWentWrong))
+			return
+		}
+	}
+
+	err = t.subscriptionService.Mute(ctx, tgsub.SubscriberID, eventID, until)
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, message.SomethingWentWrong))
+		return
+	}
+
+	fmtUntil := until.In(t.loc).Format(formatter.TimeFormat)
+	text := message.Format(message.Muted, fmtUntil)
+	if event != nil {
+		text = message.Format(message.MutedEvent, event.Translate, fmtUntil)
+	}
+	_ = t.bot.SoftSend(tg.NewMessage(chatID, text))
+}
+
+//handleUnmute removes every mute window of the subscriber
+func (t *telegramListener) handleUnmute(ctx context.Context, chatID int64) {
+	tgsub, ok := t.linkedSubscriber(ctx, chatID)
+	if ok != true {
+		return
+	}
+
+	text := message.Unmuted
+	err := t.subscriptionService.Unmute(ctx, tgsub.SubscriberID, nil)
+	if err != nil {
+		if errors.Is(err, http_errors.ErrNotMuted) != true {
+			logging.FromContext(ctx, t.logger).Error(err.Error())
+			_ = t.bot.SoftSend(tg.NewMessage(chatID, message.SomethingWentWrong))
+			return
+		}
+		text = message.NotMuted
+	}
+
+	_ = t.bot.SoftSend(tg.NewMessage(chatID, text))
+}
+
+//handleSubscriptionCallback handles buttons of /subscribe and /unsubscribe
+func (t *telegramListener) handleSubscriptionCallback(ctx context.Context, cb *tg.CallbackQuery, eventID uint64, subscribe bool) {
+	//Buttons of inline messages come without the message, so there's no chat to find the subscriber by
+	if cb.Message == nil {
+		logging.FromContext(ctx, t.logger).Debugf("subscription callback %s without message", cb.ID)
+		_ = t.bot.AnswerCallback(cb.ID, message.SomethingWentWrong)
+		return
+	}
+	chatID := cb.Message.Chat.ID
+
+	tgsub, ok := t.linkedSubscriber(ctx, chatID)
+	if ok != true {
+		_ = t.bot.AnswerCallback(cb.ID, "")
+		return
+	}
+
+	event, err := t.findEvent(ctx, eventID)
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		_ = t.bot.AnswerCallback(cb.ID, message.SomethingWentWrong)
+		return
+	}
+
+	//Allow-list might have changed since the keyboard was sent
+	if event == nil || t.isSelfSubscribable(event) != true {
+		_ = t.bot.AnswerCallback(cb.ID, message.NotSelfSubscribable)
+		return
+	}
+
+	var text string
+	if subscribe {
+		err = t.subscriptionService.SubscribeToEvent(ctx, tgsub.SubscriberID, eventID)
+		text = message.Format(message.Subscribed, event.Translate)
+		if errors.Is(err, http_errors.ErrSubscriptionAlreadyExists) {
+			err = nil
+			text = message.Format(message.AlreadySubscribed, event.Translate)
+		}
+	} else {
+		err = t.subscriptionService.Unsubscribe(ctx, tgsub.SubscriberID, eventID)
+		text = message.Format(message.Unsubscribed, event.Translate)
+		if errors.Is(err, http_errors.ErrSubscriptionDoesNotExist) {
+			err = nil
+		}
+	}
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		text = message.SomethingWentWrong
+	}
+
+	_ = t.bot.AnswerCallback(cb.ID, text)
+	_ = t.bot.SoftSend(tg.NewMessage(chatID, text))
+}
+
+func (t *telegramListener) directlySubscribed(ctx context.Context, subscriberID uint64) (map[uint64]bool, error) {
+	subEvents, err := t.subscriptionService.GetSubscriberEvents(ctx, subscriberID)
+	if err != nil {
+		return nil, err
+	}
+	subscribed := make(map[uint64]bool, len(subEvents))
+	for _, se := range subEvents {
+		if se.GroupName == "" {
+			subscribed[se.EventID] = true
+		}
+	}
+	return subscribed, nil
+}
+
+func (t *telegramListener) findEvent(ctx context.Context, eventID uint64) (*entity.Event, error) {
+	evnts, err := t.eventsService.GetAvailableEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range evnts {
+		if e.EventID == eventID {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
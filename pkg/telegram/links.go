@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/subscription/response_object"
+	"github.com/sonyamoonglade/notification-service/pkg/bot"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"github.com/sonyamoonglade/notification-service/pkg/message"
+	"github.com/sonyamoonglade/notification-service/pkg/telegram_errors"
+)
+
+//handleLinks lists phones linked with the chat with buttons to enable, disable or unlink each of them
+func (t *telegramListener) handleLinks(ctx context.Context, chatID int64) {
+	links, err := t.subscriptionService.GetTelegramLinksByTelegramID(ctx, t.bot.Name(), chatID)
+	if err != nil {
+		text := message.NotLinked
+		if errors.Is(err, telegram_errors.ErrNoSuchTelegramSubscriber) != true {
+			logging.FromContext(ctx, t.logger).Error(err.Error())
+			text = message.SomethingWentWrong
+		}
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, text))
+		return
+	}
+
+	msg := tg.NewMessage(chatID, linksText(links))
+	msg.ReplyMarkup = t.bot.LinksKeyboard(links)
+	_ = t.bot.SoftSend(msg)
+}
+
+//handleRelink asks to share the new phone. Sharing it adds one more link
+func (t *telegramListener) handleRelink(chatID int64) {
+	msg := tg.NewMessage(chatID, message.Relink)
+	msg.ReplyMarkup = t.bot.StartKeyboard()
+	_ = t.bot.SoftSend(msg)
+}
+
+//handleLinkCallback handles buttons of /links. Only links of the chat the button is pressed in can be changed
+func (t *telegramListener) handleLinkCallback(ctx context.Context, cb *tg.CallbackQuery, linkID uint64, action string) {
+	//Buttons of inline messages come without the message, so there's no chat to check the link against
+	if cb.Message == nil {
+		logging.FromContext(ctx, t.logger).Debugf("link callback %s without message", cb.ID)
+		_ = t.bot.AnswerCallback(cb.ID, message.SomethingWentWrong)
+		return
+	}
+	chatID := cb.Message.Chat.ID
+
+	link, err := t.subscriptionService.GetTelegramLink(ctx, linkID)
+	if err != nil {
+		if errors.Is(err, http_errors.ErrTelegramLinkDoesNotExist) != true {
+			logging.FromContext(ctx, t.logger).Error(err.Error())
+			_ = t.bot.AnswerCallback(cb.ID, message.SomethingWentWrong)
+			return
+		}
+		_ = t.bot.AnswerCallback(cb.ID, "")
+		return
+	}
+	if link.TelegramID != chatID || link.BotName != t.bot.Name() {
+		_ = t.bot.AnswerCallback(cb.ID, "")
+		return
+	}
+
+	var text string
+	switch action {
+	case bot.EnableLinkCallbackPrefix:
+		err = t.subscriptionService.SetTelegramLinkEnabled(ctx, linkID, true)
+		text = message.Format(message.LinkEnabled, link.PhoneNumber)
+	case bot.DisableLinkCallbackPrefix:
+		err = t.subscriptionService.SetTelegramLinkEnabled(ctx, linkID, false)
+		text = message.Format(message.LinkDisabled, link.PhoneNumber)
+	case bot.UnlinkCallbackPrefix:
+		err = t.subscriptionService.UnlinkTelegramLink(ctx, linkID)
+		text = message.Format(message.Unlinked, link.PhoneNumber)
+	}
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Error(err.Error())
+		text = message.SomethingWentWrong
+	} else {
+		logging.FromContext(ctx, t.logger).Debugf("link %d of telegram %d: %s", linkID, chatID, strings.TrimSuffix(action, ":"))
+	}
+
+	_ = t.bot.AnswerCallback(cb.ID, text)
+	_ = t.bot.SoftSend(tg.NewMessage(chatID, text))
+}
+
+func linksText(links []*response_object.TelegramLinkRO) string {
+	lines := make([]string, 0, len(links))
+	for _, l := range links {
+		line := message.LinkLine
+		if l.Enabled != true {
+			line = message.DisabledLinkLine
+		}
+		lines = append(lines, message.Format(line, l.PhoneNumber))
+	}
+	return message.Format(message.YourLinks, strings.Join(lines, "\n"))
+}
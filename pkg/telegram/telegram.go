@@ -2,16 +2,23 @@ package telegram
 
 import (
 	"context"
+	"strconv"
 	"strings"
 	"time"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/internal/escalation"
+	"github.com/sonyamoonglade/notification-service/internal/events"
 	"github.com/sonyamoonglade/notification-service/internal/subscription"
+	"github.com/sonyamoonglade/notification-service/pkg/actor"
 	"github.com/sonyamoonglade/notification-service/pkg/bot"
 	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
 	"github.com/sonyamoonglade/notification-service/pkg/message"
+	"github.com/sonyamoonglade/notification-service/pkg/metrics"
 	"github.com/sonyamoonglade/notification-service/pkg/telegram_errors"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
 	"go.uber.org/zap"
 )
 
@@ -19,21 +26,62 @@ type Listener interface {
 	ListenForUpdates()
 	handleContact(ctx context.Context, chatID int64, cnt *tg.Contact)
 	handleMessage(ctx context.Context, chatID int64, msg *tg.Message)
+	handleCallback(ctx context.Context, cb *tg.CallbackQuery)
+	handleLinkCallback(ctx context.Context, cb *tg.CallbackQuery, linkID uint64, action string)
+	handleChatUpdate(ctx context.Context, chat *tg.Chat, upd *tg.Update)
+	handleMyChatMember(ctx context.Context, member *tg.ChatMemberUpdated)
 	mapUpdate(upd *tg.Update)
 }
 
 type telegramListener struct {
-	logger              *zap.SugaredLogger
-	bot                 bot.Bot
+	logger *zap.SugaredLogger
+	bot    bot.Bot
+	//tenantID is tenant of the bot. Every update is handled within it
+	tenantID            uint64
 	subscriptionService subscription.Service
+	escalationService   escalation.Service
+	eventsService       events.Service
+	//selfSubscribable is allow-list of event names subscribers can subscribe to by themselves
+	selfSubscribable map[string]bool
+	//loc is used to resolve human-friendly times like "until tomorrow"
+	loc *time.Location
 }
 
-func NewTelegramListener(logger *zap.SugaredLogger, bot bot.Bot, subscriptionService subscription.Service) Listener {
-	return &telegramListener{logger: logger, bot: bot, subscriptionService: subscriptionService}
+func NewTelegramListener(logger *zap.SugaredLogger,
+	bot bot.Bot,
+	tenantID uint64,
+	subscriptionService subscription.Service,
+	escalationService escalation.Service,
+	eventsService events.Service,
+	selfSubscribableEvents []string,
+	loc *time.Location) Listener {
+
+	selfSubscribable := make(map[string]bool, len(selfSubscribableEvents))
+	for _, name := range selfSubscribableEvents {
+		selfSubscribable[name] = true
+	}
+
+	return &telegramListener{
+		logger:              logger,
+		bot:                 bot,
+		tenantID:            tenantID,
+		subscriptionService: subscriptionService,
+		escalationService:   escalationService,
+		eventsService:       eventsService,
+		selfSubscribable:    selfSubscribable,
+		loc:                 loc,
+	}
 }
 
 func (t *telegramListener) handleContact(ctx context.Context, chatID int64, cnt *tg.Contact) {
 
+	//Private chat id is user id. Forwarded contacts of other people can't be linked
+	if cnt.UserID != chatID {
+		logging.FromContext(ctx, t.logger).Debugf("foreign contact is sent to %d", chatID)
+		_ = t.bot.SoftSend(tg.NewMessage(chatID, message.ForeignContact))
+		return
+	}
+
 	phoneNumber := cnt.PhoneNumber
 
 	//Make sure phoneNumber startsWith '+'
@@ -48,7 +96,7 @@ func (t *telegramListener) handleContact(ctx context.Context, chatID int64, cnt
 		if errors.Is(err, http_errors.ErrSubscriberDoesNotExist) {
 			text := message.Format(message.NoSuchSubscriber, phoneNumber)
 			msg := tg.NewMessage(chatID, text)
-			t.logger.Debugf("no such subscriber %s", phoneNumber)
+			logging.FromContext(ctx, t.logger).Debugf("no such subscriber %s", phoneNumber)
 
 			err := t.bot.SoftSend(msg)
 			if err != nil {
@@ -57,7 +105,7 @@ func (t *telegramListener) handleContact(ctx context.Context, chatID int64, cnt
 			return
 		}
 		//Some internal error
-		t.logger.Error(err.Error())
+		logging.FromContext(ctx, t.logger).Error(err.Error())
 		msg := tg.NewMessage(chatID, message.SomethingWentWrong)
 		err := t.bot.SoftSend(msg)
 		if err != nil {
@@ -75,8 +123,8 @@ func (t *telegramListener) handleContact(ctx context.Context, chatID int64, cnt
 	}
 
 	//Try register telegramSubscriber (might fail with ErrTelegramSubscriberExists error)
-	//In this step we assign chatID of the user with phoneNumber of subscriber
-	err = t.subscriptionService.RegisterTelegramSubscriber(ctx, chatID, sub.SubscriberID)
+	//In this step we link chatID of the user with phoneNumber of subscriber. Links with other phones stay untouched
+	err = t.subscriptionService.RegisterTelegramSubscriber(ctx, t.bot.Name(), chatID, sub.SubscriberID)
 	if err != nil {
 		//Bot already knows specified telegramSubscriber by given phoneNumber
 		if errors.Is(err, telegram_errors.ErrTgSubscriberAlreadyExists) {
@@ -90,7 +138,7 @@ func (t *telegramListener) handleContact(ctx context.Context, chatID int64, cnt
 			//End execution
 			return
 		}
-		t.logger.Error(err.Error())
+		logging.FromContext(ctx, t.logger).Error(err.Error())
 		//Something went wrong internally
 		msg := tg.NewMessage(chatID, message.SomethingWentWrong)
 
@@ -113,8 +161,8 @@ func (t *telegramListener) handleContact(ctx context.Context, chatID int64, cnt
 
 func (t *telegramListener) handleMessage(ctx context.Context, chatID int64, m *tg.Message) {
 
-	switch m.Text {
-	case "/start":
+	switch m.Command() {
+	case "start":
 		startKb := t.bot.StartKeyboard()
 		msg := tg.NewMessage(chatID, message.StartMessage)
 		msg.ReplyMarkup = startKb
@@ -128,6 +176,33 @@ func (t *telegramListener) handleMessage(ctx context.Context, chatID int64, m *t
 			return
 		}
 
+		return
+	case "events":
+		t.handleEvents(ctx, chatID)
+		return
+	case "subscribe":
+		t.handleSubscribe(ctx, chatID)
+		return
+	case "unsubscribe":
+		t.handleUnsubscribe(ctx, chatID)
+		return
+	case "mysubs":
+		t.handleMySubs(ctx, chatID)
+		return
+	case "stop":
+		t.handleStop(ctx, chatID)
+		return
+	case "links", "unlink":
+		t.handleLinks(ctx, chatID)
+		return
+	case "relink":
+		t.handleRelink(chatID)
+		return
+	case "mute":
+		t.handleMute(ctx, chatID, m.CommandArguments())
+		return
+	case "unmute":
+		t.handleUnmute(ctx, chatID)
 		return
 	default:
 		//Ignore other messages...
@@ -136,16 +211,114 @@ func (t *telegramListener) handleMessage(ctx context.Context, chatID int64, m *t
 
 }
 
-func (t *telegramListener) mapUpdate(upd *tg.Update) {
-	isGroup := upd.FromChat().IsGroup()
-	if isGroup {
-		//Ignore group messages at all
+func (t *telegramListener) handleCallback(ctx context.Context, cb *tg.CallbackQuery) {
+
+	switch true {
+	case strings.HasPrefix(cb.Data, bot.SubscribeCallbackPrefix):
+		eventID, err := strconv.ParseUint(strings.TrimPrefix(cb.Data, bot.SubscribeCallbackPrefix), 10, 64)
+		if err != nil {
+			logging.FromContext(ctx, t.logger).Debugf("invalid subscribe callback data %s", cb.Data)
+			return
+		}
+		t.handleSubscriptionCallback(ctx, cb, eventID, true)
+		return
+	case strings.HasPrefix(cb.Data, bot.UnsubscribeCallbackPrefix):
+		eventID, err := strconv.ParseUint(strings.TrimPrefix(cb.Data, bot.UnsubscribeCallbackPrefix), 10, 64)
+		if err != nil {
+			logging.FromContext(ctx, t.logger).Debugf("invalid unsubscribe callback data %s", cb.Data)
+			return
+		}
+		t.handleSubscriptionCallback(ctx, cb, eventID, false)
+		return
+	case strings.HasPrefix(cb.Data, bot.EnableLinkCallbackPrefix),
+		strings.HasPrefix(cb.Data, bot.DisableLinkCallbackPrefix),
+		strings.HasPrefix(cb.Data, bot.UnlinkCallbackPrefix):
+		//Prefix is everything up to ':' inclusive
+		sep := strings.Index(cb.Data, ":") + 1
+		linkID, err := strconv.ParseUint(cb.Data[sep:], 10, 64)
+		if err != nil {
+			logging.FromContext(ctx, t.logger).Debugf("invalid link callback data %s", cb.Data)
+			return
+		}
+		t.handleLinkCallback(ctx, cb, linkID, cb.Data[:sep])
+		return
+	case strings.HasPrefix(cb.Data, bot.AckCallbackPrefix) != true:
+		//Ignore unknown buttons...
+		return
+	}
+
+	escalationID, err := strconv.ParseUint(strings.TrimPrefix(cb.Data, bot.AckCallbackPrefix), 10, 64)
+	if err != nil {
+		logging.FromContext(ctx, t.logger).Debugf("invalid ack callback data %s", cb.Data)
 		return
 	}
-	chatID := upd.FromChat().ID
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	text := message.Acknowledged
+	err = t.escalationService.Acknowledge(ctx, escalationID, cb.From.ID)
+	if err != nil {
+		//Someone else was faster or escalation is exhausted
+		if errors.Is(err, http_errors.ErrEscalationAlreadyHandled) != true {
+			logging.FromContext(ctx, t.logger).Error(err.Error())
+			text = message.SomethingWentWrong
+		} else {
+			text = message.AlreadyHandled
+		}
+	} else {
+		logging.FromContext(ctx, t.logger).Debugf("escalation %d is acknowledged by %d", escalationID, cb.From.ID)
+	}
+
+	err = t.bot.AnswerCallback(cb.ID, text)
+	if err != nil {
+		return
+	}
+}
+
+func (t *telegramListener) mapUpdate(upd *tg.Update) {
+	ctx, cancel := context.WithTimeout(tenancy.WithID(context.Background(), t.tenantID), time.Second*3)
 	defer cancel()
+
+	metrics.BotUpdates.WithLabelValues(t.bot.Name(), updateType(upd)).Inc()
+
+	//Logs of handlers and services are correlated by update
+	fields := []interface{}{"bot", t.bot.Name(), "update_id", upd.UpdateID, "update_type", updateType(upd)}
+	if chat := upd.FromChat(); chat != nil {
+		fields = append(fields, "chat_id", chat.ID)
+	}
+	ctx = logging.WithLogger(ctx, t.logger.With(fields...))
+
+	//Changes made by bot commands and buttons are audited as made by the telegram user
+	if from := upd.SentFrom(); from != nil {
+		ctx = actor.With(ctx, actor.Actor{
+			Type: actor.TypeTelegram,
+			ID:   strconv.FormatInt(from.ID, 10),
+			Name: from.UserName,
+		})
+	}
+
+	//Bot was added to or removed from the chat
+	if upd.MyChatMember != nil {
+		t.handleMyChatMember(ctx, upd.MyChatMember)
+		return
+	}
+
+	chat := upd.FromChat()
+	if chat == nil {
+		return
+	}
+
+	//Acknowledge buttons might be pressed in group chats as well
+	if upd.CallbackQuery != nil {
+		t.handleCallback(ctx, upd.CallbackQuery)
+		return
+	}
+
+	//Group, supergroup and channel chats are only linked via /link and receive notifications
+	if chat.IsPrivate() != true {
+		t.handleChatUpdate(ctx, chat, upd)
+		return
+	}
+	chatID := chat.ID
+
 	switch true {
 	case upd.Message != nil && upd.Message.Contact != nil:
 		t.handleContact(ctx, chatID, upd.Message.Contact)
@@ -156,6 +329,28 @@ func (t *telegramListener) mapUpdate(upd *tg.Update) {
 	}
 }
 
+//updateType names kind of upd for metrics
+func updateType(upd *tg.Update) string {
+	switch true {
+	case upd.MyChatMember != nil:
+		return "my_chat_member"
+	case upd.CallbackQuery != nil:
+		return "callback_query"
+	case upd.Message != nil && upd.Message.Contact != nil:
+		return "contact"
+	case upd.Message != nil && upd.Message.IsCommand():
+		return "command"
+	case upd.Message != nil:
+		return "message"
+	case upd.EditedMessage != nil:
+		return "edited_message"
+	case upd.ChannelPost != nil:
+		return "channel_post"
+	default:
+		return "other"
+	}
+}
+
 func (t *telegramListener) ListenForUpdates() {
 	cl := t.bot.GetClient()
 
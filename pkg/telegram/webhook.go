@@ -0,0 +1,70 @@
+package telegram
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/pkg/bot"
+	"github.com/sonyamoonglade/notification-service/pkg/logging"
+	"go.uber.org/zap"
+)
+
+//WebhookPath is the route telegram sends updates of the default bot to in webhook mode
+const WebhookPath = "/api/telegram/webhook"
+
+//WebhookPathFor is the route of the bot. Each bot has its own route, so updates are dispatched to its listener
+func WebhookPathFor(botName string) string {
+	if botName == bot.DefaultName {
+		return WebhookPath
+	}
+	return WebhookPath + "/" + botName
+}
+
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+//Webhook receives updates pushed by telegram and dispatches them the same way as long polling does.
+//Unlike polling, any number of replicas might receive updates at the same time
+type Webhook struct {
+	logger   *zap.SugaredLogger
+	listener Listener
+	botName  string
+	secret   string
+}
+
+func NewWebhook(logger *zap.SugaredLogger, listener Listener, botName string, secret string) *Webhook {
+	return &Webhook{
+		logger:   logger,
+		listener: listener,
+		botName:  botName,
+		secret:   secret,
+	}
+}
+
+func (wh *Webhook) InitRoutes(router *httprouter.Router) {
+	router.POST(WebhookPathFor(wh.botName), wh.HandleUpdate)
+}
+
+func (wh *Webhook) HandleUpdate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+
+	token := r.Header.Get(secretTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(wh.secret)) != 1 {
+		logging.FromContext(r.Context(), wh.logger).Warnf("webhook update with invalid secret token from %s", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var upd tg.Update
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		logging.FromContext(r.Context(), wh.logger).Error(err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	//Telegram waits for the response before sending next update, so updates of the chat stay in order
+	wh.listener.mapUpdate(&upd)
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,128 @@
+package telegram_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sonyamoonglade/notification-service/pkg/bot"
+	"github.com/sonyamoonglade/notification-service/pkg/telegram"
+	"github.com/sonyamoonglade/notification-service/pkg/tenancy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	token  = "123:test"
+	secret = "s3cr3t_token"
+)
+
+//fakeTelegram is local telegram api server. It records called methods with their form values
+type fakeTelegram struct {
+	mu    sync.Mutex
+	calls map[string][]map[string]string
+}
+
+func newFakeTelegram(t *testing.T) (*fakeTelegram, *httptest.Server) {
+	f := &fakeTelegram{calls: make(map[string][]map[string]string)}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//Path is /bot<token>/<method>
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if len(parts) != 2 || parts[0] != "bot"+token {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		method := parts[1]
+		require.NoError(t, r.ParseForm())
+
+		values := make(map[string]string, len(r.PostForm))
+		for k := range r.PostForm {
+			values[k] = r.PostForm.Get(k)
+		}
+		f.mu.Lock()
+		f.calls[method] = append(f.calls[method], values)
+		f.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch method {
+		case "getMe":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"id":123,"is_bot":true,"first_name":"test","username":"test_bot"}}`))
+		case "sendMessage":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":42,"type":"private"}}}`))
+		default:
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return f, srv
+}
+
+func (f *fakeTelegram) called(method string) []map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func TestWebhook(t *testing.T) {
+
+	logger := zap.NewNop().Sugar()
+	fake, srv := newFakeTelegram(t)
+
+	appBot, err := bot.NewBot(bot.DefaultName, token, srv.URL+"/bot%s/%s", logger)
+	require.NoError(t, err)
+
+	err = appBot.SetWebhook("https://notifications.example.com"+telegram.WebhookPath, secret)
+	require.NoError(t, err)
+
+	setCalls := fake.called("setWebhook")
+	require.Len(t, setCalls, 1)
+	assert.Equal(t, "https://notifications.example.com/api/telegram/webhook", setCalls[0]["url"])
+	assert.Equal(t, secret, setCalls[0]["secret_token"])
+
+	//Services are not needed to answer /start
+	listener := telegram.NewTelegramListener(logger, appBot, tenancy.DefaultID, nil, nil, nil, nil, time.UTC)
+	router := httprouter.New()
+	telegram.NewWebhook(logger, listener, bot.DefaultName, secret).InitRoutes(router)
+
+	update := []byte(`{"update_id":1,"message":{"message_id":1,"date":0,"text":"/start",
+		"entities":[{"type":"bot_command","offset":0,"length":6}],
+		"chat":{"id":42,"type":"private"},"from":{"id":42,"is_bot":false,"first_name":"user"}}}`)
+
+	tests := []struct {
+		name     string
+		token    string
+		expected int
+		sent     int
+	}{
+		{"missing secret", "", http.StatusUnauthorized, 0},
+		{"invalid secret", "wrong", http.StatusUnauthorized, 0},
+		{"valid secret", secret, http.StatusOK, 1},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodPost, telegram.WebhookPath, bytes.NewReader(update))
+		if tc.token != "" {
+			req.Header.Set("X-Telegram-Bot-Api-Secret-Token", tc.token)
+		}
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.expected, w.Code, tc.name)
+		assert.Len(t, fake.called("sendMessage"), tc.sent, tc.name)
+	}
+
+	assert.Equal(t, "42", fake.called("sendMessage")[0]["chat_id"])
+
+	err = appBot.DeleteWebhook()
+	require.NoError(t, err)
+	assert.Len(t, fake.called("deleteWebhook"), 1)
+}
@@ -1,6 +1,72 @@
 package telegram_errors
 
-import "github.com/pkg/errors"
+import (
+	"fmt"
+	"net/http"
+	"strings"
 
-var ErrNoSuchTelegramSubscriber = errors.New("no such telegram subscriber")
-var ErrTgSubscriberAlreadyExists = errors.New("telegram subscriber already exists")
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
+)
+
+var ErrNoSuchTelegramSubscriber = http_errors.New("telegram_subscriber_not_found", http.StatusNotFound, "no such telegram subscriber")
+var ErrTgSubscriberAlreadyExists = http_errors.New("telegram_subscriber_already_exists", http.StatusConflict, "telegram subscriber already exists")
+var ErrInvalidLinkCode = http_errors.New("invalid_link_code", http.StatusBadRequest, "invalid or expired link code")
+var ErrTgChatAlreadyLinked = http_errors.New("telegram_chat_already_linked", http.StatusConflict, "telegram chat is already linked with subscriber")
+
+//ErrChatUnreachable is wrapped by errors telegram returns when the chat can't receive messages anymore
+var ErrChatUnreachable = errors.New("telegram chat is unreachable")
+var ErrBotBlocked = fmt.Errorf("%w: bot was blocked by the user", ErrChatUnreachable)
+var ErrUserDeactivated = fmt.Errorf("%w: user is deactivated", ErrChatUnreachable)
+var ErrChatNotFound = fmt.Errorf("%w: chat not found", ErrChatUnreachable)
+var ErrBotKicked = fmt.Errorf("%w: bot was kicked from the chat", ErrChatUnreachable)
+
+//Reasons of link inactivity stored in telegram_subscribers.inactive_reason
+const (
+	ReasonBlocked     = "blocked"
+	ReasonDeactivated = "deactivated"
+	ReasonNotFound    = "chat_not_found"
+	ReasonKicked      = "kicked"
+)
+
+//FromAPIError maps telegram api error to one of typed errors above. Other errors are returned as is
+func FromAPIError(err error) error {
+	var apiErr *tg.Error
+	if errors.As(err, &apiErr) != true {
+		return err
+	}
+	if apiErr.Code != http.StatusForbidden && apiErr.Code != http.StatusBadRequest {
+		return err
+	}
+
+	msg := strings.ToLower(apiErr.Message)
+	switch true {
+	case strings.Contains(msg, "bot was blocked by the user"):
+		return ErrBotBlocked
+	case strings.Contains(msg, "user is deactivated"):
+		return ErrUserDeactivated
+	case strings.Contains(msg, "chat not found"):
+		return ErrChatNotFound
+	case strings.Contains(msg, "bot was kicked"), strings.Contains(msg, "bot is not a member"):
+		return ErrBotKicked
+	default:
+		return err
+	}
+}
+
+//Reason returns reason of link inactivity for unreachable chat error, empty string otherwise
+func Reason(err error) string {
+	switch true {
+	case errors.Is(err, ErrBotBlocked):
+		return ReasonBlocked
+	case errors.Is(err, ErrUserDeactivated):
+		return ReasonDeactivated
+	case errors.Is(err, ErrChatNotFound):
+		return ReasonNotFound
+	case errors.Is(err, ErrBotKicked):
+		return ReasonKicked
+	default:
+		return ""
+	}
+}
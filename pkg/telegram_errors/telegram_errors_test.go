@@ -0,0 +1,40 @@
+package telegram_errors_test
+
+import (
+	"fmt"
+	"testing"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pkg/errors"
+	"github.com/sonyamoonglade/notification-service/pkg/telegram_errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromAPIError(t *testing.T) {
+
+	tests := []struct {
+		apiErr   *tg.Error
+		expected error
+		reason   string
+	}{
+		{&tg.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}, telegram_errors.ErrBotBlocked, telegram_errors.ReasonBlocked},
+		{&tg.Error{Code: 403, Message: "Forbidden: user is deactivated"}, telegram_errors.ErrUserDeactivated, telegram_errors.ReasonDeactivated},
+		{&tg.Error{Code: 400, Message: "Bad Request: chat not found"}, telegram_errors.ErrChatNotFound, telegram_errors.ReasonNotFound},
+		{&tg.Error{Code: 403, Message: "Forbidden: bot was kicked from the supergroup chat"}, telegram_errors.ErrBotKicked, telegram_errors.ReasonKicked},
+	}
+
+	for _, tc := range tests {
+		//Bot wraps api errors before returning them
+		err := fmt.Errorf("bot could not send a message. %w", telegram_errors.FromAPIError(tc.apiErr))
+		assert.True(t, errors.Is(err, tc.expected))
+		assert.True(t, errors.Is(err, telegram_errors.ErrChatUnreachable))
+		assert.Equal(t, tc.reason, telegram_errors.Reason(err))
+	}
+
+	//Other errors are not typed
+	apiErr := &tg.Error{Code: 429, Message: "Too Many Requests: retry after 5"}
+	err := telegram_errors.FromAPIError(apiErr)
+	assert.Equal(t, apiErr, err)
+	assert.False(t, errors.Is(err, telegram_errors.ErrChatUnreachable))
+	assert.Equal(t, "", telegram_errors.Reason(err))
+}
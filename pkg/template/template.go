@@ -8,6 +8,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/sonyamoonglade/notification-service/internal/entity"
+	"github.com/sonyamoonglade/notification-service/pkg/http_errors"
 )
 
 var path = "./templates.json"
@@ -28,7 +29,9 @@ func NewTemplateProvider() Provider {
 func (t *templateProvider) Find(eventID uint64) (string, error) {
 	templ, ok := t.store[eventID]
 	if ok != true {
-		return "", fmt.Errorf("template for event %d not found", eventID)
+		return "", http_errors.ErrMissingTemplateServiceUnavailable.With(
+			fmt.Sprintf("template for event %d not found", eventID),
+			http_errors.Details{"event_id": eventID})
 	}
 	return templ, nil
 }
@@ -0,0 +1,27 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+)
+
+//DefaultID is the tenant everything created before tenants belongs to. Default bot serves it
+const DefaultID uint64 = 1
+
+const DefaultName = "default"
+
+//ErrNoTenant is returned by queries of tenant data made without tenant in ctx
+var ErrNoTenant = errors.New("no tenant in context")
+
+type ctxKey struct{}
+
+//WithID returns ctx every storage query of which is scoped to tenantID
+func WithID(ctx context.Context, tenantID uint64) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tenantID)
+}
+
+//FromContext returns tenant of ctx. Queries with ctx without tenant see no tenant rows at all
+func FromContext(ctx context.Context) (uint64, bool) {
+	tenantID, ok := ctx.Value(ctxKey{}).(uint64)
+	return tenantID, ok
+}